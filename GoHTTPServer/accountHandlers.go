@@ -0,0 +1,295 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/mail"
+)
+
+const (
+	minPasswordLength = 8
+	// emailChangeLifetime is how long the link confirming a new email
+	// address works.
+	emailChangeLifetime = 24 * time.Hour
+)
+
+type accountUpdateRequest struct {
+	// CurrentPassword is required for every change, so an access token on
+	// its own can't take over the account.
+	CurrentPassword string `json:"current_password"`
+	// Fields left out are unchanged.
+	Email    *string `json:"email"`
+	Password *string `json:"password"`
+}
+
+type accountUpdateResponse struct {
+	User
+	// PendingEmail is a new address waiting to be confirmed. The account
+	// keeps its current email until then.
+	PendingEmail string `json:"pending_email,omitempty"`
+	// Token and RefreshToken replace the caller's session after a password
+	// change, which signs out every other session.
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// updateAccountHandler changes the caller's email address and password.
+// A new email address only takes effect once it's confirmed through a link
+// sent to it; the old address is told about the request. A new password
+// takes effect at once and revokes every refresh token, so other sessions
+// end when their access tokens expire.
+func (cfg *apiConfig) updateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	var req accountUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.Email == nil && req.Password == nil {
+		respondWithError(w, http.StatusBadRequest, "Nothing to update", nil)
+		return
+	}
+
+	user, err := cfg.DB.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found", err)
+		return
+	}
+	if err := auth.CheckPasswordHash(req.CurrentPassword, user.HashedPassword); err != nil {
+		respondWithError(w, http.StatusForbidden, "Current password is incorrect", err)
+		return
+	}
+
+	// Any difference counts as a change, including case, since the address
+	// is stored as typed.
+	var newEmail string
+	if req.Email != nil && strings.TrimSpace(*req.Email) != user.Email {
+		newEmail = strings.TrimSpace(*req.Email)
+		if addr, err := netmail.ParseAddress(newEmail); err != nil || addr.Address != newEmail {
+			respondWithError(w, http.StatusBadRequest, "Invalid email address", err)
+			return
+		}
+		_, err := cfg.DB.GetUserByEmail(r.Context(), newEmail)
+		if err == nil {
+			respondWithError(w, http.StatusConflict, "Email is already in use", nil)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update account", err)
+			return
+		}
+	}
+
+	var hashedPassword string
+	if req.Password != nil {
+		if len(*req.Password) < minPasswordLength {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Passwords need at least %d characters", minPasswordLength), nil)
+			return
+		}
+		hashedPassword, err = auth.HashPassword(*req.Password)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't hash password", err)
+			return
+		}
+	}
+
+	var confirmToken, tokenHash string
+	if newEmail != "" {
+		confirmToken, tokenHash, err = auth.MakeConfirmationToken()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update account", err)
+			return
+		}
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update account", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	if hashedPassword != "" {
+		user, err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+			ID:             userID,
+			HashedPassword: hashedPassword,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update account", err)
+			return
+		}
+		if _, err := qtx.RevokeUserRefreshTokens(r.Context(), userID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update account", err)
+			return
+		}
+	}
+
+	if newEmail != "" {
+		// A new request replaces any earlier one still waiting.
+		if err := qtx.DeleteEmailChanges(r.Context(), userID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update account", err)
+			return
+		}
+		err := qtx.CreateEmailChange(r.Context(), database.CreateEmailChangeParams{
+			TokenHash: tokenHash,
+			UserID:    userID,
+			NewEmail:  newEmail,
+			ExpiresAt: time.Now().UTC().Add(emailChangeLifetime),
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update account", err)
+			return
+		}
+
+		// The mail goes out before anything is committed, so if it can't be
+		// sent the whole update is dropped rather than the password
+		// changing and signing the caller out on its own.
+		if err := cfg.sendEmailChangeMail(r, user.Email, newEmail, confirmToken); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't send confirmation email", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update account", err)
+		return
+	}
+
+	response := accountUpdateResponse{User: User{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		Handle:      user.Handle.String,
+		IsChirpyRed: user.IsChirpyRed,
+	}}
+
+	if hashedPassword != "" {
+		response.Token, response.RefreshToken, err = cfg.startSession(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Password changed, but couldn't start a new session", err)
+			return
+		}
+	}
+
+	if newEmail != "" {
+		response.PendingEmail = newEmail
+	} else {
+		pending, err := cfg.DB.GetPendingEmail(r.Context(), userID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't load account", err)
+			return
+		}
+		response.PendingEmail = pending
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// sendEmailChangeMail sends the confirmation link to the new address and
+// lets the old one know a change was asked for.
+func (cfg *apiConfig) sendEmailChangeMail(r *http.Request, oldEmail, newEmail, token string) error {
+	link := cfg.PublicURL + "/api/users/email/confirm?token=" + url.QueryEscape(token)
+
+	err := cfg.Mail.Send(r.Context(), mail.Message{
+		To:      newEmail,
+		Subject: "Confirm your new Chirpy email address",
+		Body: "Open this link to start using this address for your Chirpy account:\n\n" +
+			link + "\n\nThe link works for 24 hours. If you didn't ask for this, you can ignore this email.",
+	})
+	if err != nil {
+		return err
+	}
+
+	return cfg.Mail.Send(r.Context(), mail.Message{
+		To:      oldEmail,
+		Subject: "Your Chirpy email address is changing",
+		Body: "Someone asked to change your Chirpy account's email address to " + newEmail + ".\n\n" +
+			"The change only happens once the new address is confirmed. If this wasn't you, change your password.",
+	})
+}
+
+// confirmEmailHandler completes an email change from the link sent to the
+// new address. Each link works once.
+func (cfg *apiConfig) confirmEmailHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing confirmation token", nil)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't confirm email", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	change, err := qtx.TakeEmailChange(r.Context(), auth.HashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired confirmation link", nil)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't confirm email", err)
+		return
+	}
+	if time.Now().After(change.ExpiresAt) {
+		// The expired request is still removed.
+		if err := tx.Commit(); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't confirm email", err)
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired confirmation link", nil)
+		return
+	}
+
+	user, err := qtx.UpdateUserEmail(r.Context(), database.UpdateUserEmailParams{
+		ID:    change.UserID,
+		Email: change.NewEmail,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Email is already in use", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't confirm email", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't confirm email", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, User{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		Handle:      user.Handle.String,
+		IsChirpyRed: user.IsChirpyRed,
+	})
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/profanity"
+)
+
+// profanityReloadInterval is how often each server checks the database for
+// term changes made through another instance.
+const profanityReloadInterval = 30 * time.Second
+
+type bannedTermRequest struct {
+	Term   string `json:"term"`
+	Action string `json:"action"`
+}
+
+type bannedTermResponse struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Term      string    `json:"term"`
+	Action    string    `json:"action"`
+}
+
+// reloadProfanityFilter builds a new filter from the banned_terms table and
+// swaps it in. Requests already using the old filter finish with it.
+func (cfg *apiConfig) reloadProfanityFilter(ctx context.Context) error {
+	rows, err := cfg.DB.GetBannedTerms(ctx)
+	if err != nil {
+		return err
+	}
+
+	terms := make([]profanity.Term, 0, len(rows))
+	for _, row := range rows {
+		terms = append(terms, profanity.Term{Term: row.Term, Action: profanity.Action(row.Action)})
+	}
+
+	cfg.Profanity.Store(profanity.New(terms))
+	return nil
+}
+
+// runProfanityReloader keeps the filter in sync with the database until ctx
+// is cancelled, reloading only when the term list has changed.
+func (cfg *apiConfig) runProfanityReloader(ctx context.Context) {
+	var loaded database.GetBannedTermsVersionRow
+
+	ticker := time.NewTicker(profanityReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		version, err := cfg.DB.GetBannedTermsVersion(ctx)
+		if err != nil {
+			log.Printf("Couldn't check banned terms: %v", err)
+		} else if version != loaded {
+			if err := cfg.reloadProfanityFilter(ctx); err != nil {
+				log.Printf("Couldn't reload banned terms: %v", err)
+			} else {
+				loaded = version
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) getBannedTermsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireRole(w, r, roleAdmin); !ok {
+		return
+	}
+
+	rows, err := cfg.DB.GetBannedTerms(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve banned terms", err)
+		return
+	}
+
+	terms := make([]bannedTermResponse, 0, len(rows))
+	for _, row := range rows {
+		terms = append(terms, toBannedTermResponse(row))
+	}
+	respondWithJSON(w, http.StatusOK, terms)
+}
+
+func (cfg *apiConfig) createBannedTermHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireRole(w, r, roleAdmin); !ok {
+		return
+	}
+
+	var req bannedTermRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	term := profanity.Normalize(req.Term)
+	if term == "" {
+		respondWithError(w, http.StatusBadRequest, "Term is required", nil)
+		return
+	}
+	if req.Action == "" {
+		req.Action = string(profanity.ActionMask)
+	}
+	if !profanity.ValidAction(profanity.Action(req.Action)) {
+		respondWithError(w, http.StatusBadRequest, "Action must be mask, flag or reject", nil)
+		return
+	}
+
+	row, err := cfg.DB.CreateBannedTerm(r.Context(), database.CreateBannedTermParams{
+		Term:   term,
+		Action: req.Action,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Term is already banned", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create banned term", err)
+		return
+	}
+
+	cfg.reloadAfterTermChange(r.Context())
+	respondWithJSON(w, http.StatusCreated, toBannedTermResponse(row))
+}
+
+func (cfg *apiConfig) updateBannedTermHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireRole(w, r, roleAdmin); !ok {
+		return
+	}
+
+	termID, err := uuid.Parse(r.PathValue("termID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid term ID", err)
+		return
+	}
+
+	var req bannedTermRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if !profanity.ValidAction(profanity.Action(req.Action)) {
+		respondWithError(w, http.StatusBadRequest, "Action must be mask, flag or reject", nil)
+		return
+	}
+
+	row, err := cfg.DB.UpdateBannedTerm(r.Context(), database.UpdateBannedTermParams{
+		ID:     termID,
+		Action: req.Action,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Banned term not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update banned term", err)
+		return
+	}
+
+	cfg.reloadAfterTermChange(r.Context())
+	respondWithJSON(w, http.StatusOK, toBannedTermResponse(row))
+}
+
+func (cfg *apiConfig) deleteBannedTermHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireRole(w, r, roleAdmin); !ok {
+		return
+	}
+
+	termID, err := uuid.Parse(r.PathValue("termID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid term ID", err)
+		return
+	}
+
+	deleted, err := cfg.DB.DeleteBannedTerm(r.Context(), termID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete banned term", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Banned term not found", nil)
+		return
+	}
+
+	cfg.reloadAfterTermChange(r.Context())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reloadAfterTermChange applies an edit on this instance straight away.
+// Other instances pick it up on their next reload tick.
+func (cfg *apiConfig) reloadAfterTermChange(ctx context.Context) {
+	if err := cfg.reloadProfanityFilter(ctx); err != nil {
+		log.Printf("Couldn't reload banned terms: %v", err)
+	}
+}
+
+func toBannedTermResponse(t database.BannedTerm) bannedTermResponse {
+	return bannedTermResponse{
+		ID:        t.ID,
+		CreatedAt: t.CreatedAt,
+		UpdatedAt: t.UpdatedAt,
+		Term:      t.Term,
+		Action:    t.Action,
+	}
+}
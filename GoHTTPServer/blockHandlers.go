@@ -0,0 +1,241 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pagination"
+)
+
+type relatedUser struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type relatedUserListResponse struct {
+	Users      []relatedUser `json:"users"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// parseRelationRequest authenticates the caller and reads the target user
+// from the path for the block and mute endpoints.
+func (cfg *apiConfig) parseRelationRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	targetID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	if targetID == userID {
+		respondWithError(w, http.StatusBadRequest, "You can't do that to yourself", nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, targetID, true
+}
+
+// blockHandler blocks a user. Any follows between the two are removed, and
+// from then on neither sees the other's chirps, and neither can follow,
+// rechirp or quote the other.
+func (cfg *apiConfig) blockHandler(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.parseRelationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't block user", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	err = qtx.BlockUser(r.Context(), database.BlockUserParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+	})
+	if isForeignKeyViolation(err) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't block user", err)
+		return
+	}
+
+	err = qtx.RemoveFollowsBetween(r.Context(), database.RemoveFollowsBetweenParams{
+		UserA: userID,
+		UserB: targetID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't block user", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't block user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unblockHandler(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.parseRelationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unblock user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// muteHandler hides a user's chirps from the caller's feeds without them
+// knowing. Unlike a block it doesn't affect follows or what they can see.
+func (cfg *apiConfig) muteHandler(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.parseRelationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.MuteUser(r.Context(), database.MuteUserParams{
+		MuterID: userID,
+		MutedID: targetID,
+	})
+	if isForeignKeyViolation(err) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't mute user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unmuteHandler(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.parseRelationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.UnmuteUser(r.Context(), database.UnmuteUserParams{
+		MuterID: userID,
+		MutedID: targetID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unmute user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) getBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, cursor, limit, ok := cfg.parseOwnListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.DB.GetBlockedUsers(r.Context(), database.GetBlockedUsersParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve blocked users", err)
+		return
+	}
+
+	users := make([]relatedUser, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, relatedUser{UserID: row.UserID, CreatedAt: row.CreatedAt})
+	}
+	respondWithRelatedUsers(w, users, limit)
+}
+
+func (cfg *apiConfig) getMutesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, cursor, limit, ok := cfg.parseOwnListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.DB.GetMutedUsers(r.Context(), database.GetMutedUsersParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve muted users", err)
+		return
+	}
+
+	users := make([]relatedUser, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, relatedUser{UserID: row.UserID, CreatedAt: row.CreatedAt})
+	}
+	respondWithRelatedUsers(w, users, limit)
+}
+
+// parseOwnListRequest authenticates the caller and reads the paging
+// parameters for lists only the caller may see.
+func (cfg *apiConfig) parseOwnListRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, pagination.Cursor, int32, bool) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return uuid.Nil, pagination.Cursor{}, 0, false
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return uuid.Nil, pagination.Cursor{}, 0, false
+	}
+
+	cursor, err := pagination.Parse(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+		return uuid.Nil, pagination.Cursor{}, 0, false
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return uuid.Nil, pagination.Cursor{}, 0, false
+	}
+
+	return userID, cursor, limit, true
+}
+
+func respondWithRelatedUsers(w http.ResponseWriter, users []relatedUser, limit int32) {
+	response := relatedUserListResponse{Users: users}
+	if len(users) == int(limit) {
+		last := users[len(users)-1]
+		response.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.UserID}.Encode()
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/sensitive"
+)
+
+// buildChirpResponses turns database chirps into API responses. Chirps that
+// rechirp or quote another chirp get the original embedded, and every chirp
+// carries its rechirp and like counts, hashtag/mention entities, media,
+// link previews and poll, and whether the viewer liked it and it starts
+// collapsed for them.
+// Lookups are batched so a page of chirps costs a fixed number of queries.
+// Originals the viewer can't see, because of a block or the original's
+// visibility, are left out. Quotes of deleted chirps are marked as such.
+func (cfg *apiConfig) buildChirpResponses(ctx context.Context, viewerID uuid.UUID, chirps []database.Chirp) ([]chirpResponse, error) {
+	byID := make(map[uuid.UUID]database.Chirp, len(chirps))
+	for _, c := range chirps {
+		byID[c.ID] = c
+	}
+
+	var missing []uuid.UUID
+	for _, c := range chirps {
+		for _, ref := range []uuid.NullUUID{c.RechirpOf, c.QuoteOf} {
+			if _, ok := byID[ref.UUID]; ref.Valid && !ok {
+				missing = append(missing, ref.UUID)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		originals, err := cfg.DB.GetChirpsByIDs(ctx, database.GetChirpsByIDsParams{
+			Ids:      missing,
+			ViewerID: viewerID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range originals {
+			byID[c.ID] = c
+		}
+	}
+
+	deletedQuotes, err := cfg.deletedQuotes(ctx, chirps, byID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	counts, err := cfg.DB.GetRechirpCounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	rechirpCounts := make(map[uuid.UUID]int64, len(counts))
+	for _, row := range counts {
+		rechirpCounts[row.RechirpOf.UUID] = row.RechirpCount
+	}
+
+	likes, err := cfg.DB.GetLikeCounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	likeCounts := make(map[uuid.UUID]int64, len(likes))
+	for _, row := range likes {
+		likeCounts[row.ChirpID] = row.LikeCount
+	}
+	liked := make(map[uuid.UUID]bool)
+	if viewerID != uuid.Nil {
+		likedIDs, err := cfg.DB.GetLikedChirpIDs(ctx, database.GetLikedChirpIDsParams{
+			UserID:   viewerID,
+			ChirpIds: ids,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range likedIDs {
+			liked[id] = true
+		}
+	}
+
+	entitiesByChirp, err := cfg.loadChirpEntities(ctx, byID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := cfg.DB.GetChirpAttachments(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	mediaByChirp := make(map[uuid.UUID][]mediaResponse, len(ids))
+	for _, a := range attachments {
+		mediaByChirp[a.ChirpID] = append(mediaByChirp[a.ChirpID], cfg.toMediaResponse(database.MediaFile{
+			ID:           a.ID,
+			CreatedAt:    a.CreatedAt,
+			UserID:       a.UserID,
+			Sha256:       a.Sha256,
+			ContentType:  a.ContentType,
+			SizeBytes:    a.SizeBytes,
+			Width:        a.Width,
+			Height:       a.Height,
+			StorageKey:   a.StorageKey,
+			ThumbnailKey: a.ThumbnailKey,
+		}))
+	}
+	links, err := cfg.DB.GetChirpLinkPreviews(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	previewsByChirp := make(map[uuid.UUID][]linkPreviewResponse, len(ids))
+	for _, l := range links {
+		previewsByChirp[l.ChirpID] = append(previewsByChirp[l.ChirpID], linkPreviewResponse{
+			URL:         l.URL,
+			Title:       l.Title,
+			Description: l.Description,
+			ImageURL:    l.ImageURL,
+			SiteName:    l.SiteName,
+		})
+	}
+	chirpPreviews := func(id uuid.UUID) []linkPreviewResponse {
+		if p, ok := previewsByChirp[id]; ok {
+			return p
+		}
+		return []linkPreviewResponse{}
+	}
+
+	pollsByChirp, err := cfg.loadPolls(ctx, viewerID, byID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	preference, err := cfg.sensitivePreference(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	chirpMedia := func(id uuid.UUID) []mediaResponse {
+		if m, ok := mediaByChirp[id]; ok {
+			return m
+		}
+		return []mediaResponse{}
+	}
+
+	embed := func(ref uuid.NullUUID) *chirpResponse {
+		if !ref.Valid {
+			return nil
+		}
+		original, ok := byID[ref.UUID]
+		if !ok {
+			return nil
+		}
+		resp := toChirpResponse(original)
+		resp.Collapsed = sensitive.Collapsed(preference, original.ContentWarning, original.SensitiveMedia)
+		resp.RechirpCount = rechirpCounts[original.ID]
+		resp.LikeCount = likeCounts[original.ID]
+		resp.Liked = liked[original.ID]
+		resp.Entities = entitiesByChirp[original.ID]
+		resp.Media = chirpMedia(original.ID)
+		resp.Previews = chirpPreviews(original.ID)
+		resp.Poll = pollsByChirp[original.ID]
+		return &resp
+	}
+
+	responses := make([]chirpResponse, 0, len(chirps))
+	for _, c := range chirps {
+		resp := toChirpResponse(c)
+		resp.Collapsed = sensitive.Collapsed(preference, c.ContentWarning, c.SensitiveMedia)
+		resp.RechirpCount = rechirpCounts[c.ID]
+		resp.LikeCount = likeCounts[c.ID]
+		resp.Liked = liked[c.ID]
+		resp.Entities = entitiesByChirp[c.ID]
+		resp.Media = chirpMedia(c.ID)
+		resp.Previews = chirpPreviews(c.ID)
+		resp.Poll = pollsByChirp[c.ID]
+		resp.RechirpedChirp = embed(c.RechirpOf)
+		resp.QuotedChirp = embed(c.QuoteOf)
+		resp.QuoteDeleted = c.QuoteOf.Valid && deletedQuotes[c.QuoteOf.UUID]
+		responses = append(responses, resp)
+	}
+	return responses, nil
+}
+
+// deletedQuotes returns the ids quoted by chirps that no longer exist.
+// Only quotes missing from byID are checked, since a missing original may
+// just be one the viewer can't see.
+func (cfg *apiConfig) deletedQuotes(ctx context.Context, chirps []database.Chirp, byID map[uuid.UUID]database.Chirp) (map[uuid.UUID]bool, error) {
+	var unseen []uuid.UUID
+	for _, c := range chirps {
+		if _, ok := byID[c.QuoteOf.UUID]; c.QuoteOf.Valid && !ok {
+			unseen = append(unseen, c.QuoteOf.UUID)
+		}
+	}
+	if len(unseen) == 0 {
+		return nil, nil
+	}
+
+	existing, err := cfg.DB.GetExistingChirpIDs(ctx, unseen)
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[uuid.UUID]bool, len(existing))
+	for _, id := range existing {
+		exists[id] = true
+	}
+	deleted := make(map[uuid.UUID]bool, len(unseen))
+	for _, id := range unseen {
+		deleted[id] = !exists[id]
+	}
+	return deleted, nil
+}
+
+// loadChirpEntities fetches the hashtags and mentions for the given chirps.
+// Every chirp gets an entry with non-nil slices so responses always encode
+// empty lists rather than null.
+func (cfg *apiConfig) loadChirpEntities(ctx context.Context, byID map[uuid.UUID]database.Chirp, ids []uuid.UUID) (map[uuid.UUID]chirpEntities, error) {
+	result := make(map[uuid.UUID]chirpEntities, len(ids))
+	for _, id := range ids {
+		result[id] = chirpEntities{
+			Hashtags: []hashtagEntity{},
+			Mentions: []mentionEntity{},
+		}
+	}
+
+	hashtags, err := cfg.DB.GetHashtagEntities(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hashtags {
+		e := result[h.ChirpID]
+		e.Hashtags = append(e.Hashtags, hashtagEntity{Tag: h.Tag, Start: h.StartOffset, End: h.EndOffset})
+		result[h.ChirpID] = e
+	}
+
+	mentions, err := cfg.DB.GetMentionEntities(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mentions {
+		body := []rune(byID[m.ChirpID].Body)
+		username := ""
+		if int(m.EndOffset) <= len(body) {
+			username = string(body[m.StartOffset+1 : m.EndOffset])
+		}
+		e := result[m.ChirpID]
+		e.Mentions = append(e.Mentions, mentionEntity{
+			UserID:   m.UserID,
+			Username: username,
+			Start:    m.StartOffset,
+			End:      m.EndOffset,
+		})
+		result[m.ChirpID] = e
+	}
+
+	return result, nil
+}
+
+func (cfg *apiConfig) buildChirpResponse(ctx context.Context, viewerID uuid.UUID, chirp database.Chirp) (chirpResponse, error) {
+	responses, err := cfg.buildChirpResponses(ctx, viewerID, []database.Chirp{chirp})
+	if err != nil {
+		return chirpResponse{}, err
+	}
+	return responses[0], nil
+}
+
+func toChirpResponse(c database.Chirp) chirpResponse {
+	return chirpResponse{
+		ID:             c.ID,
+		CreatedAt:      c.CreatedAt,
+		UpdatedAt:      c.UpdatedAt,
+		Body:           c.Body,
+		UserID:         c.UserID,
+		Visibility:     c.Visibility,
+		ContentWarning: c.ContentWarning,
+		SensitiveMedia: c.SensitiveMedia,
+		RechirpOf:      nullUUIDPtr(c.RechirpOf),
+		QuoteOf:        nullUUIDPtr(c.QuoteOf),
+		ReplyTo:        nullUUIDPtr(c.ReplyTo),
+	}
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"sort"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/activitypub"
 	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
 	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pipeline"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pubsub"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/sensitive"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/visibility"
 )
 
 func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request) {
@@ -23,6 +33,15 @@ func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	user, err := cfg.DB.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found", err)
+		return
+	}
+	if respondIfSuspended(w, user) {
+		return
+	}
+
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
 	err = decoder.Decode(&params)
@@ -31,33 +50,238 @@ func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	const maxChirpLength = 140
-	if len(params.Body) > maxChirpLength {
-		respondWithError(w, http.StatusBadRequest, "Chirp is too long", nil)
+	level, err := visibility.Parse(params.Visibility)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Unknown visibility", err)
 		return
 	}
 
-	cleaned := cleanWords(params.Body)
+	if params.RechirpOf != "" && params.QuoteOf != "" {
+		respondWithError(w, http.StatusBadRequest, "A chirp can't be both a rechirp and a quote", nil)
+		return
+	}
 
-	chirp, err := cfg.DB.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleaned,
-		UserID: userID,
-	})
+	scheduled := params.Draft || params.PublishAt != nil
+	if params.Draft && params.PublishAt != nil {
+		respondWithError(w, http.StatusBadRequest, "A draft can't have a publish time", nil)
+		return
+	}
+
+	if params.RechirpOf != "" {
+		if params.ReplyTo != "" {
+			respondWithError(w, http.StatusBadRequest, "Rechirps can't be replies", nil)
+			return
+		}
+		if scheduled {
+			respondWithError(w, http.StatusBadRequest, "Rechirps can't be scheduled", nil)
+			return
+		}
+		if params.Poll != nil {
+			respondWithError(w, http.StatusBadRequest, "Rechirps can't have polls", nil)
+			return
+		}
+		if params.ContentWarning != "" || params.SensitiveMedia {
+			respondWithError(w, http.StatusBadRequest, "Rechirps show the original's content warning", nil)
+			return
+		}
+		cfg.createRechirp(w, r, userID, level, params)
+		return
+	}
+
+	var quoteOf uuid.NullUUID
+	if params.QuoteOf != "" {
+		original, err := cfg.resolveOriginalChirp(r.Context(), userID, params.QuoteOf)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Quoted chirp not found", err)
+			return
+		}
+		if params.Body == "" {
+			respondWithError(w, http.StatusBadRequest, "Quote chirps need a body", nil)
+			return
+		}
+		quoteOf = uuid.NullUUID{UUID: original.ID, Valid: true}
+	}
+
+	var replyTo uuid.NullUUID
+	if params.ReplyTo != "" {
+		if scheduled {
+			respondWithError(w, http.StatusBadRequest, "Replies can't be scheduled", nil)
+			return
+		}
+		original, err := cfg.resolveOriginalChirp(r.Context(), userID, params.ReplyTo)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Replied-to chirp not found", err)
+			return
+		}
+		replyTo = uuid.NullUUID{UUID: original.ID, Valid: true}
+	}
 
+	mediaIDs, err := cfg.parseChirpMedia(r.Context(), userID, params.MediaIDs)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	content := &pipeline.Content{AuthorID: userID, Body: params.Body}
+	if !cfg.runChirpPipeline(w, r, content) {
+		return
+	}
+
+	warning, err := cfg.parseContentWarning(params.ContentWarning, content)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	var poll *pollDraft
+	if params.Poll != nil {
+		if scheduled {
+			respondWithError(w, http.StatusBadRequest, "Polls can't be scheduled", nil)
+			return
+		}
+		poll, err = cfg.parsePoll(params.Poll, content)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+
+	if scheduled {
+		draft := database.CreateScheduledChirpParams{
+			UserID:         userID,
+			Body:           content.Body,
+			QuoteOf:        quoteOf,
+			Visibility:     string(level),
+			MediaIds:       mediaIDs,
+			ContentWarning: warning,
+			SensitiveMedia: params.SensitiveMedia,
+		}
+		if params.PublishAt != nil {
+			draft.PublishAt = sql.NullTime{Time: *params.PublishAt, Valid: true}
+		}
+		cfg.saveScheduledChirp(w, r, draft)
+		return
+	}
+
+	chirp, err := cfg.createChirp(r.Context(), chirpDraft{
+		Params: database.CreateChirpParams{
+			Body:           content.Body,
+			UserID:         userID,
+			QuoteOf:        quoteOf,
+			ReplyTo:        replyTo,
+			Visibility:     string(level),
+			ContentWarning: warning,
+			SensitiveMedia: params.SensitiveMedia,
+		},
+		MediaIDs: mediaIDs,
+		Flags:    content.Flags,
+		Poll:     poll,
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't create chirp", err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, chirpResponse{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	response, err := cfg.buildChirpResponse(r.Context(), userID, chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+// createRechirp re-shares an existing chirp without adding any text of its
+// own. Rechirping a rechirp shares the underlying original instead. Only
+// public chirps can be rechirped, so a rechirp never carries a chirp to
+// people its author didn't choose.
+func (cfg *apiConfig) createRechirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID, level visibility.Level, params parameters) {
+	if params.Body != "" || len(params.MediaIDs) > 0 {
+		respondWithError(w, http.StatusBadRequest, "Rechirps can't have a body or media, use quote_of instead", nil)
+		return
+	}
+
+	original, err := cfg.resolveOriginalChirp(r.Context(), userID, params.RechirpOf)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Rechirped chirp not found", err)
+		return
+	}
+	if original.Visibility != string(visibility.Public) {
+		respondWithError(w, http.StatusForbidden, "Only public chirps can be rechirped", nil)
+		return
+	}
+
+	chirp, err := cfg.createChirp(r.Context(), chirpDraft{
+		Params: database.CreateChirpParams{
+			Body:       "",
+			UserID:     userID,
+			RechirpOf:  uuid.NullUUID{UUID: original.ID, Valid: true},
+			Visibility: string(level),
+		},
 	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "You have already rechirped this chirp", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create rechirp", err)
+		return
+	}
+
+	response, err := cfg.buildChirpResponse(r.Context(), userID, chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, response)
 }
 
+// resolveOriginalChirp looks up the chirp being rechirped or quoted. When
+// that chirp is itself a rechirp, the chirp it re-shares is returned so
+// rechirps never nest. Hidden chirps, chirps userID can't see, and chirps by
+// someone who has a block with userID in either direction, can't be
+// re-shared.
+func (cfg *apiConfig) resolveOriginalChirp(ctx context.Context, userID uuid.UUID, chirpIDString string) (database.Chirp, error) {
+	chirpID, err := uuid.Parse(chirpIDString)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	chirp, err := cfg.DB.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	if chirp.RechirpOf.Valid {
+		chirp, err = cfg.DB.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+			ID:       chirp.RechirpOf.UUID,
+			ViewerID: userID,
+		})
+		if err != nil {
+			return database.Chirp{}, err
+		}
+	}
+
+	blocked, err := cfg.DB.IsBlockedBetween(ctx, database.IsBlockedBetweenParams{
+		UserA: userID,
+		UserB: chirp.UserID,
+	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if blocked {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+// getChirpHandler returns a single chirp. Chirps the caller isn't allowed
+// to see get the same 404 as chirps that don't exist, so their existence
+// isn't revealed.
 func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
 	chirpIDString := r.PathValue("chirpID")
 	chirpID, err := uuid.Parse(chirpIDString)
@@ -66,21 +290,43 @@ func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	chirpDB, err := cfg.DB.GetChirp(r.Context(), chirpID)
+	viewerID := cfg.viewerID(r)
+	chirpDB, err := cfg.DB.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{
+		ID:       chirpID,
+		ViewerID: viewerID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp", err)
+		return
+	}
 
+	blocked, err := cfg.DB.IsBlockedBetween(r.Context(), database.IsBlockedBetweenParams{
+		UserA: viewerID,
+		UserB: chirpDB.UserID,
+	})
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp", err)
+		return
+	}
+	if blocked {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", nil)
 		return
 	}
 
-	response := chirpResponse{
-		ID:        chirpDB.ID,
-		Body:      chirpDB.Body,
-		UserID:    chirpDB.UserID,
-		CreatedAt: chirpDB.CreatedAt,
-		UpdatedAt: chirpDB.UpdatedAt,
+	response, err := cfg.buildChirpResponse(r.Context(), viewerID, chirpDB)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp", err)
+		return
 	}
 
+	if chirpDB.Visibility == string(visibility.Public) {
+		oembed := cfg.PublicURL + "/api/oembed?url=" + url.QueryEscape(cfg.PublicURL+r.URL.Path)
+		w.Header().Add("Link", "<"+oembed+`>; rel="alternate"; type="application/json+oembed"`)
+	}
 	respondWithJSON(w, http.StatusOK, response)
 }
 
@@ -105,7 +351,7 @@ func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request)
 
 	chirpDB, err := cfg.DB.GetChirp(r.Context(), chirpID)
 
-	if err != nil {
+	if err != nil || chirpDB.HiddenAt.Valid {
 		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
 		return
 	}
@@ -115,25 +361,70 @@ func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = cfg.DB.DeleteChirp(r.Context(), chirpID)
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Could not delete chirp", err)
 		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	if err := cfg.deleteChirp(r.Context(), qtx, chirpDB); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not delete chirp", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not delete chirp", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusNoContent, struct{}{})
 }
 
+// deleteChirp deletes a chirp and its rechirps, recording an event and
+// federating a Delete for each so followers drop them too. Rechirps would
+// otherwise vanish silently with the chirp they point at.
+func (cfg *apiConfig) deleteChirp(ctx context.Context, qtx *database.Queries, chirp database.Chirp) error {
+	rechirps, err := qtx.GetRechirpsOf(ctx, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	if err != nil {
+		return err
+	}
+	for _, rechirp := range append(rechirps, chirp) {
+		// The event is recorded first so it can still read the chirp's
+		// mentions.
+		if err := recordChirpEvent(ctx, qtx, pubsub.ChirpDeleted, rechirp); err != nil {
+			return err
+		}
+		if err := cfg.federateChirp(ctx, qtx, activitypub.TypeDelete, rechirp); err != nil {
+			return err
+		}
+		if err := qtx.DeleteChirp(ctx, rechirp.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (cfg *apiConfig) getChirpsHandler(w http.ResponseWriter, r *http.Request) {
-	var chirps []chirpResponse
 	var chirpDB []database.Chirp
 	var err error
+	viewerID := cfg.viewerID(r)
 	sortOrder := r.URL.Query().Get("sort")
 
 	if sortOrder == "" {
 		sortOrder = "asc"
 	}
 
+	sensitiveFilter, err := sensitive.ParseFilter(r.URL.Query().Get("sensitive"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "sensitive must be include, exclude or only", err)
+		return
+	}
+	// content_warning matches chirps whose warning contains it, ignoring
+	// case. A rechirp is matched on its original's warning.
+	warningFilter := strings.TrimSpace(r.URL.Query().Get("content_warning"))
+
 	authorIDString := r.URL.Query().Get("author_id")
 	if authorIDString != "" {
 		authorID, err := uuid.Parse(authorIDString)
@@ -141,13 +432,22 @@ func (cfg *apiConfig) getChirpsHandler(w http.ResponseWriter, r *http.Request) {
 			respondWithError(w, http.StatusBadRequest, "Invalid author ID", err)
 			return
 		}
-		chirpDB, err = cfg.DB.GetChirpsByAuthor(r.Context(), authorID)
+		chirpDB, err = cfg.DB.GetChirpsByAuthor(r.Context(), database.GetChirpsByAuthorParams{
+			UserID:         authorID,
+			ViewerID:       viewerID,
+			Sensitive:      string(sensitiveFilter),
+			ContentWarning: warningFilter,
+		})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
 			return
 		}
 	} else {
-		chirpDB, err = cfg.DB.GetChirps(r.Context())
+		chirpDB, err = cfg.DB.GetChirps(r.Context(), database.GetChirpsParams{
+			ViewerID:       viewerID,
+			Sensitive:      string(sensitiveFilter),
+			ContentWarning: warningFilter,
+		})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
 			return
@@ -165,14 +465,10 @@ func (cfg *apiConfig) getChirpsHandler(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	for _, c := range chirpDB {
-		chirps = append(chirps, chirpResponse{
-			ID:        c.ID,
-			Body:      c.Body,
-			UserID:    c.UserID,
-			CreatedAt: c.CreatedAt,
-			UpdatedAt: c.UpdatedAt,
-		})
+	chirps, err := cfg.buildChirpResponses(r.Context(), viewerID, chirpDB)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
 	}
 	respondWithJSON(w, http.StatusOK, chirps)
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pipeline"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/profanity"
+)
+
+type rejectionResponse struct {
+	Error string `json:"error"`
+	Stage string `json:"stage"`
+	Code  string `json:"code"`
+}
+
+type validateChirpResponse struct {
+	Valid     bool   `json:"valid"`
+	Body      string `json:"body"`
+	Length    int    `json:"length"`
+	MaxLength int    `json:"max_length"`
+	Remaining int    `json:"remaining"`
+	Error     string `json:"error,omitempty"`
+	Stage     string `json:"stage,omitempty"`
+	Code      string `json:"code,omitempty"`
+}
+
+// messagePipelineSpec is the pipeline direct messages go through. They're
+// private, so only the profanity filter applies, and they can be longer
+// than chirps.
+const messagePipelineSpec = "normalize,length=1000,profanity"
+
+// newContentPipeline builds the content pipeline described by spec,
+// falling back to pipeline.DefaultSpec when it's empty. Every pipeline has
+// to normalize bodies and then check their length, since the rest of the
+// server relies on both.
+func (cfg *apiConfig) newContentPipeline(spec string) (*pipeline.Pipeline, error) {
+	if spec == "" {
+		spec = pipeline.DefaultSpec
+	}
+
+	reg := pipeline.Builtins()
+	reg["profanity"] = func(string) (pipeline.Stage, error) {
+		return pipeline.Profanity(cfg.Profanity.Load), nil
+	}
+	p, err := reg.Build(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Require("normalize", "length"); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// runChirpPipeline passes content through the configured pipeline. When it
+// returns false the chirp was refused and a response has been written.
+func (cfg *apiConfig) runChirpPipeline(w http.ResponseWriter, r *http.Request, content *pipeline.Content) bool {
+	return runPipeline(w, r, cfg.Pipeline, content)
+}
+
+// filterExtraText masks banned terms in text posted alongside a chirp's
+// body, such as poll options and content warnings, which doesn't go
+// through the pipeline. Flagged terms are added to content so the chirp is
+// still reviewed. It reports false if any of texts has a rejected term.
+func (cfg *apiConfig) filterExtraText(content *pipeline.Content, what string, texts []string) bool {
+	filter := cfg.Profanity.Load()
+	var flagged []string
+	for i, t := range texts {
+		result := filter.Apply(t)
+		if result.Action == profanity.ActionReject {
+			return false
+		}
+		for _, m := range result.Matches {
+			if m.Action == profanity.ActionFlag {
+				flagged = append(flagged, m.Term)
+			}
+		}
+		texts[i] = result.Text
+	}
+	if len(flagged) > 0 {
+		content.Flag(what + " matched flagged terms: " + strings.Join(flagged, ", "))
+	}
+	return true
+}
+
+func runPipeline(w http.ResponseWriter, r *http.Request, p *pipeline.Pipeline, content *pipeline.Content) bool {
+	err := p.Run(r.Context(), content)
+	var rejection *pipeline.Rejection
+	if errors.As(err, &rejection) {
+		respondWithJSON(w, http.StatusBadRequest, rejectionResponse{
+			Error: rejection.Message,
+			Stage: rejection.Stage,
+			Code:  rejection.Code,
+		})
+		return false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't process text", err)
+		return false
+	}
+	return true
+}
+
+// validateChirpHandler runs a body through the same pipeline as
+// createChirpHandler without saving anything, so clients can show the
+// normalized text and remaining length before posting.
+func (cfg *apiConfig) validateChirpHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	content := &pipeline.Content{AuthorID: userID, Body: params.Body}
+	err = cfg.Pipeline.Run(r.Context(), content)
+	response := validateChirpResponse{
+		Valid:     err == nil,
+		Body:      content.Body,
+		Length:    content.Length,
+		MaxLength: content.MaxLength,
+		Remaining: content.MaxLength - content.Length,
+	}
+
+	var rejection *pipeline.Rejection
+	if errors.As(err, &rejection) {
+		response.Error = rejection.Message
+		response.Stage = rejection.Stage
+		response.Code = rejection.Code
+	} else if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't process chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,630 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pagination"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pipeline"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pubsub"
+)
+
+const (
+	// maxConversationMembers includes the creator.
+	maxConversationMembers = 10
+	maxConversationTitle   = 100
+)
+
+type conversationMember struct {
+	UserID            uuid.UUID  `json:"user_id"`
+	JoinedAt          time.Time  `json:"joined_at"`
+	LastReadMessageID *uuid.UUID `json:"last_read_message_id,omitempty"`
+	LastReadAt        *time.Time `json:"last_read_at,omitempty"`
+}
+
+type conversationResponse struct {
+	ID          uuid.UUID            `json:"id"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	CreatedBy   uuid.UUID            `json:"created_by"`
+	IsGroup     bool                 `json:"is_group"`
+	Title       string               `json:"title,omitempty"`
+	Members     []conversationMember `json:"members"`
+	UnreadCount int64                `json:"unread_count"`
+}
+
+type conversationListResponse struct {
+	Conversations []conversationResponse `json:"conversations"`
+	NextCursor    string                 `json:"next_cursor,omitempty"`
+}
+
+type messageResponse struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+// conversationActivity is the payload of typing and read receipt events.
+type conversationActivity struct {
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	MessageID      *uuid.UUID `json:"message_id,omitempty"`
+}
+
+type messageListResponse struct {
+	Messages   []messageResponse `json:"messages"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+func newMessageResponse(m database.DirectMessage) messageResponse {
+	return messageResponse{
+		ID:             m.ID,
+		CreatedAt:      m.CreatedAt,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Body:           m.Body,
+	}
+}
+
+// directKey identifies the one-to-one conversation between two users,
+// whichever of them starts it.
+func directKey(a, b uuid.UUID) string {
+	ids := []string{a.String(), b.String()}
+	sort.Strings(ids)
+	return ids[0] + ":" + ids[1]
+}
+
+// createConversationHandler starts a conversation between the caller and
+// member_ids. With one other member it's a direct conversation, and asking
+// for one that already exists returns it rather than making another.
+// Nobody can start a conversation with someone on the other side of a
+// block.
+func (cfg *apiConfig) createConversationHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	user, err := cfg.DB.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found", err)
+		return
+	}
+	if respondIfSuspended(w, user) {
+		return
+	}
+
+	var req struct {
+		MemberIDs []string `json:"member_ids"`
+		Title     string   `json:"title"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	seen := map[uuid.UUID]bool{userID: true}
+	var members []uuid.UUID
+	for _, idStr := range req.MemberIDs {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid member ID", err)
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			members = append(members, id)
+		}
+	}
+	if len(members) == 0 {
+		respondWithError(w, http.StatusBadRequest, "A conversation needs at least one other member", nil)
+		return
+	}
+	if len(members)+1 > maxConversationMembers {
+		respondWithError(w, http.StatusBadRequest, "Too many members", nil)
+		return
+	}
+
+	isGroup := len(members) > 1
+	title := strings.TrimSpace(req.Title)
+	if !isGroup {
+		title = ""
+	}
+	if utf8.RuneCountInString(title) > maxConversationTitle {
+		respondWithError(w, http.StatusBadRequest, "Title is too long", nil)
+		return
+	}
+
+	for _, memberID := range members {
+		blocked, err := cfg.DB.IsBlockedBetween(r.Context(), database.IsBlockedBetweenParams{
+			UserA: userID,
+			UserB: memberID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create conversation", err)
+			return
+		}
+		if blocked {
+			respondWithError(w, http.StatusForbidden, "You can't message this user", nil)
+			return
+		}
+	}
+
+	var key sql.NullString
+	if !isGroup {
+		key = sql.NullString{String: directKey(userID, members[0]), Valid: true}
+		existing, err := cfg.DB.GetDirectConversation(r.Context(), key)
+		if err == nil {
+			cfg.respondWithConversation(w, r, http.StatusOK, existing)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create conversation", err)
+			return
+		}
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create conversation", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	conversation, err := qtx.CreateConversation(r.Context(), database.CreateConversationParams{
+		CreatedBy: userID,
+		IsGroup:   isGroup,
+		Title:     title,
+		DirectKey: key,
+	})
+	if isUniqueViolation(err) {
+		// The other user started the same conversation at the same moment.
+		tx.Rollback()
+		existing, err := cfg.DB.GetDirectConversation(r.Context(), key)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create conversation", err)
+			return
+		}
+		cfg.respondWithConversation(w, r, http.StatusOK, existing)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create conversation", err)
+		return
+	}
+
+	for _, memberID := range append([]uuid.UUID{userID}, members...) {
+		err := qtx.AddConversationMember(r.Context(), database.AddConversationMemberParams{
+			ConversationID: conversation.ID,
+			UserID:         memberID,
+		})
+		if isForeignKeyViolation(err) {
+			respondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create conversation", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create conversation", err)
+		return
+	}
+
+	cfg.respondWithConversation(w, r, http.StatusCreated, conversation)
+}
+
+// getConversationsHandler lists the caller's conversations, most recently
+// active first, with how many messages in each they haven't read.
+func (cfg *apiConfig) getConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, cursor, limit, ok := cfg.parseOwnListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.DB.GetConversations(r.Context(), database.GetConversationsParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve conversations", err)
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	members, err := cfg.conversationMembers(r.Context(), ids)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve conversations", err)
+		return
+	}
+
+	response := conversationListResponse{Conversations: make([]conversationResponse, 0, len(rows))}
+	for _, row := range rows {
+		response.Conversations = append(response.Conversations, conversationResponse{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			CreatedBy:   row.CreatedBy,
+			IsGroup:     row.IsGroup,
+			Title:       row.Title,
+			Members:     members[row.ID],
+			UnreadCount: row.UnreadCount,
+		})
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		response.NextCursor = pagination.Cursor{CreatedAt: last.UpdatedAt, ID: last.ID}.Encode()
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// sendMessageHandler posts a message to a conversation the caller belongs
+// to. Messages go through the profanity filter like chirps do. In a
+// direct conversation a block stops either side sending; in a group,
+// members on the other side of a block from the sender just don't get the
+// message.
+func (cfg *apiConfig) sendMessageHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	user, err := cfg.DB.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found", err)
+		return
+	}
+	if respondIfSuspended(w, user) {
+		return
+	}
+
+	conversation, ok := cfg.memberConversation(w, r, userID)
+	if !ok {
+		return
+	}
+
+	recipients, err := cfg.DB.GetMessageRecipients(r.Context(), database.GetMessageRecipientsParams{
+		ConversationID: conversation.ID,
+		SenderID:       userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't send message", err)
+		return
+	}
+	if !conversation.IsGroup && len(recipients) == 0 {
+		respondWithError(w, http.StatusForbidden, "You can't message this user", nil)
+		return
+	}
+
+	var req struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	content := &pipeline.Content{AuthorID: userID, Body: req.Body}
+	if !runPipeline(w, r, cfg.MessagePipeline, content) {
+		return
+	}
+	if content.Body == "" {
+		respondWithError(w, http.StatusBadRequest, "Message can't be empty", nil)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't send message", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	message, err := qtx.CreateDirectMessage(r.Context(), database.CreateDirectMessageParams{
+		ConversationID: conversation.ID,
+		SenderID:       userID,
+		Body:           content.Body,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't send message", err)
+		return
+	}
+
+	if err := qtx.TouchConversation(r.Context(), conversation.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't send message", err)
+		return
+	}
+
+	// Senders have read their own messages.
+	err = qtx.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		MessageID:      message.ID,
+		ReadAt:         message.CreatedAt,
+		ConversationID: conversation.ID,
+		UserID:         userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't send message", err)
+		return
+	}
+
+	err = publishPrivateEvent(r.Context(), qtx, privateEvent{
+		Type:       pubsub.MessageCreated,
+		Recipients: recipients,
+		MessageID:  message.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't send message", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't send message", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, newMessageResponse(message))
+}
+
+// getMessagesHandler lists a conversation's messages, newest first.
+// Messages from users on the other side of a block from the caller are
+// left out.
+func (cfg *apiConfig) getMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, cursor, limit, ok := cfg.parseOwnListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	conversation, ok := cfg.memberConversation(w, r, userID)
+	if !ok {
+		return
+	}
+
+	messages, err := cfg.DB.GetDirectMessages(r.Context(), database.GetDirectMessagesParams{
+		ConversationID:  conversation.ID,
+		ViewerID:        userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve messages", err)
+		return
+	}
+
+	response := messageListResponse{Messages: make([]messageResponse, 0, len(messages))}
+	for _, m := range messages {
+		response.Messages = append(response.Messages, newMessageResponse(m))
+	}
+	if len(messages) == int(limit) {
+		last := messages[len(messages)-1]
+		response.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// markConversationReadHandler moves the caller's read marker up to
+// message_id. Markers only move forward.
+func (cfg *apiConfig) markConversationReadHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid conversation ID", err)
+		return
+	}
+
+	var req struct {
+		MessageID uuid.UUID `json:"message_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	err = cfg.markConversationRead(r.Context(), userID, conversationID, req.MessageID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Message not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update read marker", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// markConversationRead moves a member's read marker up to messageID and
+// sends a read receipt to the other members. It returns sql.ErrNoRows if
+// the user isn't in the conversation or the message isn't part of it.
+func (cfg *apiConfig) markConversationRead(ctx context.Context, userID, conversationID, messageID uuid.UUID) error {
+	_, err := cfg.DB.GetConversationForMember(ctx, database.GetConversationForMemberParams{
+		ID:     conversationID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	message, err := cfg.DB.GetDirectMessage(ctx, messageID)
+	if err != nil {
+		return err
+	}
+	if message.ConversationID != conversationID {
+		return sql.ErrNoRows
+	}
+
+	tx, err := cfg.DBConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	err = qtx.MarkConversationRead(ctx, database.MarkConversationReadParams{
+		MessageID:      message.ID,
+		ReadAt:         message.CreatedAt,
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	recipients, err := qtx.GetMessageRecipients(ctx, database.GetMessageRecipientsParams{
+		ConversationID: conversationID,
+		SenderID:       userID,
+	})
+	if err != nil {
+		return err
+	}
+	err = publishPrivateEvent(ctx, qtx, privateEvent{
+		Type:       pubsub.MessageRead,
+		Recipients: recipients,
+		Data: conversationActivity{
+			ConversationID: conversationID,
+			UserID:         userID,
+			MessageID:      &message.ID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// sendTyping tells the other members of a conversation that userID is
+// typing. It returns sql.ErrNoRows if the user isn't in the conversation.
+func (cfg *apiConfig) sendTyping(ctx context.Context, userID, conversationID uuid.UUID) error {
+	_, err := cfg.DB.GetConversationForMember(ctx, database.GetConversationForMemberParams{
+		ID:     conversationID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	recipients, err := cfg.DB.GetMessageRecipients(ctx, database.GetMessageRecipientsParams{
+		ConversationID: conversationID,
+		SenderID:       userID,
+	})
+	if err != nil {
+		return err
+	}
+	return publishPrivateEvent(ctx, cfg.DB, privateEvent{
+		Type:       pubsub.Typing,
+		Recipients: recipients,
+		Data: conversationActivity{
+			ConversationID: conversationID,
+			UserID:         userID,
+		},
+	})
+}
+
+// memberConversation loads the conversation in the path, responding 404 if
+// it doesn't exist or the user isn't in it.
+func (cfg *apiConfig) memberConversation(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.Conversation, bool) {
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid conversation ID", err)
+		return database.Conversation{}, false
+	}
+
+	conversation, err := cfg.DB.GetConversationForMember(r.Context(), database.GetConversationForMemberParams{
+		ID:     conversationID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Conversation not found", err)
+		return database.Conversation{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load conversation", err)
+		return database.Conversation{}, false
+	}
+	return conversation, true
+}
+
+func (cfg *apiConfig) conversationMembers(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]conversationMember, error) {
+	rows, err := cfg.DB.GetConversationMembers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[uuid.UUID][]conversationMember, len(ids))
+	for _, row := range rows {
+		member := conversationMember{
+			UserID:            row.UserID,
+			JoinedAt:          row.JoinedAt,
+			LastReadMessageID: nullUUIDPtr(row.LastReadMessageID),
+		}
+		if row.LastReadAt.Valid {
+			member.LastReadAt = &row.LastReadAt.Time
+		}
+		members[row.ConversationID] = append(members[row.ConversationID], member)
+	}
+	return members, nil
+}
+
+func (cfg *apiConfig) respondWithConversation(w http.ResponseWriter, r *http.Request, status int, c database.Conversation) {
+	members, err := cfg.conversationMembers(r.Context(), []uuid.UUID{c.ID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load conversation", err)
+		return
+	}
+
+	respondWithJSON(w, status, conversationResponse{
+		ID:        c.ID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		CreatedBy: c.CreatedBy,
+		IsGroup:   c.IsGroup,
+		Title:     c.Title,
+		Members:   members[c.ID],
+	})
+}
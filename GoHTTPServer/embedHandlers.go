@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/embed"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/visibility"
+)
+
+// embedCacheAge is how long embed pages and oEmbed responses may be cached.
+// A deleted chirp can stay visible in embeds for this long.
+const embedCacheAge = 5 * time.Minute
+
+type oembedResponse struct {
+	Type         string `json:"type"`
+	Version      string `json:"version"`
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name"`
+	AuthorURL    string `json:"author_url,omitempty"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	CacheAge     int    `json:"cache_age"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+// loadEmbeddableChirp returns a chirp anyone may see embedded: one that
+// exists, isn't hidden by a moderator, and is public. A rechirp is shown
+// as the chirp it re-shares. Anything else is sql.ErrNoRows, so embeds
+// don't reveal whether a chirp exists.
+func (cfg *apiConfig) loadEmbeddableChirp(ctx context.Context, chirpID uuid.UUID) (database.Chirp, database.User, error) {
+	chirp, err := cfg.DB.GetChirp(ctx, chirpID)
+	if err == nil && chirp.RechirpOf.Valid {
+		chirp, err = cfg.DB.GetChirp(ctx, chirp.RechirpOf.UUID)
+	}
+	if err != nil {
+		return database.Chirp{}, database.User{}, err
+	}
+	if chirp.HiddenAt.Valid || chirp.Visibility != string(visibility.Public) {
+		return database.Chirp{}, database.User{}, sql.ErrNoRows
+	}
+
+	author, err := cfg.DB.GetUser(ctx, chirp.UserID)
+	if err != nil {
+		return database.Chirp{}, database.User{}, err
+	}
+	return chirp, author, nil
+}
+
+// oembedHandler describes how to embed the chirp at ?url=, following the
+// oEmbed spec. Only JSON is supported.
+func (cfg *apiConfig) oembedHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if format := query.Get("format"); format != "" && format != "json" {
+		respondWithError(w, http.StatusNotImplemented, "Only the json format is supported", nil)
+		return
+	}
+	rawURL := query.Get("url")
+	if rawURL == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing url", nil)
+		return
+	}
+
+	chirpID, err := embed.ChirpID(cfg.PublicURL, rawURL)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Not a chirp URL", err)
+		return
+	}
+
+	chirp, author, err := cfg.loadEmbeddableChirp(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp", err)
+		return
+	}
+
+	width, height := embed.Size(query.Get("maxwidth"), query.Get("maxheight"))
+	name := feedAuthorName(author)
+	response := oembedResponse{
+		Type:         "rich",
+		Version:      "1.0",
+		Title:        "Chirp by " + name,
+		AuthorName:   name,
+		ProviderName: "Chirpy",
+		ProviderURL:  cfg.PublicURL,
+		CacheAge:     int(embedCacheAge.Seconds()),
+		HTML:         embed.IFrame(cfg.PublicURL+"/embed/chirps/"+chirp.ID.String(), width, height),
+		Width:        width,
+		Height:       height,
+	}
+	if author.Handle.Valid {
+		response.AuthorURL = cfg.PublicURL + "/api/users/" + url.PathEscape(author.Handle.String)
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", response.CacheAge))
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// embedChirpHandler serves a small HTML page showing one chirp, meant to
+// be framed by other sites.
+func (cfg *apiConfig) embedChirpHandler(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+
+	chirp, author, err := cfg.loadEmbeddableChirp(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp", err)
+		return
+	}
+
+	var page bytes.Buffer
+	err = embed.Render(&page, embed.Chirp{
+		AuthorName:     feedAuthorName(author),
+		AuthorHandle:   author.Handle.String,
+		Body:           chirp.Body,
+		CreatedAt:      chirp.CreatedAt,
+		ContentWarning: chirp.ContentWarning,
+		URL:            cfg.PublicURL + "/api/chirps/" + chirp.ID.String(),
+		OEmbedURL:      cfg.PublicURL + "/api/oembed?url=" + url.QueryEscape(cfg.PublicURL+"/embed/chirps/"+chirp.ID.String()),
+		AtomURL:        cfg.PublicURL + userFeedPath(author.ID, "atom"),
+		RSSURL:         cfg.PublicURL + userFeedPath(author.ID, "rss"),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't render chirp", err)
+		return
+	}
+
+	// The page may be framed anywhere, but can't load anything itself.
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors *")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(embedCacheAge.Seconds())))
+
+	// The page changes when the chirp or its author's name does.
+	modified := chirp.UpdatedAt
+	if author.UpdatedAt.After(modified) {
+		modified = author.UpdatedAt
+	}
+	http.ServeContent(w, r, "", modified, bytes.NewReader(page.Bytes()))
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/activitypub"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/httpsig"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/safehttp"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/visibility"
+)
+
+const (
+	// deliveryInterval is how often each server instance looks for
+	// activities to post to remote inboxes.
+	deliveryInterval = 10 * time.Second
+	// maxDeliveryAttempts is how many times an activity is posted before
+	// it's dropped. With doubling backoff from a minute, the last attempt
+	// is about a day and a half after the first.
+	maxDeliveryAttempts = 12
+	firstRetryDelay     = time.Minute
+	// maxFederationBody caps documents read from or posted by other
+	// servers.
+	maxFederationBody = 1 << 20
+
+	federationTimeout      = 10 * time.Second
+	federationDialTimeout  = 5 * time.Second
+	federationMaxRedirects = 5
+
+	// An inbox request signed with a key we don't know makes us fetch the
+	// key's owner, at a URL the sender chose. These cap how often that
+	// happens, per host and overall.
+	keyFetchesPerHost = 10
+	keyFetchesTotal   = 120
+	keyFetchWindow    = time.Minute
+)
+
+var errKeyFetchLimited = errors.New("too many key fetches")
+
+// newFederationClient returns the client used for every request to other
+// servers. The URLs come from remote documents and signatures, so it only
+// dials public addresses, except in development.
+func newFederationClient(platform string) *http.Client {
+	dev := platform == "dev"
+	return safehttp.NewClient(safehttp.Options{
+		Timeout:      federationTimeout,
+		DialTimeout:  federationDialTimeout,
+		MaxRedirects: federationMaxRedirects,
+		CheckURL:     func(u *url.URL) error { return checkFederationURL(u, dev) },
+		AllowPrivate: dev,
+	})
+}
+
+// checkFederationURL reports whether u may be requested. Only https is
+// allowed, or http in development.
+func checkFederationURL(u *url.URL, dev bool) error {
+	if (u.Scheme != "https" && !(u.Scheme == "http" && dev)) || u.Hostname() == "" || u.User != nil {
+		return fmt.Errorf("refusing to request %q", u.Redacted())
+	}
+	return nil
+}
+
+// allowKeyFetch reports whether the key owner at uri may be fetched now.
+func (cfg *apiConfig) allowKeyFetch(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	return cfg.KeyFetchesPerHost.Allow(strings.ToLower(u.Hostname())) && cfg.KeyFetches.Allow("")
+}
+
+// actorURI is the ActivityPub ID of a local user.
+func (cfg *apiConfig) actorURI(userID uuid.UUID) string {
+	return cfg.PublicURL + "/users/" + userID.String()
+}
+
+func (cfg *apiConfig) actorKeyID(userID uuid.UUID) string {
+	return cfg.actorURI(userID) + "#main-key"
+}
+
+func (cfg *apiConfig) noteURI(chirpID uuid.UUID) string {
+	return cfg.PublicURL + "/chirps/" + chirpID.String()
+}
+
+// localActorID returns the user whose actor URI is uri.
+func (cfg *apiConfig) localActorID(uri string) (uuid.UUID, bool) {
+	rest, ok := strings.CutPrefix(uri, cfg.PublicURL+"/users/")
+	if !ok {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(rest)
+	return id, err == nil
+}
+
+// federationDomain is the domain in this server's fediverse accounts.
+func (cfg *apiConfig) federationDomain() string {
+	u, err := url.Parse(cfg.PublicURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// actorKey returns userID's signing key, creating it the first time.
+func (cfg *apiConfig) actorKey(ctx context.Context, userID uuid.UUID) (database.ActorKey, error) {
+	key, err := cfg.DB.GetActorKey(ctx, userID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return key, err
+	}
+
+	privatePEM, publicPEM, err := httpsig.GenerateKey()
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+	// Two requests may race to create the key; the first one wins and
+	// both read it back.
+	err = cfg.DB.CreateActorKey(ctx, database.CreateActorKeyParams{
+		UserID:        userID,
+		PublicKeyPem:  publicPEM,
+		PrivateKeyPem: privatePEM,
+	})
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+	return cfg.DB.GetActorKey(ctx, userID)
+}
+
+// chirpAudience returns who a chirp is addressed to when it's federated,
+// or false for chirps that stay on this server: rechirps, and chirps
+// limited to mentioned users.
+func (cfg *apiConfig) chirpAudience(chirp database.Chirp) (to, cc []string, ok bool) {
+	if chirp.RechirpOf.Valid {
+		return nil, nil, false
+	}
+	followers := cfg.actorURI(chirp.UserID) + "/followers"
+	switch visibility.Level(chirp.Visibility) {
+	case visibility.Public:
+		return []string{activitypub.Public}, []string{followers}, true
+	case visibility.Followers:
+		return []string{followers}, nil, true
+	default:
+		return nil, nil, false
+	}
+}
+
+func (cfg *apiConfig) chirpNote(chirp database.Chirp, to, cc []string) activitypub.Note {
+	return activitypub.Note{
+		ID:           cfg.noteURI(chirp.ID),
+		Type:         activitypub.TypeNote,
+		AttributedTo: cfg.actorURI(chirp.UserID),
+		Summary:      chirp.ContentWarning,
+		Sensitive:    chirp.SensitiveMedia || chirp.ContentWarning != "",
+		Content:      activitypub.NoteContent(chirp.Body),
+		URL:          cfg.PublicURL + "/api/chirps/" + chirp.ID.String(),
+		Published:    chirp.CreatedAt.UTC(),
+		To:           to,
+		Cc:           cc,
+	}
+}
+
+// chirpActivity returns the Create or Delete activity for chirp.
+func (cfg *apiConfig) chirpActivity(kind string, chirp database.Chirp) (activitypub.Activity, bool, error) {
+	to, cc, ok := cfg.chirpAudience(chirp)
+	if !ok {
+		return activitypub.Activity{}, false, nil
+	}
+
+	noteURI := cfg.noteURI(chirp.ID)
+	var object any = cfg.chirpNote(chirp, to, cc)
+	if kind == activitypub.TypeDelete {
+		object = activitypub.Tombstone{ID: noteURI, Type: "Tombstone"}
+	}
+	activity, err := activitypub.NewActivity(noteURI+"#"+strings.ToLower(kind), kind, cfg.actorURI(chirp.UserID), object, to, cc)
+	return activity, true, err
+}
+
+// federateChirp queues kind (Create or Delete) for chirp to the inboxes of
+// its author's remote followers, as part of the transaction in qtx.
+func (cfg *apiConfig) federateChirp(ctx context.Context, qtx *database.Queries, kind string, chirp database.Chirp) error {
+	activity, ok, err := cfg.chirpActivity(kind, chirp)
+	if err != nil || !ok {
+		return err
+	}
+	payload, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	return qtx.EnqueueFollowerDeliveries(ctx, database.EnqueueFollowerDeliveriesParams{
+		UserID:   chirp.UserID,
+		Activity: string(payload),
+	})
+}
+
+// sendActivity queues activity from userID to a single inbox.
+func sendActivity(ctx context.Context, q *database.Queries, userID uuid.UUID, inbox string, activity activitypub.Activity) error {
+	payload, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	return q.EnqueueDelivery(ctx, database.EnqueueDeliveryParams{
+		UserID:   userID,
+		InboxURL: inbox,
+		Activity: string(payload),
+	})
+}
+
+// runFederationDelivery posts queued activities to remote inboxes until
+// ctx is cancelled. Like the chirp scheduler, every instance runs one and
+// row locks keep two instances from posting the same delivery.
+func (cfg *apiConfig) runFederationDelivery(ctx context.Context) {
+	ticker := time.NewTicker(deliveryInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			delivered, err := cfg.deliverNextActivity(ctx)
+			if err != nil {
+				log.Printf("Couldn't process federation delivery: %v", err)
+				break
+			}
+			if !delivered {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverNextActivity posts the next due delivery. A failed post is
+// retried later with backoff until maxDeliveryAttempts. It reports false
+// when nothing was due.
+func (cfg *apiConfig) deliverNextActivity(ctx context.Context) (bool, error) {
+	tx, err := cfg.DBConn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	delivery, err := qtx.ClaimDueDelivery(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	postErr := cfg.postActivity(ctx, delivery)
+	var permanent *permanentDeliveryError
+	switch {
+	case postErr == nil:
+		err = qtx.DeleteDelivery(ctx, delivery.ID)
+	case errors.As(postErr, &permanent) || delivery.Attempts+1 >= maxDeliveryAttempts:
+		log.Printf("Giving up delivering to %s: %v", delivery.InboxURL, postErr)
+		err = qtx.DeleteDelivery(ctx, delivery.ID)
+	default:
+		err = qtx.RetryDelivery(ctx, database.RetryDeliveryParams{
+			ID:            delivery.ID,
+			NextAttemptAt: time.Now().UTC().Add(firstRetryDelay << delivery.Attempts),
+			LastError:     postErr.Error(),
+		})
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// permanentDeliveryError is a delivery the remote server refused in a way
+// retrying won't fix.
+type permanentDeliveryError struct {
+	err error
+}
+
+func (e *permanentDeliveryError) Error() string { return e.err.Error() }
+
+func (cfg *apiConfig) postActivity(ctx context.Context, delivery database.FederationDelivery) error {
+	key, err := cfg.actorKey(ctx, delivery.UserID)
+	if err != nil {
+		return err
+	}
+	privateKey, err := httpsig.ParsePrivateKey(key.PrivateKeyPem)
+	if err != nil {
+		return err
+	}
+
+	inbox, err := url.Parse(delivery.InboxURL)
+	if err == nil {
+		err = checkFederationURL(inbox, cfg.Platform == "dev")
+	}
+	if err != nil {
+		return &permanentDeliveryError{err: err}
+	}
+
+	body := []byte(delivery.Activity)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.InboxURL, bytes.NewReader(body))
+	if err != nil {
+		return &permanentDeliveryError{err: err}
+	}
+	req.Header.Set("Content-Type", activitypub.ContentType)
+	if err := httpsig.Sign(req, cfg.actorKeyID(delivery.UserID), privateKey, body); err != nil {
+		return err
+	}
+
+	resp, err := cfg.HTTPClient.Do(req)
+	if errors.Is(err, safehttp.ErrPrivateAddress) {
+		return &permanentDeliveryError{err: err}
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxFederationBody))
+
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("inbox responded %d", resp.StatusCode)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentDeliveryError{err: err}
+	}
+	return err
+}
+
+// fetchJSON GETs an ActivityPub or WebFinger document from another server.
+func (cfg *apiConfig) fetchJSON(ctx context.Context, rawURL, accept string, v any) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if err := checkFederationURL(u, cfg.Platform == "dev"); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept)
+
+	resp, err := cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxFederationBody)).Decode(v)
+}
+
+// fetchRemoteActor loads the actor document at uri and caches it. The
+// document has to describe itself as uri, so a server can only speak for
+// its own actors.
+func (cfg *apiConfig) fetchRemoteActor(ctx context.Context, uri string) (database.RemoteActor, error) {
+	var actor activitypub.Actor
+	if err := cfg.fetchJSON(ctx, uri, activitypub.ContentType, &actor); err != nil {
+		return database.RemoteActor{}, err
+	}
+	if actor.ID != uri || actor.PublicKey.Owner != uri || actor.Inbox == "" || actor.PublicKey.PublicKeyPem == "" {
+		return database.RemoteActor{}, fmt.Errorf("invalid actor document at %s", uri)
+	}
+	if _, err := httpsig.ParsePublicKey(actor.PublicKey.PublicKeyPem); err != nil {
+		return database.RemoteActor{}, fmt.Errorf("invalid key for %s: %w", uri, err)
+	}
+	// Activities are posted to the inboxes, so they're held to the same
+	// rules as any other federation URL.
+	inboxes := []string{actor.Inbox}
+	if actor.Endpoints != nil && actor.Endpoints.SharedInbox != "" {
+		inboxes = append(inboxes, actor.Endpoints.SharedInbox)
+	}
+	for _, inbox := range inboxes {
+		u, err := url.Parse(inbox)
+		if err == nil {
+			err = checkFederationURL(u, cfg.Platform == "dev")
+		}
+		if err != nil {
+			return database.RemoteActor{}, fmt.Errorf("invalid inbox for %s: %w", uri, err)
+		}
+	}
+
+	u, err := url.Parse(actor.ID)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+	params := database.UpsertRemoteActorParams{
+		URI:          actor.ID,
+		Username:     actor.PreferredUsername,
+		Domain:       strings.ToLower(u.Host),
+		DisplayName:  actor.Name,
+		InboxURL:     actor.Inbox,
+		KeyID:        actor.PublicKey.ID,
+		PublicKeyPem: actor.PublicKey.PublicKeyPem,
+	}
+	if actor.Endpoints != nil {
+		params.SharedInboxURL = actor.Endpoints.SharedInbox
+	}
+	return cfg.DB.UpsertRemoteActor(ctx, params)
+}
+
+// resolveAccount finds a remote account such as alice@example.com through
+// WebFinger and caches its actor.
+func (cfg *apiConfig) resolveAccount(ctx context.Context, account string) (database.RemoteActor, error) {
+	user, domain, err := activitypub.ParseAccount(account)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+
+	scheme := "https"
+	if cfg.Platform == "dev" {
+		scheme = "http"
+	}
+	query := url.Values{"resource": {"acct:" + user + "@" + domain}}
+	var finger activitypub.WebFinger
+	err = cfg.fetchJSON(ctx, scheme+"://"+domain+"/.well-known/webfinger?"+query.Encode(), activitypub.JRDContentType, &finger)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+
+	uri, ok := finger.SelfLink()
+	if !ok {
+		return database.RemoteActor{}, fmt.Errorf("%s has no ActivityPub actor", account)
+	}
+	return cfg.fetchRemoteActor(ctx, uri)
+}
@@ -0,0 +1,724 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/activitypub"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/httpsig"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pagination"
+)
+
+// outboxSize is how many recent activities an actor's outbox lists.
+const outboxSize = 20
+
+// errBadActivity is an activity from another server that can't be
+// accepted as sent.
+var errBadActivity = errors.New("invalid activity")
+
+func respondWithActivity(w http.ResponseWriter, code int, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't encode response", err)
+		return
+	}
+	w.Header().Set("Content-Type", activitypub.ContentType)
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// webfingerHandler answers WebFinger lookups of acct:handle@domain, which
+// is how other servers find a local user's actor.
+func (cfg *apiConfig) webfingerHandler(w http.ResponseWriter, r *http.Request) {
+	handle, domain, err := activitypub.ParseAccount(r.URL.Query().Get("resource"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid resource", err)
+		return
+	}
+	if domain != cfg.federationDomain() {
+		respondWithError(w, http.StatusNotFound, "Unknown domain", nil)
+		return
+	}
+
+	user, err := cfg.DB.GetUserByHandle(r.Context(), handle)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't look up user", err)
+		return
+	}
+
+	actorURI := cfg.actorURI(user.ID)
+	data, err := json.Marshal(activitypub.WebFinger{
+		Subject: "acct:" + user.Handle.String + "@" + domain,
+		Aliases: []string{actorURI},
+		Links: []activitypub.WebFingerLink{
+			{Rel: "self", Type: activitypub.ContentType, Href: actorURI},
+			{Rel: "http://webfinger.net/rel/profile-page", Href: cfg.PublicURL + "/api/users/" + url.PathEscape(user.Handle.String)},
+		},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't encode response", err)
+		return
+	}
+	w.Header().Set("Content-Type", activitypub.JRDContentType)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(data)
+}
+
+// actorHandler serves a local user's actor document.
+func (cfg *apiConfig) actorHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.loadActorUser(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := cfg.actorKey(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load actor", err)
+		return
+	}
+
+	actorURI := cfg.actorURI(user.ID)
+	actor := activitypub.Actor{
+		Context:           activitypub.Context,
+		ID:                actorURI,
+		Type:              "Person",
+		PreferredUsername: user.Handle.String,
+		Name:              user.DisplayName,
+		Summary:           user.Bio,
+		Inbox:             actorURI + "/inbox",
+		Outbox:            actorURI + "/outbox",
+		Followers:         actorURI + "/followers",
+		Endpoints:         &activitypub.Endpoints{SharedInbox: cfg.PublicURL + "/inbox"},
+		PublicKey: activitypub.PublicKey{
+			ID:           cfg.actorKeyID(user.ID),
+			Owner:        actorURI,
+			PublicKeyPem: key.PublicKeyPem,
+		},
+	}
+	if user.Handle.Valid {
+		actor.URL = cfg.PublicURL + "/api/users/" + url.PathEscape(user.Handle.String)
+	} else {
+		actor.PreferredUsername = user.ID.String()
+	}
+
+	respondWithActivity(w, http.StatusOK, actor)
+}
+
+// outboxHandler lists a user's most recent public chirps as Create
+// activities.
+func (cfg *apiConfig) outboxHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.loadActorUser(w, r)
+	if !ok {
+		return
+	}
+
+	total, err := cfg.DB.CountPublicChirpsByAuthor(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load outbox", err)
+		return
+	}
+	chirps, err := cfg.DB.GetRecentPublicChirpsByAuthor(r.Context(), database.GetRecentPublicChirpsByAuthorParams{
+		UserID:   user.ID,
+		PageSize: outboxSize,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load outbox", err)
+		return
+	}
+
+	outbox := activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         cfg.actorURI(user.ID) + "/outbox",
+		Type:       "OrderedCollection",
+		TotalItems: total,
+	}
+	for _, chirp := range chirps {
+		activity, ok, err := cfg.chirpActivity(activitypub.TypeCreate, chirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't load outbox", err)
+			return
+		}
+		if !ok {
+			continue
+		}
+		activity.Context = nil
+		outbox.OrderedItems = append(outbox.OrderedItems, activity)
+	}
+
+	respondWithActivity(w, http.StatusOK, outbox)
+}
+
+// followersCollectionHandler reports how many followers a user has, local
+// and remote. The followers themselves aren't listed.
+func (cfg *apiConfig) followersCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.loadActorUser(w, r)
+	if !ok {
+		return
+	}
+
+	counts, err := cfg.DB.GetFollowCounts(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load followers", err)
+		return
+	}
+	remote, err := cfg.DB.CountRemoteFollowers(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load followers", err)
+		return
+	}
+
+	respondWithActivity(w, http.StatusOK, activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         cfg.actorURI(user.ID) + "/followers",
+		Type:       "OrderedCollection",
+		TotalItems: counts.FollowerCount + remote,
+	})
+}
+
+func (cfg *apiConfig) loadActorUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return database.User{}, false
+	}
+	user, err := cfg.DB.GetUser(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return database.User{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load user", err)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// noteHandler serves a public chirp as a Note, so other servers can fetch
+// the objects they've been sent.
+func (cfg *apiConfig) noteHandler(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+
+	chirp, err := cfg.DB.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{
+		ID:       chirpID,
+		ViewerID: uuid.Nil,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp", err)
+		return
+	}
+
+	to, cc, ok := cfg.chirpAudience(chirp)
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", nil)
+		return
+	}
+	note := cfg.chirpNote(chirp, to, cc)
+	note.Context = activitypub.Context
+	respondWithActivity(w, http.StatusOK, note)
+}
+
+// inboxHandler receives activities from other servers, both on each
+// user's inbox and on the shared inbox. Every request has to be signed by
+// the actor it claims to come from.
+func (cfg *apiConfig) inboxHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxFederationBody))
+	if err != nil {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Activity too large", err)
+		return
+	}
+
+	actor, err := cfg.verifyInboxRequest(r, body)
+	if errors.Is(err, errKeyFetchLimited) {
+		respondWithError(w, http.StatusTooManyRequests, "Too many requests with unknown keys", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid signature", err)
+		return
+	}
+
+	var activity activitypub.Activity
+	if err := json.Unmarshal(body, &activity); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid activity", err)
+		return
+	}
+	if activity.Actor != actor.URI {
+		respondWithError(w, http.StatusUnauthorized, "Activity isn't from the signing actor", nil)
+		return
+	}
+
+	switch activity.Type {
+	case activitypub.TypeFollow:
+		err = cfg.receiveFollow(r.Context(), actor, activity)
+	case activitypub.TypeUndo:
+		err = cfg.receiveUndo(r.Context(), actor, activity)
+	case activitypub.TypeAccept:
+		err = cfg.receiveAccept(r.Context(), actor, activity)
+	case activitypub.TypeCreate:
+		err = cfg.receiveCreate(r.Context(), actor, activity)
+	case activitypub.TypeDelete:
+		err = cfg.receiveDelete(r.Context(), actor, activity)
+	}
+	// Other activity types are accepted and ignored.
+	if errors.Is(err, errBadActivity) {
+		respondWithError(w, http.StatusBadRequest, "Invalid activity", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't process activity", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// verifyInboxRequest checks the request's signature and returns the remote
+// actor who made it. An unknown key is fetched from its owner, and a key
+// that no longer verifies is fetched again in case it was rotated.
+func (cfg *apiConfig) verifyInboxRequest(r *http.Request, body []byte) (database.RemoteActor, error) {
+	sig, err := httpsig.Parse(r)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+
+	fetched := false
+	actor, err := cfg.DB.GetRemoteActorByKeyID(r.Context(), sig.KeyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		owner, _, _ := strings.Cut(sig.KeyID, "#")
+		if !cfg.allowKeyFetch(owner) {
+			return database.RemoteActor{}, errKeyFetchLimited
+		}
+		actor, err = cfg.fetchRemoteActor(r.Context(), owner)
+		fetched = true
+	}
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+
+	err = verifyActorSignature(r, body, sig, actor)
+	if errors.Is(err, httpsig.ErrVerification) && !fetched {
+		if !cfg.allowKeyFetch(actor.URI) {
+			return database.RemoteActor{}, errKeyFetchLimited
+		}
+		actor, err = cfg.fetchRemoteActor(r.Context(), actor.URI)
+		if err != nil {
+			return database.RemoteActor{}, err
+		}
+		err = verifyActorSignature(r, body, sig, actor)
+	}
+	return actor, err
+}
+
+func verifyActorSignature(r *http.Request, body []byte, sig httpsig.Signature, actor database.RemoteActor) error {
+	if actor.KeyID != sig.KeyID {
+		return httpsig.ErrVerification
+	}
+	key, err := httpsig.ParsePublicKey(actor.PublicKeyPem)
+	if err != nil {
+		return err
+	}
+	return httpsig.Verify(r, body, sig, key, time.Now())
+}
+
+// receiveFollow records a remote follower and accepts the follow.
+func (cfg *apiConfig) receiveFollow(ctx context.Context, actor database.RemoteActor, follow activitypub.Activity) error {
+	userID, ok := cfg.localActorID(follow.ObjectID())
+	if !ok {
+		return errBadActivity
+	}
+	if _, err := cfg.DB.GetUser(ctx, userID); errors.Is(err, sql.ErrNoRows) {
+		return errBadActivity
+	} else if err != nil {
+		return err
+	}
+
+	actorURI := cfg.actorURI(userID)
+	accept, err := activitypub.NewActivity(actorURI+"#accepts/"+uuid.NewString(), activitypub.TypeAccept, actorURI, follow, []string{actor.URI}, nil)
+	if err != nil {
+		return err
+	}
+
+	tx, err := cfg.DBConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	err = qtx.AddRemoteFollower(ctx, database.AddRemoteFollowerParams{
+		UserID:    userID,
+		ActorID:   actor.ID,
+		FollowURI: follow.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if err := sendActivity(ctx, qtx, userID, actor.InboxURL, accept); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// receiveUndo handles an Undo of a Follow. Undoing anything else is
+// ignored.
+func (cfg *apiConfig) receiveUndo(ctx context.Context, actor database.RemoteActor, undo activitypub.Activity) error {
+	var follow activitypub.Activity
+	if json.Unmarshal(undo.Object, &follow) != nil || follow.Type != activitypub.TypeFollow {
+		return nil
+	}
+	if follow.Actor != "" && follow.Actor != actor.URI {
+		return errBadActivity
+	}
+	userID, ok := cfg.localActorID(follow.ObjectID())
+	if !ok {
+		return nil
+	}
+	return cfg.DB.RemoveRemoteFollower(ctx, database.RemoveRemoteFollowerParams{
+		UserID:  userID,
+		ActorID: actor.ID,
+	})
+}
+
+// receiveAccept marks a local user's follow of actor as accepted.
+func (cfg *apiConfig) receiveAccept(ctx context.Context, actor database.RemoteActor, accept activitypub.Activity) error {
+	_, err := cfg.DB.AcceptRemoteFollow(ctx, database.AcceptRemoteFollowParams{
+		ActorID:   actor.ID,
+		FollowURI: accept.ObjectID(),
+	})
+	return err
+}
+
+// receiveCreate stores a Note from a remote account a local user follows.
+// Notes from accounts no one here follows are dropped.
+func (cfg *apiConfig) receiveCreate(ctx context.Context, actor database.RemoteActor, create activitypub.Activity) error {
+	if create.ObjectType() != activitypub.TypeNote {
+		return nil
+	}
+	var note activitypub.Note
+	if err := json.Unmarshal(create.Object, &note); err != nil {
+		return errBadActivity
+	}
+	// A server can only create notes for its own actors, under its own
+	// domain.
+	noteURL, err := url.Parse(note.ID)
+	if err != nil || note.AttributedTo != actor.URI || !strings.EqualFold(noteURL.Host, actor.Domain) {
+		return errBadActivity
+	}
+
+	followed, err := cfg.DB.IsRemoteActorFollowed(ctx, actor.ID)
+	if err != nil || !followed {
+		return err
+	}
+
+	published := note.Published
+	if published.IsZero() {
+		published = time.Now()
+	}
+	return cfg.DB.CreateRemoteNote(ctx, database.CreateRemoteNoteParams{
+		URI:         note.ID,
+		ActorID:     actor.ID,
+		PublishedAt: published.UTC(),
+		URL:         note.URL,
+		Body:        activitypub.PlainText(note.Content),
+	})
+}
+
+// receiveDelete removes a note actor deleted.
+func (cfg *apiConfig) receiveDelete(ctx context.Context, actor database.RemoteActor, del activitypub.Activity) error {
+	return cfg.DB.DeleteRemoteNote(ctx, database.DeleteRemoteNoteParams{
+		URI:     del.ObjectID(),
+		ActorID: actor.ID,
+	})
+}
+
+// followActivity is userID's Follow of actor.
+func (cfg *apiConfig) followActivity(userID uuid.UUID, followURI string, actor database.RemoteActor) (activitypub.Activity, error) {
+	return activitypub.NewActivity(followURI, activitypub.TypeFollow, cfg.actorURI(userID), actor.URI, []string{actor.URI}, nil)
+}
+
+type remoteFollowRequest struct {
+	// Account is a fediverse account such as alice@example.com.
+	Account string `json:"account"`
+}
+
+type remoteFollowResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Account     string    `json:"account"`
+	URI         string    `json:"uri"`
+	DisplayName string    `json:"display_name"`
+	// Accepted is false until the remote server accepts the follow.
+	Accepted  bool      `json:"accepted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// createRemoteFollowHandler follows an account on another server. The
+// follow is pending until that server accepts it.
+func (cfg *apiConfig) createRemoteFollowHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	user, err := cfg.DB.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found", err)
+		return
+	}
+	if respondIfSuspended(w, user) {
+		return
+	}
+
+	var req remoteFollowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if _, domain, err := activitypub.ParseAccount(req.Account); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	} else if domain == cfg.federationDomain() {
+		respondWithError(w, http.StatusBadRequest, "That account is on this server, follow it with POST /api/users/{userID}/follow", nil)
+		return
+	}
+
+	actor, err := cfg.resolveAccount(r.Context(), req.Account)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Couldn't find that account", err)
+		return
+	}
+
+	followURI := cfg.actorURI(userID) + "#follows/" + uuid.NewString()
+	follow, err := cfg.followActivity(userID, followURI, actor)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow account", err)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow account", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	err = qtx.CreateRemoteFollow(r.Context(), database.CreateRemoteFollowParams{
+		UserID:    userID,
+		ActorID:   actor.ID,
+		FollowURI: followURI,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow account", err)
+		return
+	}
+	if err := sendActivity(r.Context(), qtx, userID, actor.InboxURL, follow); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow account", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow account", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, remoteFollowResponse{
+		ID:          actor.ID,
+		Account:     actor.Username + "@" + actor.Domain,
+		URI:         actor.URI,
+		DisplayName: actor.DisplayName,
+		CreatedAt:   time.Now().UTC(),
+	})
+}
+
+// getRemoteFollowsHandler lists the remote accounts the caller follows.
+func (cfg *apiConfig) getRemoteFollowsHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	rows, err := cfg.DB.GetRemoteFollows(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve follows", err)
+		return
+	}
+
+	response := make([]remoteFollowResponse, 0, len(rows))
+	for _, row := range rows {
+		response = append(response, remoteFollowResponse{
+			ID:          row.ID,
+			Account:     row.Username + "@" + row.Domain,
+			URI:         row.URI,
+			DisplayName: row.DisplayName,
+			Accepted:    row.AcceptedAt.Valid,
+			CreatedAt:   row.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// deleteRemoteFollowHandler unfollows a remote account and tells its
+// server with an Undo.
+func (cfg *apiConfig) deleteRemoteFollowHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	actorID, err := uuid.Parse(r.PathValue("actorID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid account ID", err)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow account", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	followURI, err := qtx.DeleteRemoteFollow(r.Context(), database.DeleteRemoteFollowParams{
+		UserID:  userID,
+		ActorID: actorID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "You don't follow that account", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow account", err)
+		return
+	}
+
+	actor, err := qtx.GetRemoteActor(r.Context(), actorID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow account", err)
+		return
+	}
+	follow, err := cfg.followActivity(userID, followURI, actor)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow account", err)
+		return
+	}
+	follow.Context = nil
+	actorURI := cfg.actorURI(userID)
+	undo, err := activitypub.NewActivity(actorURI+"#undos/"+uuid.NewString(), activitypub.TypeUndo, actorURI, follow, []string{actor.URI}, nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow account", err)
+		return
+	}
+	if err := sendActivity(r.Context(), qtx, userID, actor.InboxURL, undo); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow account", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow account", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type remoteNoteResponse struct {
+	ID          uuid.UUID `json:"id"`
+	URI         string    `json:"uri"`
+	URL         string    `json:"url,omitempty"`
+	Body        string    `json:"body"`
+	PublishedAt time.Time `json:"published_at"`
+	ReceivedAt  time.Time `json:"received_at"`
+	Author      struct {
+		Account     string `json:"account"`
+		URI         string `json:"uri"`
+		DisplayName string `json:"display_name"`
+	} `json:"author"`
+}
+
+type remoteNoteListResponse struct {
+	Notes      []remoteNoteResponse `json:"notes"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// getRemoteNotesHandler lists posts from remote accounts the caller
+// follows, most recently received first.
+func (cfg *apiConfig) getRemoteNotesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, cursor, limit, ok := cfg.parseOwnListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.DB.GetRemoteNotesForUser(r.Context(), database.GetRemoteNotesForUserParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve notes", err)
+		return
+	}
+
+	response := remoteNoteListResponse{Notes: make([]remoteNoteResponse, 0, len(rows))}
+	for _, row := range rows {
+		note := remoteNoteResponse{
+			ID:          row.ID,
+			URI:         row.URI,
+			URL:         row.URL,
+			Body:        row.Body,
+			PublishedAt: row.PublishedAt,
+			ReceivedAt:  row.CreatedAt,
+		}
+		note.Author.Account = row.Username + "@" + row.Domain
+		note.Author.URI = row.ActorURI
+		note.Author.DisplayName = row.DisplayName
+		response.Notes = append(response.Notes, note)
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		response.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
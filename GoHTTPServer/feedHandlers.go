@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/entities"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/feed"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pagination"
+)
+
+// feedSize is how many of the newest chirps a feed holds. Feeds are read
+// without signing in, so they only ever hold public chirps.
+const feedSize = 50
+
+func (cfg *apiConfig) userAtomFeedHandler(w http.ResponseWriter, r *http.Request) {
+	f, ok := cfg.loadUserFeed(w, r, "atom")
+	if ok {
+		serveFeed(w, r, f, feed.Atom, feed.AtomContentType)
+	}
+}
+
+func (cfg *apiConfig) userRSSFeedHandler(w http.ResponseWriter, r *http.Request) {
+	f, ok := cfg.loadUserFeed(w, r, "rss")
+	if ok {
+		serveFeed(w, r, f, feed.RSS, feed.RSSContentType)
+	}
+}
+
+func (cfg *apiConfig) tagAtomFeedHandler(w http.ResponseWriter, r *http.Request) {
+	f, ok := cfg.loadTagFeed(w, r, "atom")
+	if ok {
+		serveFeed(w, r, f, feed.Atom, feed.AtomContentType)
+	}
+}
+
+func (cfg *apiConfig) tagRSSFeedHandler(w http.ResponseWriter, r *http.Request) {
+	f, ok := cfg.loadTagFeed(w, r, "rss")
+	if ok {
+		serveFeed(w, r, f, feed.RSS, feed.RSSContentType)
+	}
+}
+
+// loadUserFeed builds the feed of a user's own chirps. Rechirps are left
+// out since they have no text of their own.
+func (cfg *apiConfig) loadUserFeed(w http.ResponseWriter, r *http.Request, ext string) (feed.Feed, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return feed.Feed{}, false
+	}
+
+	user, err := cfg.DB.GetUser(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return feed.Feed{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load feed", err)
+		return feed.Feed{}, false
+	}
+
+	chirps, err := cfg.DB.GetRecentPublicChirpsByAuthor(r.Context(), database.GetRecentPublicChirpsByAuthorParams{
+		UserID:   userID,
+		PageSize: feedSize,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load feed", err)
+		return feed.Feed{}, false
+	}
+
+	name := feedAuthorName(user)
+	f := feed.Feed{
+		Title:       "Chirps by " + name,
+		Description: "The latest public chirps by " + name + " on Chirpy",
+		Link:        cfg.PublicURL + "/api/users/" + url.PathEscape(user.Handle.String),
+		SelfURL:     cfg.PublicURL + userFeedPath(userID, ext),
+	}
+	if !user.Handle.Valid {
+		f.Link = f.SelfURL
+	}
+	for _, chirp := range chirps {
+		f.Entries = append(f.Entries, cfg.feedEntry(chirp, name))
+	}
+
+	return f, true
+}
+
+// loadTagFeed builds the feed of chirps using a hashtag.
+func (cfg *apiConfig) loadTagFeed(w http.ResponseWriter, r *http.Request, ext string) (feed.Feed, bool) {
+	tag := entities.NormalizeTag(r.PathValue("tag"))
+	if tag == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid tag", nil)
+		return feed.Feed{}, false
+	}
+
+	start := pagination.Start()
+	chirps, err := cfg.DB.GetChirpsByTag(r.Context(), database.GetChirpsByTagParams{
+		Tag:             tag,
+		ViewerID:        uuid.Nil,
+		BeforeCreatedAt: start.CreatedAt,
+		BeforeID:        start.ID,
+		PageSize:        feedSize,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load feed", err)
+		return feed.Feed{}, false
+	}
+
+	authorIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		authorIDs = append(authorIDs, chirp.UserID)
+	}
+	authors, err := cfg.DB.GetUsersByIDs(r.Context(), authorIDs)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load feed", err)
+		return feed.Feed{}, false
+	}
+	names := make(map[uuid.UUID]string, len(authors))
+	for _, author := range authors {
+		names[author.ID] = feedAuthorName(author)
+	}
+
+	f := feed.Feed{
+		Title:       "#" + tag + " on Chirpy",
+		Description: "The latest public chirps tagged #" + tag,
+		Link:        cfg.PublicURL + "/api/tags/" + url.PathEscape(tag) + "/chirps",
+		SelfURL:     cfg.PublicURL + tagFeedPath(tag, ext),
+	}
+	for _, chirp := range chirps {
+		f.Entries = append(f.Entries, cfg.feedEntry(chirp, names[chirp.UserID]))
+	}
+
+	return f, true
+}
+
+func (cfg *apiConfig) feedEntry(chirp database.Chirp, author string) feed.Entry {
+	return feed.Entry{
+		Link:      cfg.PublicURL + "/api/chirps/" + chirp.ID.String(),
+		Author:    author,
+		Body:      chirp.Body,
+		Published: chirp.CreatedAt,
+		Updated:   chirp.UpdatedAt,
+
+		ContentWarning: chirp.ContentWarning,
+	}
+}
+
+// feedAuthorName is how a user is credited in feeds.
+func feedAuthorName(user database.User) string {
+	switch {
+	case user.DisplayName != "":
+		return user.DisplayName
+	case user.Handle.Valid:
+		return "@" + user.Handle.String
+	default:
+		return "Chirpy user"
+	}
+}
+
+// serveFeed renders f and writes it, answering conditional requests. The
+// ETag covers the whole document, so it also changes when a chirp is
+// deleted, which Last-Modified alone would miss.
+func serveFeed(w http.ResponseWriter, r *http.Request, f feed.Feed, render func(feed.Feed) ([]byte, error), contentType string) {
+	doc, err := render(f)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't render feed", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", feed.ETag(doc))
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	http.ServeContent(w, r, "", f.Updated().Truncate(time.Second), bytes.NewReader(doc))
+}
+
+func userFeedPath(userID uuid.UUID, ext string) string {
+	return fmt.Sprintf("/users/%s/feed.%s", userID, ext)
+}
+
+func tagFeedPath(tag, ext string) string {
+	return "/tags/" + url.PathEscape(tag) + "/feed." + ext
+}
+
+// setFeedLinks advertises a resource's Atom and RSS feeds in Link headers,
+// so clients can discover them from the API response they already have.
+func (cfg *apiConfig) setFeedLinks(w http.ResponseWriter, atomPath, rssPath, title string) {
+	w.Header().Add("Link", fmt.Sprintf(`<%s%s>; rel="alternate"; type="application/atom+xml"; title=%q`, cfg.PublicURL, atomPath, title))
+	w.Header().Add("Link", fmt.Sprintf(`<%s%s>; rel="alternate"; type="application/rss+xml"; title=%q`, cfg.PublicURL, rssPath, title))
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/notify"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pagination"
+)
+
+func (cfg *apiConfig) followHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	followerID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	if followeeID == followerID {
+		respondWithError(w, http.StatusBadRequest, "You can't follow yourself", nil)
+		return
+	}
+
+	blocked, err := cfg.DB.IsBlockedBetween(r.Context(), database.IsBlockedBetweenParams{
+		UserA: followerID,
+		UserB: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow user", err)
+		return
+	}
+	if blocked {
+		respondWithError(w, http.StatusForbidden, "You can't follow this user", nil)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow user", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	err = qtx.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if isForeignKeyViolation(err) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow user", err)
+		return
+	}
+
+	err = notifyUser(r.Context(), qtx, notification{
+		Recipient: followeeID,
+		Actor:     followerID,
+		Type:      notify.TypeFollow,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow user", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unfollowHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	followerID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	err = cfg.DB.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) getFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, viewerID, cursor, limit, ok := cfg.parseFollowListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.DB.GetFollowers(r.Context(), database.GetFollowersParams{
+		UserID:          userID,
+		ViewerID:        viewerID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve followers", err)
+		return
+	}
+
+	users := make([]followedUser, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, followedUser{UserID: row.UserID, FollowedAt: row.CreatedAt})
+	}
+	cfg.respondWithFollowList(w, r, userID, users, limit)
+}
+
+func (cfg *apiConfig) getFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	userID, viewerID, cursor, limit, ok := cfg.parseFollowListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.DB.GetFollowing(r.Context(), database.GetFollowingParams{
+		UserID:          userID,
+		ViewerID:        viewerID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve followed users", err)
+		return
+	}
+
+	users := make([]followedUser, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, followedUser{UserID: row.UserID, FollowedAt: row.CreatedAt})
+	}
+	cfg.respondWithFollowList(w, r, userID, users, limit)
+}
+
+// parseFollowListRequest reads the user whose follows are listed and the
+// page to show. Viewers who have a block with that user get a 404, as they
+// would for the profile.
+func (cfg *apiConfig) parseFollowListRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, pagination.Cursor, int32, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return uuid.Nil, uuid.Nil, pagination.Cursor{}, 0, false
+	}
+
+	cursor, err := pagination.Parse(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+		return uuid.Nil, uuid.Nil, pagination.Cursor{}, 0, false
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return uuid.Nil, uuid.Nil, pagination.Cursor{}, 0, false
+	}
+
+	viewerID := cfg.viewerID(r)
+	blocked, err := cfg.DB.IsBlockedBetween(r.Context(), database.IsBlockedBetweenParams{
+		UserA: viewerID,
+		UserB: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check blocks", err)
+		return uuid.Nil, uuid.Nil, pagination.Cursor{}, 0, false
+	}
+	if blocked {
+		respondWithError(w, http.StatusNotFound, "User not found", nil)
+		return uuid.Nil, uuid.Nil, pagination.Cursor{}, 0, false
+	}
+
+	return userID, viewerID, cursor, limit, true
+}
+
+func (cfg *apiConfig) respondWithFollowList(w http.ResponseWriter, r *http.Request, userID uuid.UUID, users []followedUser, limit int32) {
+	counts, err := cfg.DB.GetFollowCounts(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count follows", err)
+		return
+	}
+
+	response := followListResponse{
+		Users:          users,
+		FollowerCount:  counts.FollowerCount,
+		FollowingCount: counts.FollowingCount,
+	}
+	if len(users) == int(limit) {
+		last := users[len(users)-1]
+		response.NextCursor = pagination.Cursor{CreatedAt: last.FollowedAt, ID: last.UserID}.Encode()
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// timelineHandler serves the caller's home timeline: their own chirps and
+// those of everyone they follow, newest first. The feed is assembled at
+// read time from the follows table rather than fanned out on write.
+func (cfg *apiConfig) timelineHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	cursor, err := pagination.Parse(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+		return
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	chirpDB, err := cfg.DB.GetTimeline(r.Context(), database.GetTimelineParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve timeline", err)
+		return
+	}
+
+	cfg.respondWithChirpPage(w, r, userID, chirpDB, limit)
+}
+
+// respondWithChirpPage writes one page of a cursor-paginated chirp feed.
+// A next cursor is only included when the page came back full.
+func (cfg *apiConfig) respondWithChirpPage(w http.ResponseWriter, r *http.Request, viewerID uuid.UUID, chirpDB []database.Chirp, limit int32) {
+	chirps, err := cfg.buildChirpResponses(r.Context(), viewerID, chirpDB)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
+	}
+
+	response := chirpPageResponse{Chirps: chirps}
+	if len(chirpDB) == int(limit) {
+		last := chirpDB[len(chirpDB)-1]
+		response.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -2,9 +2,15 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"strings"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/lib/pq"
 )
 
 func respondWithError(w http.ResponseWriter, code int, msg string, err error) {
@@ -35,21 +41,73 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Write(dat)
 }
 
-func cleanWords(text string) string {
-	bannedWords := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert":  {},
-		"fornax":    {},
+// isUniqueViolation reports whether err came from Postgres rejecting a
+// duplicate value for a unique constraint or index.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isUniqueViolationOf is isUniqueViolation for one named constraint or
+// index, for tables with more than one.
+func isUniqueViolationOf(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+// isForeignKeyViolation reports whether err came from Postgres rejecting a
+// row that references something that doesn't exist.
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+// requireRole authenticates the request and checks that the caller has one
+// of the given roles. When it returns false an error response has already
+// been written.
+func (cfg *apiConfig) requireRole(w http.ResponseWriter, r *http.Request, roles ...string) (database.User, bool) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return database.User{}, false
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return database.User{}, false
 	}
 
-	body := strings.Split(text, " ")
+	user, err := cfg.DB.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found", err)
+		return database.User{}, false
+	}
 
-	for i, word := range body {
-		if _, ok := bannedWords[strings.ToLower(word)]; ok {
-			body[i] = "****"
-		}
+	if !slices.Contains(roles, user.Role) {
+		respondWithError(w, http.StatusForbidden, "You don't have permission to do that", nil)
+		return database.User{}, false
 	}
 
-	return strings.Join(body, " ")
+	return user, true
+}
 
+// viewerID identifies the caller of an endpoint that works with or without
+// a login, so results can respect their blocks and mutes. Anonymous
+// requests, and ones whose token doesn't validate, get uuid.Nil.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.UUID {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		return uuid.Nil
+	}
+	return userID
 }
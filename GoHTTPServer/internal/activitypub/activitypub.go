@@ -0,0 +1,200 @@
+// Package activitypub holds the ActivityStreams documents Chirpy exchanges
+// with other fediverse servers, and helpers for reading what they send.
+package activitypub
+
+import (
+	"encoding/json"
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// ContentType is the media type of ActivityPub documents.
+	ContentType = "application/activity+json"
+	// JRDContentType is the media type of WebFinger responses.
+	JRDContentType = "application/jrd+json"
+
+	// Public addresses an activity to everyone.
+	Public = "https://www.w3.org/ns/activitystreams#Public"
+
+	activityStreams = "https://www.w3.org/ns/activitystreams"
+	security        = "https://w3id.org/security/v1"
+)
+
+const (
+	TypeCreate = "Create"
+	TypeDelete = "Delete"
+	TypeFollow = "Follow"
+	TypeAccept = "Accept"
+	TypeUndo   = "Undo"
+	TypeNote   = "Note"
+)
+
+// Context is the @context of documents Chirpy serves.
+var Context = []string{activityStreams, security}
+
+type Actor struct {
+	Context           any        `json:"@context,omitempty"`
+	ID                string     `json:"id"`
+	Type              string     `json:"type"`
+	PreferredUsername string     `json:"preferredUsername"`
+	Name              string     `json:"name,omitempty"`
+	Summary           string     `json:"summary,omitempty"`
+	URL               string     `json:"url,omitempty"`
+	Inbox             string     `json:"inbox"`
+	Outbox            string     `json:"outbox,omitempty"`
+	Followers         string     `json:"followers,omitempty"`
+	Endpoints         *Endpoints `json:"endpoints,omitempty"`
+	PublicKey         PublicKey  `json:"publicKey"`
+}
+
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+type Note struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	AttributedTo string `json:"attributedTo"`
+	// Summary is the content warning. Sensitive marks media that should be
+	// hidden until clicked, the way Mastodon does.
+	Summary   string    `json:"summary,omitempty"`
+	Sensitive bool      `json:"sensitive,omitempty"`
+	Content   string    `json:"content"`
+	URL       string    `json:"url,omitempty"`
+	Published time.Time `json:"published"`
+	To        []string  `json:"to,omitempty"`
+	Cc        []string  `json:"cc,omitempty"`
+}
+
+// Tombstone replaces a deleted object.
+type Tombstone struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// Activity is an activity Chirpy sends or receives. Object is kept raw
+// because it's either an object's ID or the object itself.
+type Activity struct {
+	Context any             `json:"@context,omitempty"`
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	Actor   string          `json:"actor"`
+	Object  json.RawMessage `json:"object"`
+	To      []string        `json:"to,omitempty"`
+	Cc      []string        `json:"cc,omitempty"`
+}
+
+// NewActivity returns an activity with object embedded.
+func NewActivity(id, typ, actor string, object any, to, cc []string) (Activity, error) {
+	raw, err := json.Marshal(object)
+	if err != nil {
+		return Activity{}, err
+	}
+	return Activity{Context: activityStreams, ID: id, Type: typ, Actor: actor, Object: raw, To: to, Cc: cc}, nil
+}
+
+// ObjectID returns the ID of the activity's object, whether it was sent
+// as a bare ID or embedded.
+func (a Activity) ObjectID() string {
+	var id string
+	if json.Unmarshal(a.Object, &id) == nil {
+		return id
+	}
+	var object struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(a.Object, &object)
+	return object.ID
+}
+
+// ObjectType returns the type of an embedded object, or "" when the
+// object was sent as a bare ID.
+func (a Activity) ObjectType() string {
+	var object struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(a.Object, &object)
+	return object.Type
+}
+
+type OrderedCollection struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	TotalItems   int64  `json:"totalItems"`
+	OrderedItems []any  `json:"orderedItems,omitempty"`
+}
+
+// WebFinger is a JSON Resource Descriptor answering a WebFinger query.
+type WebFinger struct {
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases,omitempty"`
+	Links   []WebFingerLink `json:"links"`
+}
+
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+// SelfLink returns the actor URI a WebFinger response points to.
+func (w WebFinger) SelfLink() (string, bool) {
+	for _, link := range w.Links {
+		if link.Rel == "self" && IsContentType(link.Type) {
+			return link.Href, true
+		}
+	}
+	return "", false
+}
+
+var ErrAccount = errors.New("account must look like user@domain")
+
+// ParseAccount splits a fediverse account such as "alice@example.com",
+// "@alice@example.com" or "acct:alice@example.com" into its user and
+// domain.
+func ParseAccount(account string) (user, domain string, err error) {
+	account = strings.TrimPrefix(account, "acct:")
+	account = strings.TrimPrefix(account, "@")
+	user, domain, ok := strings.Cut(account, "@")
+	if !ok || user == "" || domain == "" || strings.ContainsAny(domain, "@/?#") {
+		return "", "", ErrAccount
+	}
+	return user, strings.ToLower(domain), nil
+}
+
+// IsContentType reports whether a Content-Type or Accept value names an
+// ActivityPub document.
+func IsContentType(value string) bool {
+	return strings.Contains(value, ContentType) ||
+		(strings.Contains(value, "application/ld+json") && strings.Contains(value, activityStreams))
+}
+
+// NoteContent turns a plain text chirp into the HTML content of a Note.
+func NoteContent(body string) string {
+	return "<p>" + strings.ReplaceAll(html.EscapeString(body), "\n", "<br>") + "</p>"
+}
+
+var (
+	lineBreakTags = regexp.MustCompile(`(?i)<br\s*/?>|</p>\s*<p[^>]*>`)
+	anyTag        = regexp.MustCompile(`<[^>]*>`)
+)
+
+// PlainText reduces the HTML content of a remote post to plain text, so
+// none of its markup reaches Chirpy's clients.
+func PlainText(content string) string {
+	content = lineBreakTags.ReplaceAllString(content, "\n")
+	content = anyTag.ReplaceAllString(content, "")
+	return strings.TrimSpace(html.UnescapeString(content))
+}
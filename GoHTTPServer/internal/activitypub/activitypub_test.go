@@ -0,0 +1,114 @@
+package activitypub
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseAccount(t *testing.T) {
+	tests := []struct {
+		name       string
+		account    string
+		wantUser   string
+		wantDomain string
+		wantErr    error
+	}{
+		{name: "Bare", account: "alice@example.com", wantUser: "alice", wantDomain: "example.com"},
+		{name: "Leading at", account: "@alice@Example.com", wantUser: "alice", wantDomain: "example.com"},
+		{name: "acct URI", account: "acct:alice@example.com:8080", wantUser: "alice", wantDomain: "example.com:8080"},
+		{name: "No domain", account: "alice", wantErr: ErrAccount},
+		{name: "Empty user", account: "@example.com", wantErr: ErrAccount},
+		{name: "Path in domain", account: "alice@example.com/evil", wantErr: ErrAccount},
+		{name: "Two ats", account: "alice@bob@example.com", wantErr: ErrAccount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, domain, err := ParseAccount(tt.account)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseAccount(%q) error = %v, want %v", tt.account, err, tt.wantErr)
+			}
+			if user != tt.wantUser || domain != tt.wantDomain {
+				t.Errorf("ParseAccount(%q) = %q, %q, want %q, %q", tt.account, user, domain, tt.wantUser, tt.wantDomain)
+			}
+		})
+	}
+}
+
+func TestObject(t *testing.T) {
+	tests := []struct {
+		name     string
+		object   string
+		wantID   string
+		wantType string
+	}{
+		{name: "Bare ID", object: `"https://a.example/notes/1"`, wantID: "https://a.example/notes/1"},
+		{name: "Embedded", object: `{"id":"https://a.example/notes/1","type":"Note"}`, wantID: "https://a.example/notes/1", wantType: "Note"},
+		{name: "Missing", object: `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Activity{Object: json.RawMessage(tt.object)}
+			if got := a.ObjectID(); got != tt.wantID {
+				t.Errorf("ObjectID() = %q, want %q", got, tt.wantID)
+			}
+			if got := a.ObjectType(); got != tt.wantType {
+				t.Errorf("ObjectType() = %q, want %q", got, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "Paragraph", content: "<p>hello</p>", want: "hello"},
+		{name: "Line breaks", content: "<p>one<br>two<br />three</p><p>four</p>", want: "one\ntwo\nthree\nfour"},
+		{name: "Links keep their text", content: `<p>see <a href="https://x.example" class="u-url">x.example</a></p>`, want: "see x.example"},
+		{name: "Script", content: `<script>alert(1)</script>hi`, want: "alert(1)hi"},
+		{name: "Entities", content: "<p>fish &amp; chips &lt;3</p>", want: "fish & chips <3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlainText(tt.content); got != tt.want {
+				t.Errorf("PlainText(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoteContentRoundTrip(t *testing.T) {
+	body := "<b>bold</b> & friends\nsecond line"
+	content := NoteContent(body)
+	if content != "<p>&lt;b&gt;bold&lt;/b&gt; &amp; friends<br>second line</p>" {
+		t.Errorf("NoteContent() = %q", content)
+	}
+	if got := PlainText(content); got != body {
+		t.Errorf("PlainText(NoteContent()) = %q, want %q", got, body)
+	}
+}
+
+func TestIsContentType(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "application/activity+json", want: true},
+		{value: `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`, want: true},
+		{value: "application/ld+json", want: false},
+		{value: "application/json", want: false},
+		{value: "text/html, application/activity+json;q=0.9", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := IsContentType(tt.value); got != tt.want {
+			t.Errorf("IsContentType(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
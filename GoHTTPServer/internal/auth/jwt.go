@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -74,3 +75,21 @@ func MakeRefreshToken() (string, error) {
 
 	return encodededKey, nil
 }
+
+// MakeConfirmationToken returns a random single-use token, such as one sent
+// in an email link, and the hash to store in its place so a leaked
+// database can't be used to confirm anything.
+func MakeConfirmationToken() (token, hash string, err error) {
+	token, err = MakeRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hash a confirmation token is stored and looked up
+// by.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
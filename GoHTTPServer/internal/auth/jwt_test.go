@@ -54,3 +54,24 @@ func TestValidateJWT(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeConfirmationToken(t *testing.T) {
+	token, hash, err := MakeConfirmationToken()
+	if err != nil {
+		t.Fatalf("MakeConfirmationToken() error: %v", err)
+	}
+	if hash == token {
+		t.Errorf("hash should differ from the token")
+	}
+	if got := HashToken(token); got != hash {
+		t.Errorf("HashToken(token) = %q, want %q", got, hash)
+	}
+
+	other, _, err := MakeConfirmationToken()
+	if err != nil {
+		t.Fatalf("MakeConfirmationToken() error: %v", err)
+	}
+	if other == token {
+		t.Errorf("two tokens were the same")
+	}
+}
@@ -0,0 +1,99 @@
+package chirptext
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// URLWeight is how many characters a URL counts as, however long it is,
+// so a link costs the same whether or not it's been shortened.
+const URLWeight = 23
+
+var urlPattern = regexp.MustCompile(`https?://[^\s]+`)
+
+// trailingURLPunct is punctuation that usually ends the sentence around a
+// URL rather than the URL itself.
+const trailingURLPunct = ".,!?:;)]}'\""
+
+// Normalize puts a chirp body into the form it's stored in. The text is
+// composed to NFC, line endings become "\n", control characters other than
+// newlines and tabs are dropped, invisible formatting characters such as
+// zero-width spaces and bidi overrides are removed, and surrounding
+// whitespace is trimmed.
+//
+// Joiners and tag characters are kept because emoji sequences (family
+// emoji, regional flags) and some scripts depend on them.
+func Normalize(body string) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+
+	var b strings.Builder
+	b.Grow(len(body))
+	for _, r := range norm.NFC.String(body) {
+		switch {
+		case r == '\r', r == '\u2028', r == '\u2029':
+			b.WriteRune('\n')
+		case r == '\n', r == '\t':
+			b.WriteRune(r)
+		case unicode.IsControl(r):
+		case unicode.Is(unicode.Cf, r) && !keepFormat(r):
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func keepFormat(r rune) bool {
+	return r == '\u200c' || r == '\u200d' || (r >= 0xe0020 && r <= 0xe007f)
+}
+
+// Length is the length of body as users see it: the number of grapheme
+// clusters, with every URL counting as URLWeight. body should already be
+// normalized.
+func Length(body string) int {
+	length := 0
+	last := 0
+	for _, span := range urlSpans(body) {
+		length += uniseg.GraphemeClusterCount(body[last:span[0]]) + URLWeight
+		last = span[1]
+	}
+	return length + uniseg.GraphemeClusterCount(body[last:])
+}
+
+// URLs returns the http(s) URLs in body in the order they appear.
+func URLs(body string) []string {
+	spans := urlSpans(body)
+	urls := make([]string, 0, len(spans))
+	for _, span := range spans {
+		urls = append(urls, body[span[0]:span[1]])
+	}
+	return urls
+}
+
+func urlSpans(body string) [][2]int {
+	var spans [][2]int
+	for _, loc := range urlPattern.FindAllStringIndex(body, -1) {
+		url := strings.TrimRight(body[loc[0]:loc[1]], trailingURLPunct)
+		spans = append(spans, [2]int{loc[0], loc[0] + len(url)})
+	}
+	return spans
+}
+
+// ReplaceURLs returns body with each http(s) URL replaced by what replace
+// returns for it. Surrounding text, including punctuation trimmed from the
+// end of a URL, is left alone.
+func ReplaceURLs(body string, replace func(url string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range urlSpans(body) {
+		b.WriteString(body[last:span[0]])
+		b.WriteString(replace(body[span[0]:span[1]]))
+		last = span[1]
+	}
+	b.WriteString(body[last:])
+	return b.String()
+}
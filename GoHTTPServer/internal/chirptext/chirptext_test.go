@@ -0,0 +1,119 @@
+package chirptext
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "Plain text",
+			body: "hello world",
+			want: "hello world",
+		},
+		{
+			name: "Decomposed accents are composed",
+			body: "cafe\u0301",
+			want: "café",
+		},
+		{
+			name: "Line endings and surrounding whitespace",
+			body: "  one\r\ntwo\rthree\u2028four \n",
+			want: "one\ntwo\nthree\nfour",
+		},
+		{
+			name: "Control characters are dropped",
+			body: "bell\a and\x00 null\ttab",
+			want: "bell and null\ttab",
+		},
+		{
+			name: "Invisible formatting is dropped",
+			body: "zero\u200bwidth \u202egnp.exe\u202c soft\u00adhyphen",
+			want: "zerowidth gnp.exe softhyphen",
+		},
+		{
+			name: "Emoji sequences survive",
+			body: "\U0001f468\u200d\U0001f469\u200d\U0001f467 \U0001f3f4\U000e0067\U000e0062\U000e0073\U000e0063\U000e0074\U000e007f",
+			want: "\U0001f468\u200d\U0001f469\u200d\U0001f467 \U0001f3f4\U000e0067\U000e0062\U000e0073\U000e0063\U000e0074\U000e007f",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.body); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{
+			name: "ASCII",
+			body: "hello",
+			want: 5,
+		},
+		{
+			name: "Accented letters count once",
+			body: "café",
+			want: 4,
+		},
+		{
+			name: "Emoji count once each",
+			body: strings.Repeat("\U0001f600", 50),
+			want: 50,
+		},
+		{
+			name: "Family emoji is one grapheme",
+			body: "\U0001f468\u200d\U0001f469\u200d\U0001f467",
+			want: 1,
+		},
+		{
+			name: "URLs have a fixed weight",
+			body: "see https://example.com/a/very/long/path/that/goes/on/and/on.",
+			want: 4 + URLWeight + 1,
+		},
+		{
+			name: "Short URLs too",
+			body: "http://a.co",
+			want: URLWeight,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Length(tt.body); got != tt.want {
+				t.Errorf("Length(%q) = %d, want %d", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestURLs(t *testing.T) {
+	got := URLs("read https://example.com/a, then (http://b.org/x?y=1) now")
+	want := []string{"https://example.com/a", "http://b.org/x?y=1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("URLs() = %v, want %v", got, want)
+	}
+}
+
+func TestReplaceURLs(t *testing.T) {
+	got := ReplaceURLs("read https://example.com/a, then (http://b.org/x?y=1) now", func(url string) string {
+		return "<" + url + ">"
+	})
+	want := "read <https://example.com/a>, then (<http://b.org/x?y=1>) now"
+	if got != want {
+		t.Errorf("ReplaceURLs() = %q, want %q", got, want)
+	}
+}
@@ -7,29 +7,67 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countPublicChirpsByAuthor = `-- name: CountPublicChirpsByAuthor :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1
+AND hidden_at IS NULL
+AND visibility = 'public'
+AND rechirp_of IS NULL
+`
+
+func (q *Queries) CountPublicChirpsByAuthor(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPublicChirpsByAuthor, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of, quote_of, visibility, content_warning, sensitive_media, reply_to)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, rechirp_of, quote_of, hidden_at, visibility, content_warning, sensitive_media, reply_to
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	Visibility     string
+	ContentWarning string
+	SensitiveMedia bool
+	ReplyTo        uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.RechirpOf,
+		arg.QuoteOf,
+		arg.Visibility,
+		arg.ContentWarning,
+		arg.SensitiveMedia,
+		arg.ReplyTo,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +75,13 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.HiddenAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.SensitiveMedia,
+		&i.ReplyTo,
 	)
 	return i, err
 }
@@ -52,7 +97,7 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, rechirp_of, quote_of, hidden_at, visibility, content_warning, sensitive_media, reply_to FROM chirps
 WHERE id = $1
 `
 
@@ -65,17 +110,50 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.HiddenAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.SensitiveMedia,
+		&i.ReplyTo,
 	)
 	return i, err
 }
 
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, rechirp_of, quote_of, hidden_at, visibility, content_warning, sensitive_media, reply_to FROM chirps
+WHERE hidden_at IS NULL
+AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, $1)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $1)
+    OR (blocks.blocker_id = $1 AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = $1
+    AND mutes.muted_id = chirps.user_id
+)
+AND (
+    $2::text = 'include'
+    OR chirp_is_sensitive(chirps.content_warning, chirps.sensitive_media, chirps.rechirp_of) = ($2::text = 'only')
+)
+AND (
+    $3::text = ''
+    OR strpos(lower(chirp_shown_warning(chirps.content_warning, chirps.rechirp_of)), lower($3::text)) > 0
+)
 ORDER BY created_at ASC
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type GetChirpsParams struct {
+	ViewerID       uuid.UUID
+	Sensitive      string
+	ContentWarning string
+}
+
+func (q *Queries) GetChirps(ctx context.Context, arg GetChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirps, arg.ViewerID, arg.Sensitive, arg.ContentWarning)
 	if err != nil {
 		return nil, err
 	}
@@ -89,6 +167,13 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.SensitiveMedia,
+			&i.ReplyTo,
 		); err != nil {
 			return nil, err
 		}
@@ -104,13 +189,250 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpsByAuthor = `-- name: GetChirpsByAuthor :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, rechirp_of, quote_of, hidden_at, visibility, content_warning, sensitive_media, reply_to FROM chirps
 WHERE user_id = $1
+AND hidden_at IS NULL
+AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, $2)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2)
+    OR (blocks.blocker_id = $2 AND blocks.blocked_id = chirps.user_id)
+)
+AND (
+    $3::text = 'include'
+    OR chirp_is_sensitive(chirps.content_warning, chirps.sensitive_media, chirps.rechirp_of) = ($3::text = 'only')
+)
+AND (
+    $4::text = ''
+    OR strpos(lower(chirp_shown_warning(chirps.content_warning, chirps.rechirp_of)), lower($4::text)) > 0
+)
 ORDER BY created_at ASC
 `
 
-func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByAuthor, userID)
+type GetChirpsByAuthorParams struct {
+	UserID         uuid.UUID
+	ViewerID       uuid.UUID
+	Sensitive      string
+	ContentWarning string
+}
+
+func (q *Queries) GetChirpsByAuthor(ctx context.Context, arg GetChirpsByAuthorParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByAuthor,
+		arg.UserID,
+		arg.ViewerID,
+		arg.Sensitive,
+		arg.ContentWarning,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.SensitiveMedia,
+			&i.ReplyTo,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, rechirp_of, quote_of, hidden_at, visibility, content_warning, sensitive_media, reply_to FROM chirps
+WHERE id = ANY($1::uuid[])
+AND hidden_at IS NULL
+AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, $2)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2)
+    OR (blocks.blocker_id = $2 AND blocks.blocked_id = chirps.user_id)
+)
+`
+
+type GetChirpsByIDsParams struct {
+	Ids      []uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, arg GetChirpsByIDsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(arg.Ids), arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.SensitiveMedia,
+			&i.ReplyTo,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExistingChirpIDs = `-- name: GetExistingChirpIDs :many
+-- Returns which of the given chirps still exist, whoever can see them.
+SELECT id FROM chirps
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetExistingChirpIDs(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getExistingChirpIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var i uuid.UUID
+		if err := rows.Scan(&i); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentPublicChirpsByAuthor = `-- name: GetRecentPublicChirpsByAuthor :many
+-- An author's newest public chirps, leaving out rechirps, for feeds and
+-- the ActivityPub outbox.
+SELECT id, created_at, updated_at, body, user_id, rechirp_of, quote_of, hidden_at, visibility, content_warning, sensitive_media, reply_to FROM chirps
+WHERE user_id = $1
+AND hidden_at IS NULL
+AND visibility = 'public'
+AND rechirp_of IS NULL
+ORDER BY created_at DESC, id DESC
+LIMIT $2
+`
+
+type GetRecentPublicChirpsByAuthorParams struct {
+	UserID   uuid.UUID
+	PageSize int32
+}
+
+func (q *Queries) GetRecentPublicChirpsByAuthor(ctx context.Context, arg GetRecentPublicChirpsByAuthorParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPublicChirpsByAuthor, arg.UserID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.SensitiveMedia,
+			&i.ReplyTo,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRechirpCounts = `-- name: GetRechirpCounts :many
+SELECT rechirp_of, COUNT(*) AS rechirp_count FROM chirps
+WHERE rechirp_of = ANY($1::uuid[])
+GROUP BY rechirp_of
+`
+
+type GetRechirpCountsRow struct {
+	RechirpOf    uuid.NullUUID
+	RechirpCount int64
+}
+
+func (q *Queries) GetRechirpCounts(ctx context.Context, chirpIds []uuid.UUID) ([]GetRechirpCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRechirpCounts, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRechirpCountsRow
+	for rows.Next() {
+		var i GetRechirpCountsRow
+		if err := rows.Scan(&i.RechirpOf, &i.RechirpCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRechirpsOf = `-- name: GetRechirpsOf :many
+SELECT id, created_at, updated_at, body, user_id, rechirp_of, quote_of, hidden_at, visibility, content_warning, sensitive_media, reply_to FROM chirps
+WHERE rechirp_of = $1
+`
+
+func (q *Queries) GetRechirpsOf(ctx context.Context, rechirpOf uuid.NullUUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getRechirpsOf, rechirpOf)
 	if err != nil {
 		return nil, err
 	}
@@ -124,6 +446,13 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.SensitiveMedia,
+			&i.ReplyTo,
 		); err != nil {
 			return nil, err
 		}
@@ -137,3 +466,109 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const getTimeline = `-- name: GetTimeline :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.rechirp_of, chirps.quote_of, chirps.hidden_at, chirps.visibility, chirps.content_warning, chirps.sensitive_media, chirps.reply_to FROM chirps
+WHERE (
+    chirps.user_id = $1
+    OR chirps.user_id IN (
+        SELECT followee_id FROM follows
+        WHERE follower_id = $1
+    )
+)
+AND chirps.hidden_at IS NULL
+AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, $1)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $1)
+    OR (blocks.blocker_id = $1 AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = $1
+    AND mutes.muted_id = chirps.user_id
+)
+AND (chirps.created_at, chirps.id) < ($2::timestamp, $3::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $4
+`
+
+type GetTimelineParams struct {
+	UserID          uuid.UUID
+	BeforeCreatedAt time.Time
+	BeforeID        uuid.UUID
+	PageSize        int32
+}
+
+func (q *Queries) GetTimeline(ctx context.Context, arg GetTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTimeline,
+		arg.UserID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.SensitiveMedia,
+			&i.ReplyTo,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVisibleChirp = `-- name: GetVisibleChirp :one
+SELECT id, created_at, updated_at, body, user_id, rechirp_of, quote_of, hidden_at, visibility, content_warning, sensitive_media, reply_to FROM chirps
+WHERE id = $1
+AND hidden_at IS NULL
+AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, $2)
+`
+
+type GetVisibleChirpParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) GetVisibleChirp(ctx context.Context, arg GetVisibleChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getVisibleChirp, arg.ID, arg.ViewerID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.HiddenAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.SensitiveMedia,
+		&i.ReplyTo,
+	)
+	return i, err
+}
@@ -11,12 +11,267 @@ import (
 	"github.com/google/uuid"
 )
 
+type ActorKey struct {
+	UserID        uuid.UUID
+	CreatedAt     time.Time
+	PublicKeyPem  string
+	PrivateKeyPem string
+}
+
+type BannedTerm struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Term      string
+	Action    string
+}
+
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type BlockedLinkDomain struct {
+	Domain    string
+	CreatedAt time.Time
+	BlockedBy uuid.NullUUID
+	Reason    string
+}
+
 type Chirp struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	HiddenAt       sql.NullTime
+	Visibility     string
+	ContentWarning string
+	SensitiveMedia bool
+	ReplyTo        uuid.NullUUID
+}
+
+type ChirpAttachment struct {
+	ChirpID  uuid.UUID
+	MediaID  uuid.UUID
+	Position int32
+}
+
+type ChirpEvent struct {
+	ID              int64
+	CreatedAt       time.Time
+	Type            string
+	ChirpID         uuid.UUID
+	AuthorID        uuid.UUID
+	RelatedAuthorID uuid.NullUUID
+	Tags            []string
+	Visibility      string
+	MentionedIds    []uuid.UUID
+}
+
+type ChirpFlag struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ChirpID    uuid.UUID
+	Source     string
+	Reason     string
+	ReviewedAt sql.NullTime
+	CaseID     uuid.NullUUID
+}
+
+type ChirpHashtag struct {
+	ChirpID     uuid.UUID
+	HashtagID   uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+}
+
+type ChirpLike struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpLink struct {
+	ChirpID       uuid.UUID
+	Position      int32
+	LinkPreviewID uuid.UUID
+}
+
+type ChirpMention struct {
+	ChirpID     uuid.UUID
+	UserID      uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+}
+
+type Conversation struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	CreatedBy uuid.UUID
+	IsGroup   bool
+	Title     string
+	DirectKey sql.NullString
+}
+
+type ConversationMember struct {
+	ConversationID    uuid.UUID
+	UserID            uuid.UUID
+	JoinedAt          time.Time
+	LastReadMessageID uuid.NullUUID
+	LastReadAt        sql.NullTime
+}
+
+type DirectMessage struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+}
+
+type EmailChange struct {
+	TokenHash string
+	UserID    uuid.UUID
+	NewEmail  string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type FederationDelivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UserID        uuid.UUID
+	InboxURL      string
+	Activity      string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     string
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type HandleHistory struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Handle     string
+	ReleasedAt time.Time
+}
+
+type Hashtag struct {
+	ID        uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type LinkPreview struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	URL           string
+	Status        string
+	Title         string
+	Description   string
+	ImageURL      string
+	SiteName      string
+	FetchedAt     sql.NullTime
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     string
+}
+
+type MediaFile struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UserID       uuid.UUID
+	Sha256       string
+	ContentType  string
+	SizeBytes    int64
+	Width        int32
+	Height       int32
+	StorageKey   string
+	ThumbnailKey string
+}
+
+type ModerationAction struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	ModeratorID  uuid.NullUUID
+	Action       string
+	CaseID       uuid.NullUUID
+	TargetUserID uuid.NullUUID
+	Note         string
+}
+
+type ModerationCase struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ChirpID    uuid.UUID
+	Status     string
+	ClaimedBy  uuid.NullUUID
+	ClaimedAt  sql.NullTime
+	ResolvedAt sql.NullTime
+	Decision   sql.NullString
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Notification struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
 	UserID    uuid.UUID
+	Type      string
+	ChirpID   uuid.NullUUID
+	GroupKey  string
+	ReadAt    sql.NullTime
+}
+
+type NotificationActor struct {
+	NotificationID uuid.UUID
+	ActorID        uuid.UUID
+	ChirpID        uuid.NullUUID
+	CreatedAt      time.Time
+}
+
+type NotificationPreference struct {
+	UserID  uuid.UUID
+	Type    string
+	Enabled bool
+}
+
+type Poll struct {
+	ChirpID        uuid.UUID
+	CreatedAt      time.Time
+	ClosesAt       time.Time
+	MultipleChoice bool
+	HideResults    bool
+}
+
+type PollOption struct {
+	ChirpID  uuid.UUID
+	Position int32
+	Text     string
+}
+
+type PollVote struct {
+	ChirpID   uuid.UUID
+	Position  int32
+	UserID    uuid.UUID
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -28,11 +283,125 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
-type User struct {
+type RemoteActor struct {
 	ID             uuid.UUID
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	URI            string
+	Username       string
+	Domain         string
+	DisplayName    string
+	InboxURL       string
+	SharedInboxURL string
+	KeyID          string
+	PublicKeyPem   string
+}
+
+type RemoteFollow struct {
+	UserID     uuid.UUID
+	ActorID    uuid.UUID
+	CreatedAt  time.Time
+	FollowURI  string
+	AcceptedAt sql.NullTime
+}
+
+type RemoteFollower struct {
+	UserID    uuid.UUID
+	ActorID   uuid.UUID
+	CreatedAt time.Time
+	FollowURI string
+}
+
+type RemoteNote struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	URI         string
+	ActorID     uuid.UUID
+	PublishedAt time.Time
+	URL         string
+	Body        string
+}
+
+type Report struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	CaseID     uuid.UUID
+	ChirpID    uuid.UUID
+	ReporterID uuid.UUID
+	Reason     string
+	Details    string
+}
+
+type ScheduledChirp struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	UserID         uuid.UUID
+	Body           string
+	QuoteOf        uuid.NullUUID
+	Visibility     string
+	MediaIds       []uuid.UUID
+	PublishAt      sql.NullTime
+	Failure        string
+	ContentWarning string
+	SensitiveMedia bool
+}
+
+type ShortLink struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	Code          string
+	ChirpID       uuid.UUID
+	Position      int32
+	URL           string
+	Clicks        int64
+	LastClickedAt sql.NullTime
+}
+
+type ShortLinkReferrer struct {
+	ShortLinkID uuid.UUID
+	Referrer    string
+	Clicks      int64
+}
+
+type Strike struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	CaseID    uuid.NullUUID
+	IssuedBy  uuid.NullUUID
+	Reason    string
+	ExpiresAt time.Time
+}
+
+type TrendRun struct {
+	WindowName string
+	ComputedAt time.Time
+}
+
+type TrendSnapshot struct {
+	ID          uuid.UUID
+	WindowName  string
+	ComputedAt  time.Time
+	Rank        int32
+	Tag         string
+	Score       float64
+	RecentCount int64
+}
+
+type User struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Email            string
+	HashedPassword   string
+	IsChirpyRed      bool
+	Role             string
+	SuspendedUntil   sql.NullTime
+	Handle           sql.NullString
+	DisplayName      string
+	Bio              string
+	AvatarMediaID    uuid.NullUUID
+	PinnedChirpID    uuid.NullUUID
+	SensitiveContent string
 }
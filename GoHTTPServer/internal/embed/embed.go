@@ -0,0 +1,127 @@
+// Package embed renders chirps for embedding in other sites: an HTML page
+// meant for an iframe, and the oEmbed document that points to it.
+package embed
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultWidth and DefaultHeight size the iframe when the consumer
+	// doesn't ask for less.
+	DefaultWidth  = 550
+	DefaultHeight = 250
+	minSize       = 100
+)
+
+var ErrNotChirpURL = errors.New("not a chirp URL on this server")
+
+// chirpPaths are the paths a chirp can be linked by: the API, its
+// ActivityPub ID, and its embed page.
+var chirpPaths = []string{"/api/chirps/", "/chirps/", "/embed/chirps/"}
+
+// ChirpID returns the chirp a URL on the server at publicURL points to.
+func ChirpID(publicURL, rawURL string) (uuid.UUID, error) {
+	base, err := url.Parse(publicURL)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(u.Host, base.Host) || (u.Scheme != "http" && u.Scheme != "https") {
+		return uuid.Nil, ErrNotChirpURL
+	}
+
+	for _, prefix := range chirpPaths {
+		if rest, ok := strings.CutPrefix(u.Path, prefix); ok {
+			id, err := uuid.Parse(rest)
+			if err != nil {
+				return uuid.Nil, ErrNotChirpURL
+			}
+			return id, nil
+		}
+	}
+	return uuid.Nil, ErrNotChirpURL
+}
+
+// Size returns the iframe size for oEmbed's optional maxwidth and
+// maxheight parameters. Values that aren't positive numbers are ignored.
+func Size(maxWidth, maxHeight string) (width, height int) {
+	return limit(DefaultWidth, maxWidth), limit(DefaultHeight, maxHeight)
+}
+
+func limit(size int, requested string) int {
+	n, err := strconv.Atoi(requested)
+	if err != nil || n <= 0 {
+		return size
+	}
+	return min(size, max(n, minSize))
+}
+
+// Chirp is what an embed page shows.
+type Chirp struct {
+	AuthorName   string
+	AuthorHandle string
+	Body         string
+	CreatedAt    time.Time
+	// ContentWarning, when set, hides the body until the reader opens it.
+	ContentWarning string
+	// URL links to the chirp itself, OEmbedURL to its oEmbed document.
+	URL       string
+	OEmbedURL string
+	// AtomURL and RSSURL are the author's feeds, advertised for discovery.
+	AtomURL string
+	RSSURL  string
+}
+
+var page = template.Must(template.New("embed").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.AuthorName}} on Chirpy</title>
+<link rel="alternate" type="application/json+oembed" href="{{.OEmbedURL}}">
+{{if .AtomURL}}<link rel="alternate" type="application/atom+xml" title="Chirps by {{.AuthorName}}" href="{{.AtomURL}}">
+{{end}}{{if .RSSURL}}<link rel="alternate" type="application/rss+xml" title="Chirps by {{.AuthorName}}" href="{{.RSSURL}}">
+{{end}}<style>
+body { margin: 0; font: 15px/1.4 system-ui, sans-serif; color: #14171a; background: #fff; }
+article { border: 1px solid #e1e8ed; border-radius: 12px; padding: 12px 16px; margin: 4px; }
+header { margin-bottom: 6px; }
+.name { font-weight: 600; }
+.handle, footer { color: #657786; }
+.body { white-space: pre-wrap; overflow-wrap: anywhere; }
+summary { cursor: pointer; font-weight: 600; }
+footer { margin-top: 8px; font-size: 13px; }
+a { color: inherit; }
+</style>
+</head>
+<body>
+<article>
+<header><span class="name">{{.AuthorName}}</span>{{if .AuthorHandle}} <span class="handle">@{{.AuthorHandle}}</span>{{end}}</header>
+{{if .ContentWarning}}<details><summary>{{.ContentWarning}}</summary><div class="body">{{.Body}}</div></details>
+{{else}}<div class="body">{{.Body}}</div>
+{{end}}<footer><a href="{{.URL}}" target="_blank" rel="noopener"><time datetime="{{.CreatedAt.UTC.Format "2006-01-02T15:04:05Z07:00"}}">{{.CreatedAt.UTC.Format "Jan 2, 2006 15:04 UTC"}}</time></a> · Chirpy</footer>
+</article>
+</body>
+</html>
+`))
+
+// Render writes the embed page for c. Everything from the chirp is
+// escaped by the template.
+func Render(w io.Writer, c Chirp) error {
+	return page.Execute(w, c)
+}
+
+// IFrame returns the HTML oEmbed consumers insert to show the embed page
+// at src.
+func IFrame(src string, width, height int) string {
+	return `<iframe src="` + template.HTMLEscapeString(src) + `" width="` + strconv.Itoa(width) +
+		`" height="` + strconv.Itoa(height) + `" style="border: 0; max-width: 100%;" loading="lazy" sandbox="allow-popups allow-popups-to-escape-sandbox"></iframe>`
+}
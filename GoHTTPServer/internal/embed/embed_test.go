@@ -0,0 +1,131 @@
+package embed
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestChirpID(t *testing.T) {
+	id := uuid.MustParse("6f1c1a4e-2d0b-4a35-9d53-1f1c2a9e8b01")
+
+	tests := []struct {
+		name    string
+		url     string
+		want    uuid.UUID
+		wantErr error
+	}{
+		{name: "API URL", url: "https://chirpy.example/api/chirps/" + id.String(), want: id},
+		{name: "ActivityPub ID", url: "https://chirpy.example/chirps/" + id.String(), want: id},
+		{name: "Embed page", url: "http://Chirpy.example/embed/chirps/" + id.String(), want: id},
+		{name: "Other host", url: "https://evil.example/api/chirps/" + id.String(), wantErr: ErrNotChirpURL},
+		{name: "Not a chirp", url: "https://chirpy.example/api/users/alice", wantErr: ErrNotChirpURL},
+		{name: "Bad ID", url: "https://chirpy.example/api/chirps/123", wantErr: ErrNotChirpURL},
+		{name: "Other scheme", url: "javascript://chirpy.example/chirps/" + id.String(), wantErr: ErrNotChirpURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ChirpID("https://chirpy.example", tt.url)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ChirpID(%q) error = %v, want %v", tt.url, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ChirpID(%q) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSize(t *testing.T) {
+	tests := []struct {
+		name                  string
+		maxWidth, maxHeight   string
+		wantWidth, wantHeight int
+	}{
+		{name: "No limits", wantWidth: DefaultWidth, wantHeight: DefaultHeight},
+		{name: "Smaller", maxWidth: "400", maxHeight: "150", wantWidth: 400, wantHeight: 150},
+		{name: "Larger than default", maxWidth: "2000", maxHeight: "2000", wantWidth: DefaultWidth, wantHeight: DefaultHeight},
+		{name: "Too small", maxWidth: "10", wantWidth: minSize, wantHeight: DefaultHeight},
+		{name: "Not numbers", maxWidth: "wide", maxHeight: "-5", wantWidth: DefaultWidth, wantHeight: DefaultHeight},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h := Size(tt.maxWidth, tt.maxHeight)
+			if w != tt.wantWidth || h != tt.wantHeight {
+				t.Errorf("Size(%q, %q) = %d, %d, want %d, %d", tt.maxWidth, tt.maxHeight, w, h, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+func TestRenderEscapes(t *testing.T) {
+	var b strings.Builder
+	err := Render(&b, Chirp{
+		AuthorName:   `<img src=x onerror=alert(1)>`,
+		AuthorHandle: "alice",
+		Body:         "<script>alert(1)</script>\nline two",
+		CreatedAt:    time.Date(2025, time.May, 1, 12, 0, 0, 0, time.UTC),
+		URL:          "https://chirpy.example/api/chirps/1",
+		OEmbedURL:    "https://chirpy.example/api/oembed?url=x",
+		AtomURL:      "https://chirpy.example/users/1/feed.atom",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	page := b.String()
+	for _, raw := range []string{"<script>", "<img"} {
+		if strings.Contains(page, raw) {
+			t.Errorf("page contains unescaped %q", raw)
+		}
+	}
+	for _, want := range []string{"&lt;script&gt;", "@alice", `type="application/atom+xml"`, `datetime="2025-05-01T12:00:00Z"`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page is missing %q", want)
+		}
+	}
+	if strings.Contains(page, "application/rss+xml") {
+		t.Error("page links an RSS feed it wasn't given")
+	}
+}
+
+func TestRenderContentWarning(t *testing.T) {
+	tests := []struct {
+		name    string
+		warning string
+		want    string
+		notWant string
+	}{
+		{name: "No warning", want: `<div class="body">Spoilers ahead</div>`, notWant: "<details>"},
+		{name: "Warning", warning: "Film <spoilers>", want: `<details><summary>Film &lt;spoilers&gt;</summary><div class="body">Spoilers ahead</div></details>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			err := Render(&b, Chirp{AuthorName: "Alice", Body: "Spoilers ahead", ContentWarning: tt.warning})
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			page := b.String()
+			if !strings.Contains(page, tt.want) {
+				t.Errorf("page is missing %q", tt.want)
+			}
+			if tt.notWant != "" && strings.Contains(page, tt.notWant) {
+				t.Errorf("page contains %q", tt.notWant)
+			}
+		})
+	}
+}
+
+func TestIFrame(t *testing.T) {
+	got := IFrame(`https://chirpy.example/embed/chirps/1?a="b"`, 400, 200)
+	if !strings.HasPrefix(got, `<iframe src="https://chirpy.example/embed/chirps/1?a=&#34;b&#34;" width="400" height="200"`) {
+		t.Errorf("IFrame() = %s", got)
+	}
+}
@@ -0,0 +1,96 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	maxHashtagLength  = 64
+	maxUsernameLength = 30
+)
+
+// Hashtag is a #tag found in a chirp body. Tag is lowercased and excludes
+// the leading '#'. Start and End are rune offsets into the body, with End
+// exclusive, and cover the '#'.
+type Hashtag struct {
+	Tag   string
+	Start int
+	End   int
+}
+
+// Mention is an @username found in a chirp body. Username excludes the
+// leading '@'. Offsets follow the same rules as Hashtag.
+type Mention struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// Extract finds hashtags and mentions in body. A marker only counts when it
+// starts the body or follows a character that can't be part of a word, so
+// email addresses and things like "C#" are left alone.
+func Extract(body string) ([]Hashtag, []Mention) {
+	runes := []rune(body)
+	var hashtags []Hashtag
+	var mentions []Mention
+
+	for i := 0; i < len(runes); i++ {
+		marker := runes[i]
+		if marker != '#' && marker != '@' {
+			continue
+		}
+		if i > 0 && (isWordRune(runes[i-1]) || runes[i-1] == '#' || runes[i-1] == '@') {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		word := string(runes[i+1 : end])
+
+		switch marker {
+		case '#':
+			if validHashtag(word) {
+				hashtags = append(hashtags, Hashtag{Tag: NormalizeTag(word), Start: i, End: end})
+			}
+		case '@':
+			if validUsername(word) {
+				mentions = append(mentions, Mention{Username: strings.ToLower(word), Start: i, End: end})
+			}
+		}
+		i = end - 1
+	}
+
+	return hashtags, mentions
+}
+
+// NormalizeTag converts user input such as "#Go" into the stored tag form.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func validHashtag(word string) bool {
+	if word == "" || len([]rune(word)) > maxHashtagLength {
+		return false
+	}
+	// Purely numeric tags like "#1" read as numbering, not topics.
+	return strings.IndexFunc(word, unicode.IsLetter) >= 0
+}
+
+func validUsername(word string) bool {
+	if word == "" || len(word) > maxUsernameLength {
+		return false
+	}
+	for _, r := range word {
+		if r != '_' && (r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return true
+}
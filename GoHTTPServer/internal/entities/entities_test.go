@@ -0,0 +1,65 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantHashtags []Hashtag
+		wantMentions []Mention
+	}{
+		{
+			name: "Hashtag and mention",
+			body: "Hey @Walt check out #GoLang",
+			wantHashtags: []Hashtag{
+				{Tag: "golang", Start: 20, End: 27},
+			},
+			wantMentions: []Mention{
+				{Username: "walt", Start: 4, End: 9},
+			},
+		},
+		{
+			name: "Punctuation ends a tag",
+			body: "#chirpy, #go!",
+			wantHashtags: []Hashtag{
+				{Tag: "chirpy", Start: 0, End: 7},
+				{Tag: "go", Start: 9, End: 12},
+			},
+		},
+		{
+			name: "Offsets count runes not bytes",
+			body: "🐦 #café",
+			wantHashtags: []Hashtag{
+				{Tag: "café", Start: 2, End: 7},
+			},
+		},
+		{
+			name: "Email addresses are not mentions",
+			body: "mail walt@breakingbad.com",
+		},
+		{
+			name: "Numeric and embedded tags are ignored",
+			body: "item #1 in C#",
+		},
+		{
+			name: "Bare markers are ignored",
+			body: "# @ ##",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotHashtags, gotMentions := Extract(tt.body)
+			if !reflect.DeepEqual(gotHashtags, tt.wantHashtags) {
+				t.Errorf("Extract() hashtags = %+v, want %+v", gotHashtags, tt.wantHashtags)
+			}
+			if !reflect.DeepEqual(gotMentions, tt.wantMentions) {
+				t.Errorf("Extract() mentions = %+v, want %+v", gotMentions, tt.wantMentions)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+// Package feed renders chirps as Atom and RSS feeds for feed readers.
+package feed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"html"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	AtomContentType = "application/atom+xml; charset=utf-8"
+	RSSContentType  = "application/rss+xml; charset=utf-8"
+
+	// titleLength is how many characters of a chirp are used as its entry
+	// title. Chirps have no title of their own.
+	titleLength = 60
+)
+
+// Feed is a list of chirps, newest first.
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the page the feed is about, and SelfURL is where the feed
+	// itself is served. SelfURL also identifies an Atom feed.
+	Link    string
+	SelfURL string
+	Entries []Entry
+}
+
+// Entry is one chirp. Body is plain text; it's escaped when rendered.
+type Entry struct {
+	Link      string
+	Author    string
+	Body      string
+	Published time.Time
+	Updated   time.Time
+	// ContentWarning, when set, is shown instead of the body, which readers
+	// have no way to hide. The entry links to the chirp for the rest.
+	ContentWarning string
+}
+
+// Updated returns when the newest change to f happened, or the zero time
+// for an empty feed.
+func (f Feed) Updated() time.Time {
+	var updated time.Time
+	for _, e := range f.Entries {
+		if e.Updated.After(updated) {
+			updated = e.Updated
+		}
+	}
+	return updated
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Tagline string      `xml:"subtitle,omitempty"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Link      atomLink   `xml:"link"`
+	Author    atomPerson `xml:"author"`
+	Content   atomText   `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Atom renders f as an Atom 1.0 document.
+func Atom(f Feed) ([]byte, error) {
+	doc := atomFeed{
+		ID:      f.SelfURL,
+		Title:   f.Title,
+		Tagline: f.Description,
+		Updated: f.Updated().UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: f.SelfURL},
+			{Rel: "alternate", Href: f.Link},
+		},
+	}
+	for _, e := range f.Entries {
+		doc.Entries = append(doc.Entries, atomEntry{
+			ID:        e.Link,
+			Title:     e.title(),
+			Published: e.Published.UTC().Format(time.RFC3339),
+			Updated:   e.Updated.UTC().Format(time.RFC3339),
+			Link:      atomLink{Rel: "alternate", Href: e.Link},
+			Author:    atomPerson{Name: e.Author},
+			Content:   atomText{Type: "html", Body: e.html()},
+		})
+	}
+	return render(doc)
+}
+
+type rssDoc struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Creator     string  `xml:"dc:creator"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS renders f as an RSS 2.0 document. RSS wants an email address for
+// item authors, so the author's name goes in dc:creator instead.
+func RSS(f Feed) ([]byte, error) {
+	doc := rssDoc{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:       f.Title,
+			Link:        f.Link,
+			Description: f.Description,
+			Self:        atomLink{Rel: "self", Type: "application/rss+xml", Href: f.SelfURL},
+		},
+	}
+	if updated := f.Updated(); !updated.IsZero() {
+		doc.Channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, e := range f.Entries {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       e.title(),
+			Link:        e.Link,
+			GUID:        rssGUID{IsPermaLink: true, Value: e.Link},
+			PubDate:     e.Published.UTC().Format(time.RFC1123Z),
+			Creator:     e.Author,
+			Description: e.html(),
+		})
+	}
+	return render(doc)
+}
+
+// ETag returns a strong entity tag for a rendered feed.
+func ETag(doc []byte) string {
+	sum := sha256.Sum256(doc)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func render(doc any) ([]byte, error) {
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+func (e Entry) title() string {
+	if e.ContentWarning != "" {
+		return title("CW: " + e.ContentWarning)
+	}
+	return title(e.Body)
+}
+
+func (e Entry) html() string {
+	if e.ContentWarning != "" {
+		return "<p>CW: " + html.EscapeString(e.ContentWarning) + `</p><p><a href="` +
+			html.EscapeString(e.Link) + `">Read the chirp</a></p>`
+	}
+	return bodyHTML(e.Body)
+}
+
+// bodyHTML turns a plain text chirp into HTML. Readers display this, so
+// everything in the body is escaped; the XML encoder then escapes the
+// HTML once more as element text.
+func bodyHTML(body string) string {
+	return strings.ReplaceAll(html.EscapeString(body), "\n", "<br>")
+}
+
+// title shortens the first line of body to use as an entry title.
+func title(body string) string {
+	line, _, _ := strings.Cut(body, "\n")
+	if utf8.RuneCountInString(line) <= titleLength {
+		return line
+	}
+	runes := []rune(line)
+	return strings.TrimSpace(string(runes[:titleLength-1])) + "…"
+}
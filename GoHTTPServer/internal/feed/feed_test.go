@@ -0,0 +1,159 @@
+package feed
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFeed = Feed{
+	Title:       "Chirps by @alice",
+	Description: "Recent chirps",
+	Link:        "https://chirpy.example/api/users/alice",
+	SelfURL:     "https://chirpy.example/users/1/feed.atom",
+	Entries: []Entry{
+		{
+			Link:      "https://chirpy.example/api/chirps/2",
+			Author:    "Alice & co",
+			Body:      "<script>alert(1)</script> fish & chips\nsecond line",
+			Published: time.Date(2025, time.March, 2, 9, 0, 0, 0, time.UTC),
+			Updated:   time.Date(2025, time.March, 3, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			Link:      "https://chirpy.example/api/chirps/1",
+			Author:    "Alice & co",
+			Body:      "hello",
+			Published: time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC),
+			Updated:   time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC),
+		},
+	},
+}
+
+func TestAtom(t *testing.T) {
+	doc, err := Atom(testFeed)
+	if err != nil {
+		t.Fatalf("Atom() error = %v", err)
+	}
+
+	var got atomFeed
+	if err := xml.Unmarshal(doc, &got); err != nil {
+		t.Fatalf("Atom() produced invalid XML: %v", err)
+	}
+	if got.Updated != "2025-03-03T09:00:00Z" {
+		t.Errorf("updated = %q, want the newest entry's time", got.Updated)
+	}
+	if len(got.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got.Entries))
+	}
+
+	entry := got.Entries[0]
+	wantContent := "&lt;script&gt;alert(1)&lt;/script&gt; fish &amp; chips<br>second line"
+	if entry.Content.Body != wantContent {
+		t.Errorf("content = %q, want %q", entry.Content.Body, wantContent)
+	}
+	if entry.Title != "<script>alert(1)</script> fish & chips" {
+		t.Errorf("title = %q, want the first line", entry.Title)
+	}
+	if entry.Author.Name != "Alice & co" {
+		t.Errorf("author = %q", entry.Author.Name)
+	}
+	if strings.Contains(string(doc), "<script>") {
+		t.Error("raw markup from the body appears in the document")
+	}
+}
+
+func TestRSS(t *testing.T) {
+	doc, err := RSS(testFeed)
+	if err != nil {
+		t.Fatalf("RSS() error = %v", err)
+	}
+
+	var got struct {
+		Channel struct {
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				GUID        string `xml:"guid"`
+				PubDate     string `xml:"pubDate"`
+				Description string `xml:"description"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(doc, &got); err != nil {
+		t.Fatalf("RSS() produced invalid XML: %v", err)
+	}
+	if got.Channel.LastBuildDate != "Mon, 03 Mar 2025 09:00:00 +0000" {
+		t.Errorf("lastBuildDate = %q", got.Channel.LastBuildDate)
+	}
+	if len(got.Channel.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(got.Channel.Items))
+	}
+	item := got.Channel.Items[1]
+	if item.GUID != "https://chirpy.example/api/chirps/1" || item.Description != "hello" {
+		t.Errorf("item = %+v", item)
+	}
+}
+
+func TestContentWarningHidesBody(t *testing.T) {
+	f := Feed{
+		SelfURL: "https://chirpy.example/users/1/feed.atom",
+		Entries: []Entry{{
+			Link:           "https://chirpy.example/api/chirps/3",
+			Body:           "The butler did it",
+			ContentWarning: "Mystery <spoilers>",
+			Published:      time.Date(2025, time.March, 4, 9, 0, 0, 0, time.UTC),
+			Updated:        time.Date(2025, time.March, 4, 9, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	for name, render := range map[string]func(Feed) ([]byte, error){"Atom": Atom, "RSS": RSS} {
+		t.Run(name, func(t *testing.T) {
+			doc, err := render(f)
+			if err != nil {
+				t.Fatalf("%s() error = %v", name, err)
+			}
+			if strings.Contains(string(doc), "butler") {
+				t.Error("document contains the body of a chirp with a content warning")
+			}
+			for _, want := range []string{"<title>CW: Mystery &lt;spoilers&gt;</title>", "CW: Mystery &amp;lt;spoilers&amp;gt;", "chirps/3&#34;&gt;Read the chirp"} {
+				if !strings.Contains(string(doc), want) {
+					t.Errorf("document is missing %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "Short", body: "hello", want: "hello"},
+		{name: "First line only", body: "hello\nworld", want: "hello"},
+		{name: "Exactly the limit", body: strings.Repeat("a", titleLength), want: strings.Repeat("a", titleLength)},
+		{name: "Too long", body: strings.Repeat("é", titleLength+5), want: strings.Repeat("é", titleLength-1) + "…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := title(tt.body); got != tt.want {
+				t.Errorf("title(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestETag(t *testing.T) {
+	a := ETag([]byte("one"))
+	if a != ETag([]byte("one")) {
+		t.Error("ETag() isn't stable")
+	}
+	if a == ETag([]byte("two")) {
+		t.Error("ETag() is the same for different documents")
+	}
+	if !strings.HasPrefix(a, `"`) || !strings.HasSuffix(a, `"`) {
+		t.Errorf("ETag() = %s, want a quoted tag", a)
+	}
+}
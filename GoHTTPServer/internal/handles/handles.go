@@ -0,0 +1,70 @@
+// Package handles checks the @handles users pick to identify themselves.
+// Handles are unique regardless of case, but keep the case their owner
+// chose for display.
+package handles
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	MinLength = 3
+	// MaxLength matches the longest @mention recognised in chirps, so every
+	// handle can be mentioned.
+	MaxLength = 30
+
+	// RedirectPeriod is how long an old handle keeps pointing at the user
+	// who changed away from it. No one else can claim it until then.
+	RedirectPeriod = 30 * 24 * time.Hour
+)
+
+var (
+	ErrTooShort   = errors.New("handles must be at least 3 characters")
+	ErrTooLong    = errors.New("handles can be at most 30 characters")
+	ErrCharacters = errors.New("handles can only use letters, numbers and underscores")
+	ErrNoLetter   = errors.New("handles need at least one letter")
+	ErrReserved   = errors.New("that handle is reserved")
+)
+
+// reserved are handles that could be mistaken for the service itself.
+var reserved = map[string]bool{
+	"admin":         true,
+	"administrator": true,
+	"api":           true,
+	"chirpy":        true,
+	"help":          true,
+	"moderator":     true,
+	"root":          true,
+	"support":       true,
+	"system":        true,
+}
+
+// Validate reports why handle can't be used, or nil if it can.
+func Validate(handle string) error {
+	if len(handle) < MinLength {
+		return ErrTooShort
+	}
+	if len(handle) > MaxLength {
+		return ErrTooLong
+	}
+	for _, r := range handle {
+		if r != '_' && (r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r))) {
+			return ErrCharacters
+		}
+	}
+	if strings.IndexFunc(handle, unicode.IsLetter) < 0 {
+		return ErrNoLetter
+	}
+	if reserved[Normalize(handle)] {
+		return ErrReserved
+	}
+	return nil
+}
+
+// Normalize returns the form handles are compared in.
+func Normalize(handle string) string {
+	return strings.ToLower(handle)
+}
@@ -0,0 +1,34 @@
+package handles
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle string
+		want   error
+	}{
+		{name: "Simple", handle: "alice"},
+		{name: "Mixed case and digits", handle: "Alice_99"},
+		{name: "Longest", handle: strings.Repeat("a", MaxLength)},
+		{name: "Too short", handle: "al", want: ErrTooShort},
+		{name: "Too long", handle: strings.Repeat("a", MaxLength+1), want: ErrTooLong},
+		{name: "Punctuation", handle: "alice.b", want: ErrCharacters},
+		{name: "Non-ASCII letter", handle: "zoë", want: ErrCharacters},
+		{name: "Digits only", handle: "12345", want: ErrNoLetter},
+		{name: "Reserved", handle: "admin", want: ErrReserved},
+		{name: "Reserved in another case", handle: "Support", want: ErrReserved},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.handle); !errors.Is(err, tt.want) {
+				t.Errorf("Validate(%q) = %v, want %v", tt.handle, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+// Package httpsig signs and verifies HTTP requests with the HTTP Signatures
+// scheme (draft-cavage-http-signatures) that ActivityPub servers use to
+// authenticate server-to-server requests.
+package httpsig
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxClockSkew is how far a request's Date header can be from the
+	// current time before its signature is refused, which limits how long
+	// a captured request can be replayed.
+	MaxClockSkew = 5 * time.Minute
+
+	algorithm = "rsa-sha256"
+	keyBits   = 2048
+)
+
+var (
+	ErrNoSignature  = errors.New("request isn't signed")
+	ErrMalformed    = errors.New("malformed signature header")
+	ErrAlgorithm    = errors.New("unsupported signature algorithm")
+	ErrMissingField = errors.New("signature doesn't cover the required headers")
+	ErrDate         = errors.New("request date is missing or outside the allowed skew")
+	ErrDigest       = errors.New("body digest doesn't match")
+	ErrVerification = errors.New("signature verification failed")
+)
+
+// Signature is a parsed Signature header.
+type Signature struct {
+	KeyID   string
+	Headers []string
+	Value   []byte
+}
+
+// Sign adds Date, Digest (when there's a body) and Signature headers to r,
+// signed with key. keyID tells the receiver where to find the public key.
+func Sign(r *http.Request, keyID string, key *rsa.PrivateKey, body []byte) error {
+	if r.Header.Get("Date") == "" {
+		r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		r.Header.Set("Digest", digest(body))
+		headers = append(headers, "digest")
+	}
+
+	hashed := sha256.Sum256([]byte(signingString(r, headers)))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+
+	r.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="%s",headers="%s",signature="%s"`,
+		keyID, algorithm, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// Parse reads the Signature header of r, so the caller can look up the
+// key named by KeyID before calling Verify.
+func Parse(r *http.Request) (Signature, error) {
+	header := r.Header.Get("Signature")
+	if header == "" {
+		return Signature{}, ErrNoSignature
+	}
+
+	params := map[string]string{}
+	for _, part := range splitParams(header) {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+			return Signature{}, ErrMalformed
+		}
+		params[strings.TrimSpace(name)] = value[1 : len(value)-1]
+	}
+
+	if alg := params["algorithm"]; alg != "" && alg != algorithm && alg != "hs2019" {
+		return Signature{}, ErrAlgorithm
+	}
+	if params["keyId"] == "" || params["signature"] == "" {
+		return Signature{}, ErrMalformed
+	}
+	value, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return Signature{}, ErrMalformed
+	}
+
+	// Without a headers parameter only the Date header is signed.
+	headers := []string{"date"}
+	if params["headers"] != "" {
+		headers = strings.Fields(strings.ToLower(params["headers"]))
+	}
+
+	return Signature{KeyID: params["keyId"], Headers: headers, Value: value}, nil
+}
+
+// Verify checks that sig was made by key over r. The signature has to
+// cover the request target, host and date, and the digest when there's a
+// body, and the Date header has to be within MaxClockSkew of now.
+func Verify(r *http.Request, body []byte, sig Signature, key *rsa.PublicKey, now time.Time) error {
+	required := []string{"(request-target)", "host", "date"}
+	if len(body) > 0 {
+		required = append(required, "digest")
+	}
+	for _, h := range required {
+		if !slices.Contains(sig.Headers, h) {
+			return ErrMissingField
+		}
+	}
+
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil || date.Sub(now).Abs() > MaxClockSkew {
+		return ErrDate
+	}
+
+	if len(body) > 0 && r.Header.Get("Digest") != digest(body) {
+		return ErrDigest
+	}
+
+	hashed := sha256.Sum256([]byte(signingString(r, sig.Headers)))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sig.Value); err != nil {
+		return ErrVerification
+	}
+	return nil
+}
+
+func signingString(r *http.Request, headers []string) string {
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		var value string
+		switch h {
+		case "(request-target)":
+			value = strings.ToLower(r.Method) + " " + r.URL.RequestURI()
+		case "host":
+			value = r.Host
+			if value == "" {
+				value = r.URL.Host
+			}
+		default:
+			value = strings.Join(r.Header.Values(h), ", ")
+		}
+		lines = append(lines, h+": "+value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// splitParams splits a Signature header on the commas between parameters,
+// ignoring commas inside quoted values.
+func splitParams(header string) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i, c := range header {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			parts = append(parts, strings.TrimSpace(header[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(header[start:]))
+}
+
+// GenerateKey returns a new signing key as PEM encoded private and public
+// keys.
+func GenerateKey() (privatePEM, publicPEM string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return "", "", err
+	}
+	private, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private}))
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+	return privatePEM, publicPEM, nil
+}
+
+// ParsePrivateKey reads a PEM encoded RSA private key.
+func ParsePrivateKey(s string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("no PEM data in private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrAlgorithm
+	}
+	return rsaKey, nil
+}
+
+// ParsePublicKey reads a PEM encoded RSA public key, in either the PKIX
+// form most servers publish or PKCS #1.
+func ParsePublicKey(s string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("no PEM data in public key")
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrAlgorithm
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,175 @@
+package httpsig
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	privatePEM, publicPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	private, err := ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatalf("ParsePrivateKey() error = %v", err)
+	}
+	public, err := ParsePublicKey(publicPEM)
+	if err != nil {
+		t.Fatalf("ParsePublicKey() error = %v", err)
+	}
+	_, otherPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	other, err := ParsePublicKey(otherPEM)
+	if err != nil {
+		t.Fatalf("ParsePublicKey() error = %v", err)
+	}
+
+	body := []byte(`{"type":"Follow"}`)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		tamper  func(r *http.Request) []byte
+		now     time.Time
+		wantErr error
+	}{
+		{
+			name:   "Untouched",
+			tamper: func(r *http.Request) []byte { return body },
+			now:    now,
+		},
+		{
+			name:    "Body changed",
+			tamper:  func(r *http.Request) []byte { return []byte(`{"type":"Delete"}`) },
+			now:     now,
+			wantErr: ErrDigest,
+		},
+		{
+			name: "Path changed",
+			tamper: func(r *http.Request) []byte {
+				r.URL.Path = "/users/someone-else/inbox"
+				return body
+			},
+			now:     now,
+			wantErr: ErrVerification,
+		},
+		{
+			name: "Host changed",
+			tamper: func(r *http.Request) []byte {
+				r.Host = "other.example"
+				return body
+			},
+			now:     now,
+			wantErr: ErrVerification,
+		},
+		{
+			name:    "Replayed later",
+			tamper:  func(r *http.Request) []byte { return body },
+			now:     now.Add(MaxClockSkew + time.Minute),
+			wantErr: ErrDate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Sign a client request, then read it back as a server would.
+			out, _ := http.NewRequest(http.MethodPost, "https://chirpy.example/users/1/inbox", bytes.NewReader(body))
+			if err := Sign(out, "https://remote.example/users/2#main-key", private, body); err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			in := httptest.NewRequest(http.MethodPost, "/users/1/inbox", nil)
+			in.Host = "chirpy.example"
+			in.Header = out.Header.Clone()
+
+			got := tt.tamper(in)
+			sig, err := Parse(in)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if sig.KeyID != "https://remote.example/users/2#main-key" {
+				t.Errorf("KeyID = %q", sig.KeyID)
+			}
+			if err := Verify(in, got, sig, public, tt.now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("Wrong key", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "https://chirpy.example/users/1/inbox", nil)
+		if err := Sign(r, "key", private, body); err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+		sig, err := Parse(r)
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if err := Verify(r, body, sig, other, now); !errors.Is(err, ErrVerification) {
+			t.Errorf("Verify() error = %v, want %v", err, ErrVerification)
+		}
+	})
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		wantHeaders []string
+		wantErr     error
+	}{
+		{
+			name:        "Full header",
+			header:      `keyId="https://a.example/u#key",algorithm="rsa-sha256",headers="(request-target) host date digest",signature="c2ln"`,
+			wantHeaders: []string{"(request-target)", "host", "date", "digest"},
+		},
+		{
+			name:        "No headers parameter signs only the date",
+			header:      `keyId="k",signature="c2ln"`,
+			wantHeaders: []string{"date"},
+		},
+		{
+			name:        "Comma inside a value",
+			header:      `keyId="k,1", signature="c2ln"`,
+			wantHeaders: []string{"date"},
+		},
+		{name: "Missing", header: "", wantErr: ErrNoSignature},
+		{name: "Unquoted value", header: `keyId=k,signature="c2ln"`, wantErr: ErrMalformed},
+		{name: "No key", header: `signature="c2ln"`, wantErr: ErrMalformed},
+		{name: "Other algorithm", header: `keyId="k",algorithm="hmac-sha256",signature="c2ln"`, wantErr: ErrAlgorithm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Signature", tt.header)
+			}
+			sig, err := Parse(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !equal(sig.Headers, tt.wantHeaders) {
+				t.Errorf("Headers = %v, want %v", sig.Headers, tt.wantHeaders)
+			}
+		})
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,261 @@
+// Package linkpreview fetches the OpenGraph and Twitter card metadata of
+// pages linked from chirps. Fetching is locked down because the URLs come
+// from users: only public addresses are dialed, responses are capped in
+// size and time, and domains can be allowed or denied by configuration.
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/safehttp"
+)
+
+const (
+	// MaxURLLength is the longest URL a preview is made for.
+	MaxURLLength = 2048
+	// maxBodyBytes is how much of a page is read. Metadata lives in the
+	// head, so this is plenty.
+	maxBodyBytes = 512 << 10
+	maxRedirects = 5
+	fetchTimeout = 5 * time.Second
+	dialTimeout  = 3 * time.Second
+
+	maxTitleLength       = 200
+	maxDescriptionLength = 500
+	userAgent            = "ChirpyBot/1.0 (link previews)"
+)
+
+var (
+	ErrBlocked        = errors.New("domain isn't allowed for previews")
+	ErrPrivateAddress = safehttp.ErrPrivateAddress
+	ErrNotHTML        = errors.New("page isn't HTML")
+	ErrBadURL         = errors.New("invalid link URL")
+)
+
+// Preview is the metadata shown on a link's card.
+type Preview struct {
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+// Policy decides which domains previews are fetched from. A domain also
+// matches its subdomains. Deny wins over Allow, and an empty Allow permits
+// every domain that isn't denied.
+type Policy struct {
+	Allow []string
+	Deny  []string
+}
+
+// ParseDomains reads a comma separated domain list from configuration.
+func ParseDomains(s string) []string {
+	var domains []string
+	for _, d := range strings.Split(s, ",") {
+		d = strings.Trim(strings.ToLower(strings.TrimSpace(d)), ".")
+		if d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+// Permits reports whether previews may be fetched from host.
+func (p Policy) Permits(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if matchesAny(host, p.Deny) {
+		return false
+	}
+	return len(p.Allow) == 0 || matchesAny(host, p.Allow)
+}
+
+func matchesAny(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize returns the form of a link URL previews are cached under: an
+// absolute http(s) URL with a lowercase scheme and host and no fragment.
+func Normalize(rawURL string) (string, error) {
+	if len(rawURL) > MaxURLLength {
+		return "", ErrBadURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return "", ErrBadURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String(), nil
+}
+
+// Fetcher fetches previews. Its zero value isn't usable; use NewFetcher.
+type Fetcher struct {
+	policy Policy
+	client *http.Client
+}
+
+func NewFetcher(policy Policy) *Fetcher {
+	return newFetcher(policy, false)
+}
+
+// newFetcher returns a fetcher, which with allowPrivate can reach local
+// servers in tests.
+func newFetcher(policy Policy, allowPrivate bool) *Fetcher {
+	f := &Fetcher{policy: policy}
+	f.client = safehttp.NewClient(safehttp.Options{
+		Timeout:      fetchTimeout,
+		DialTimeout:  dialTimeout,
+		MaxRedirects: maxRedirects,
+		CheckURL:     f.check,
+		AllowPrivate: allowPrivate,
+	})
+	return f
+}
+
+func (f *Fetcher) check(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrBadURL
+	}
+	if !f.policy.Permits(u.Hostname()) {
+		return ErrBlocked
+	}
+	return nil
+}
+
+// Fetch retrieves the page at rawURL and reads its metadata. ErrBlocked,
+// ErrPrivateAddress, ErrBadURL and ErrNotHTML mean retrying won't help.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Preview{}, ErrBadURL
+	}
+	if err := f.check(u); err != nil {
+		return Preview{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return Preview{}, ErrBadURL
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		// The client wraps errors from CheckRedirect and the dialer.
+		for _, permanent := range []error{ErrBlocked, ErrPrivateAddress, ErrBadURL} {
+			if errors.Is(err, permanent) {
+				return Preview{}, permanent
+			}
+		}
+		return Preview{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("page responded %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Preview{}, ErrNotHTML
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		return Preview{}, err
+	}
+	return Parse(string(page), resp.Request.URL), nil
+}
+
+var (
+	metaTag   = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attribute = regexp.MustCompile(`(?is)([a-z][a-z0-9:_-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	titleTag  = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	headEnd   = regexp.MustCompile(`(?i)</head\s*>`)
+)
+
+// Parse reads a page's OpenGraph tags, falling back to Twitter card tags
+// and then the plain title and description. base resolves a relative
+// image URL.
+func Parse(page string, base *url.URL) Preview {
+	if loc := headEnd.FindStringIndex(page); loc != nil {
+		page = page[:loc[0]]
+	}
+
+	meta := map[string]string{}
+	for _, tag := range metaTag.FindAllString(page, -1) {
+		attrs := map[string]string{}
+		for _, m := range attribute.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = m[2] + m[3] + m[4]
+		}
+		key := strings.ToLower(attrs["property"])
+		if key == "" {
+			key = strings.ToLower(attrs["name"])
+		}
+		if _, seen := meta[key]; key != "" && !seen {
+			meta[key] = clean(attrs["content"])
+		}
+	}
+
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := meta[k]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	p := Preview{
+		Title:       first("og:title", "twitter:title"),
+		Description: first("og:description", "twitter:description", "description"),
+		SiteName:    first("og:site_name"),
+	}
+	if p.Title == "" {
+		if m := titleTag.FindStringSubmatch(page); m != nil {
+			p.Title = clean(m[1])
+		}
+	}
+	p.Title = truncate(p.Title, maxTitleLength)
+	p.Description = truncate(p.Description, maxDescriptionLength)
+
+	if image := first("og:image:secure_url", "og:image", "twitter:image", "twitter:image:src"); image != "" && base != nil {
+		if u, err := base.Parse(image); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			p.ImageURL = u.String()
+		}
+	}
+	return p
+}
+
+// clean unescapes HTML entities and collapses whitespace.
+func clean(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return strings.TrimSpace(string([]rune(s)[:n-1])) + "…"
+}
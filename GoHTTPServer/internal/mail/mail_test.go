@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     Message
+		want    string
+		wantErr error
+	}{
+		{
+			name: "Plain message",
+			msg:  Message{To: "alice@example.com", Subject: "Hello", Body: "Line one\nLine two"},
+			want: "From: chirpy@example.com\r\n" +
+				"To: alice@example.com\r\n" +
+				"Subject: Hello\r\n" +
+				"MIME-Version: 1.0\r\n" +
+				"Content-Type: text/plain; charset=UTF-8\r\n" +
+				"\r\n" +
+				"Line one\r\nLine two",
+		},
+		{
+			name:    "Line break in subject",
+			msg:     Message{To: "alice@example.com", Subject: "Hi\r\nBcc: mallory@example.com"},
+			wantErr: ErrHeaderInjection,
+		},
+		{
+			name:    "Line break in recipient",
+			msg:     Message{To: "alice@example.com\nBcc: mallory@example.com", Subject: "Hi"},
+			wantErr: ErrHeaderInjection,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := format("chirpy@example.com", tt.msg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("format() error = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	MaxUploadBytes = 5 << 20
+	maxPixels      = 25_000_000
+	// maxGIFFrames and maxGIFPixels bound an animated GIF, whose frames are
+	// all decoded at once. maxGIFPixels is the total over every frame.
+	maxGIFFrames  = 500
+	maxGIFPixels  = 100_000_000
+	thumbnailSize = 320
+	jpegQuality   = 90
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrTooLarge        = errors.New("image dimensions too large")
+)
+
+// extensions maps the content types we accept to the file extension used
+// for stored blobs.
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Processed is an upload that has been validated and re-encoded.
+type Processed struct {
+	ContentType string
+	Extension   string
+	Data        []byte
+	Width       int
+	Height      int
+	Sha256      string
+
+	Thumbnail            []byte
+	ThumbnailContentType string
+	ThumbnailExtension   string
+}
+
+// Process checks that data is an image we accept and re-encodes it.
+// Re-encoding is what strips EXIF and other metadata: only pixel data
+// survives, so GPS tags, camera details and embedded comments are dropped
+// along with the EXIF orientation flag. The content type is sniffed from
+// the bytes and any client-supplied type is ignored.
+func Process(data []byte) (*Processed, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read image header: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+	if contentType == "image/gif" {
+		frames, err := countGIFFrames(data)
+		if err != nil {
+			return nil, err
+		}
+		if frames > maxGIFFrames || frames*cfg.Width*cfg.Height > maxGIFPixels {
+			return nil, ErrTooLarge
+		}
+	}
+
+	var out bytes.Buffer
+	var img image.Image
+
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: jpegQuality})
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		err = png.Encode(&out, img)
+	case "image/gif":
+		var anim *gif.GIF
+		anim, err = gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		img = anim.Image[0]
+		err = gif.EncodeAll(&out, anim)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	thumb, thumbType, thumbExt, err := encodeThumbnail(img, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(out.Bytes())
+	bounds := img.Bounds()
+
+	return &Processed{
+		ContentType:          contentType,
+		Extension:            ext,
+		Data:                 out.Bytes(),
+		Width:                bounds.Dx(),
+		Height:               bounds.Dy(),
+		Sha256:               hex.EncodeToString(sum[:]),
+		Thumbnail:            thumb,
+		ThumbnailContentType: thumbType,
+		ThumbnailExtension:   thumbExt,
+	}, nil
+}
+
+// encodeThumbnail shrinks img to fit in a thumbnailSize square. Photos stay
+// JPEG; PNGs and GIFs become PNG so transparency is kept.
+func encodeThumbnail(img image.Image, contentType string) ([]byte, string, string, error) {
+	thumb := Thumbnail(img, thumbnailSize)
+
+	var out bytes.Buffer
+	if contentType == "image/jpeg" {
+		if err := jpeg.Encode(&out, thumb, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, "", "", err
+		}
+		return out.Bytes(), "image/jpeg", ".jpg", nil
+	}
+
+	if err := png.Encode(&out, thumb); err != nil {
+		return nil, "", "", err
+	}
+	return out.Bytes(), "image/png", ".png", nil
+}
+
+// Thumbnail scales img down to fit within a maxSize square, keeping its
+// aspect ratio. Each output pixel is the average of the source pixels it
+// covers. Images that already fit are copied unchanged.
+func Thumbnail(img image.Image, maxSize int) image.Image {
+	src := img.Bounds()
+	w, h := src.Dx(), src.Dy()
+	if w > maxSize || h > maxSize {
+		if w >= h {
+			h = max(1, h*maxSize/w)
+			w = maxSize
+		} else {
+			w = max(1, w*maxSize/h)
+			h = maxSize
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := src.Min.Y + y*src.Dy()/h
+		y1 := max(y0+1, src.Min.Y+(y+1)*src.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := src.Min.X + x*src.Dx()/w
+			x1 := max(x0+1, src.Min.X+(x+1)*src.Dx()/w)
+			dst.SetNRGBA(x, y, averageArea(img, x0, y0, x1, y1))
+		}
+	}
+	return dst
+}
+
+// averageArea averages at most a 4x4 grid of samples from the rectangle,
+// which is plenty for a thumbnail and keeps large images fast.
+func averageArea(img image.Image, x0, y0, x1, y1 int) color.NRGBA {
+	stepX := max(1, (x1-x0)/4)
+	stepY := max(1, (y1-y0)/4)
+
+	var r, g, b, a, n uint64
+	for y := y0; y < y1; y += stepY {
+		for x := x0; x < x1; x += stepX {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			r += uint64(c.R)
+			g += uint64(c.G)
+			b += uint64(c.B)
+			a += uint64(c.A)
+			n++
+		}
+	}
+	return color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)}
+}
+
+var errBadGIF = errors.New("malformed GIF")
+
+// countGIFFrames counts the images in a GIF by walking its blocks, without
+// decompressing any of them.
+func countGIFFrames(data []byte) (int, error) {
+	// Header, logical screen width and height, then the packed fields
+	// byte saying whether a global color table follows.
+	const headerLen = 13
+	if len(data) < headerLen {
+		return 0, errBadGIF
+	}
+	pos := headerLen
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << (flags&0x07 + 1)
+	}
+
+	// skipSubBlocks moves past a run of length-prefixed data sub-blocks
+	// ending with a zero length.
+	skipSubBlocks := func() error {
+		for {
+			if pos >= len(data) {
+				return errBadGIF
+			}
+			n := int(data[pos])
+			pos++
+			if n == 0 {
+				return nil
+			}
+			pos += n
+		}
+	}
+
+	frames := 0
+	for {
+		if pos >= len(data) {
+			return 0, errBadGIF
+		}
+		block := data[pos]
+		pos++
+		switch block {
+		case 0x21: // Extension: a label byte, then sub-blocks.
+			pos++
+			if err := skipSubBlocks(); err != nil {
+				return 0, err
+			}
+		case 0x2C: // Image descriptor.
+			frames++
+			if pos+9 > len(data) {
+				return 0, errBadGIF
+			}
+			flags := data[pos+8]
+			pos += 9
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			pos++ // LZW minimum code size.
+			if err := skipSubBlocks(); err != nil {
+				return 0, err
+			}
+		case 0x3B: // Trailer.
+			return frames, nil
+		default:
+			return 0, errBadGIF
+		}
+	}
+}
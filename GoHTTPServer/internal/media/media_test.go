@@ -0,0 +1,160 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	return img
+}
+
+// withEXIF inserts an APP1 EXIF segment right after the JPEG SOI marker.
+func withEXIF(jpegData []byte) []byte {
+	payload := append([]byte("Exif\x00\x00"), []byte("GPS 51.5N 0.1W")...)
+	segment := []byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpegData[:2]...)
+	out = append(out, segment...)
+	return append(out, jpegData[2:]...)
+}
+
+func TestProcessStripsEXIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(640, 480), nil); err != nil {
+		t.Fatal(err)
+	}
+	upload := withEXIF(buf.Bytes())
+	if !bytes.Contains(upload, []byte("Exif")) {
+		t.Fatal("test upload is missing its EXIF segment")
+	}
+
+	got, err := Process(upload)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if bytes.Contains(got.Data, []byte("Exif")) || bytes.Contains(got.Data, []byte("GPS")) {
+		t.Error("Process() kept EXIF metadata")
+	}
+	if got.ContentType != "image/jpeg" || got.Width != 640 || got.Height != 480 {
+		t.Errorf("Process() = %s %dx%d, want image/jpeg 640x480", got.ContentType, got.Width, got.Height)
+	}
+	if len(got.Sha256) != 64 {
+		t.Errorf("Process() hash = %q, want 64 hex characters", got.Sha256)
+	}
+
+	thumb, err := jpeg.Decode(bytes.NewReader(got.Thumbnail))
+	if err != nil {
+		t.Fatalf("thumbnail doesn't decode: %v", err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 320 || b.Dy() != 240 {
+		t.Errorf("thumbnail is %dx%d, want 320x240", b.Dx(), b.Dy())
+	}
+}
+
+func TestProcessIsDeterministic(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(50, 50)); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	second, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if first.Sha256 != second.Sha256 {
+		t.Error("Process() hashed the same upload differently")
+	}
+}
+
+func TestProcessRejectsNonImages(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "Plain text", data: []byte("definitely not an image")},
+		{name: "HTML", data: []byte("<html><script>alert(1)</script></html>")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Process(tt.data)
+			if !errors.Is(err, ErrUnsupportedType) {
+				t.Errorf("Process() error = %v, want ErrUnsupportedType", err)
+			}
+		})
+	}
+}
+
+func TestThumbnailKeepsSmallImages(t *testing.T) {
+	thumb := Thumbnail(testImage(100, 40), 320)
+	if b := thumb.Bounds(); b.Dx() != 100 || b.Dy() != 40 {
+		t.Errorf("Thumbnail() = %dx%d, want 100x40", b.Dx(), b.Dy())
+	}
+}
+
+func testGIF(t *testing.T, frames int) []byte {
+	t.Helper()
+	anim := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 4), palette.Plan9)
+		frame.SetColorIndex(i%4, 0, uint8(i))
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var b bytes.Buffer
+	if err := gif.EncodeAll(&b, anim); err != nil {
+		t.Fatalf("EncodeAll() error = %v", err)
+	}
+	return b.Bytes()
+}
+
+func TestProcessGIFFrames(t *testing.T) {
+	tests := []struct {
+		name    string
+		frames  int
+		wantErr error
+	}{
+		{name: "Animation", frames: 3},
+		{name: "Too many frames", frames: maxGIFFrames + 1, wantErr: ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := testGIF(t, tt.frames)
+			if n, err := countGIFFrames(data); err != nil || n != tt.frames {
+				t.Fatalf("countGIFFrames() = %d, %v, want %d", n, err, tt.frames)
+			}
+			_, err := Process(data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Process() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCountGIFFramesRejectsTruncated(t *testing.T) {
+	data := testGIF(t, 2)
+	if _, err := countGIFFrames(data[:len(data)-4]); err == nil {
+		t.Error("countGIFFrames() of a truncated GIF succeeded")
+	}
+}
@@ -0,0 +1,71 @@
+package moderation
+
+import "time"
+
+// Reason is why a user reported a chirp.
+type Reason string
+
+const (
+	ReasonSpam           Reason = "spam"
+	ReasonHarassment     Reason = "harassment"
+	ReasonHate           Reason = "hate"
+	ReasonViolence       Reason = "violence"
+	ReasonSexual         Reason = "sexual"
+	ReasonSelfHarm       Reason = "self_harm"
+	ReasonMisinformation Reason = "misinformation"
+	ReasonOther          Reason = "other"
+)
+
+func ValidReason(r Reason) bool {
+	switch r {
+	case ReasonSpam, ReasonHarassment, ReasonHate, ReasonViolence,
+		ReasonSexual, ReasonSelfHarm, ReasonMisinformation, ReasonOther:
+		return true
+	}
+	return false
+}
+
+// Decision is how a moderator resolves a case.
+type Decision string
+
+const (
+	// DecisionDismiss restores the chirp if it was hidden.
+	DecisionDismiss Decision = "dismiss"
+	// DecisionHide keeps the chirp hidden without penalising its author.
+	DecisionHide Decision = "hide"
+	// DecisionRemove hides the chirp and gives its author a strike.
+	DecisionRemove Decision = "remove"
+)
+
+func ValidDecision(d Decision) bool {
+	return d == DecisionDismiss || d == DecisionHide || d == DecisionRemove
+}
+
+const (
+	// AutoHideReports is how many reports a chirp needs before it's hidden
+	// while it waits for a moderator.
+	AutoHideReports = 3
+
+	// AutoHideMinAccountAge is how old a reporter's account has to be for
+	// their report to count towards AutoHideReports.
+	AutoHideMinAccountAge = 7 * 24 * time.Hour
+
+	// StrikeLifetime is how long a strike counts towards suspension.
+	StrikeLifetime = 90 * 24 * time.Hour
+
+	MaxReportDetails = 500
+)
+
+// SuspensionFor returns how long to suspend a user who now has
+// activeStrikes unexpired strikes. Zero means no suspension: the first two
+// strikes are warnings, the third suspends for a week and any beyond that
+// for a month.
+func SuspensionFor(activeStrikes int64) time.Duration {
+	switch {
+	case activeStrikes >= 4:
+		return 30 * 24 * time.Hour
+	case activeStrikes == 3:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package moderation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuspensionFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		strikes int64
+		want    time.Duration
+	}{
+		{name: "No strikes", strikes: 0, want: 0},
+		{name: "First strike is a warning", strikes: 1, want: 0},
+		{name: "Second strike is a warning", strikes: 2, want: 0},
+		{name: "Third strike", strikes: 3, want: 7 * 24 * time.Hour},
+		{name: "Fourth strike", strikes: 4, want: 30 * 24 * time.Hour},
+		{name: "Many strikes", strikes: 10, want: 30 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SuspensionFor(tt.strikes); got != tt.want {
+				t.Errorf("SuspensionFor(%d) = %v, want %v", tt.strikes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidReason(t *testing.T) {
+	tests := []struct {
+		reason Reason
+		want   bool
+	}{
+		{reason: ReasonSpam, want: true},
+		{reason: ReasonSelfHarm, want: true},
+		{reason: ReasonOther, want: true},
+		{reason: "", want: false},
+		{reason: "Spam", want: false},
+		{reason: "boring", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.reason), func(t *testing.T) {
+			if got := ValidReason(tt.reason); got != tt.want {
+				t.Errorf("ValidReason(%q) = %v, want %v", tt.reason, got, tt.want)
+			}
+		})
+	}
+}
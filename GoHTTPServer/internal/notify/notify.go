@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Type is what a notification is about.
+type Type string
+
+const (
+	// TypeMention is sent to each user mentioned in a chirp.
+	TypeMention Type = "mention"
+	// TypeFollow is sent when someone follows the user.
+	TypeFollow Type = "follow"
+	// TypeRechirp is sent when someone rechirps one of the user's chirps.
+	TypeRechirp Type = "rechirp"
+	// TypeQuote is sent when someone quotes one of the user's chirps.
+	TypeQuote Type = "quote"
+	// TypeLike is sent when someone likes one of the user's chirps.
+	TypeLike Type = "like"
+	// TypeReply is sent when someone replies to one of the user's chirps.
+	TypeReply Type = "reply"
+)
+
+// Types lists every notification type, in the order preferences are shown.
+var Types = []Type{TypeMention, TypeReply, TypeFollow, TypeLike, TypeRechirp, TypeQuote}
+
+func ValidType(t Type) bool {
+	switch t {
+	case TypeMention, TypeFollow, TypeRechirp, TypeQuote, TypeLike, TypeReply:
+		return true
+	}
+	return false
+}
+
+// GroupKey returns the key that repeated events are folded under while the
+// recipient hasn't read them. Follows all share one notification, and
+// likes and rechirps share one per chirp. Mentions, quotes and replies each
+// carry their own text, so chirpID is the mentioning, quoting or replying
+// chirp and they're never grouped.
+func GroupKey(t Type, chirpID uuid.UUID) string {
+	if t == TypeFollow {
+		return string(t)
+	}
+	return string(t) + ":" + chirpID.String()
+}
+
+// Summary describes a notification from actors people.
+func Summary(t Type, actors int64) string {
+	who := "Someone"
+	if actors > 1 {
+		who = fmt.Sprintf("%d people", actors)
+	}
+
+	switch t {
+	case TypeMention:
+		return who + " mentioned you"
+	case TypeFollow:
+		return who + " followed you"
+	case TypeRechirp:
+		return who + " rechirped your chirp"
+	case TypeQuote:
+		return who + " quoted your chirp"
+	case TypeLike:
+		return who + " liked your chirp"
+	case TypeReply:
+		return who + " replied to your chirp"
+	}
+	return who + " interacted with you"
+}
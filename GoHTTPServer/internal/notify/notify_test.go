@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestGroupKey(t *testing.T) {
+	first := uuid.New()
+	second := uuid.New()
+
+	tests := []struct {
+		name      string
+		a, b      Type
+		chirpA    uuid.UUID
+		chirpB    uuid.UUID
+		wantGroup bool
+	}{
+		{
+			name:      "Follows group together",
+			a:         TypeFollow,
+			b:         TypeFollow,
+			chirpA:    uuid.Nil,
+			chirpB:    uuid.Nil,
+			wantGroup: true,
+		},
+		{
+			name:      "Rechirps of one chirp group together",
+			a:         TypeRechirp,
+			b:         TypeRechirp,
+			chirpA:    first,
+			chirpB:    first,
+			wantGroup: true,
+		},
+		{
+			name:   "Rechirps of different chirps don't",
+			a:      TypeRechirp,
+			b:      TypeRechirp,
+			chirpA: first,
+			chirpB: second,
+		},
+		{
+			name:      "Likes of one chirp group together",
+			a:         TypeLike,
+			b:         TypeLike,
+			chirpA:    first,
+			chirpB:    first,
+			wantGroup: true,
+		},
+		{
+			name:   "Different types don't",
+			a:      TypeRechirp,
+			b:      TypeQuote,
+			chirpA: first,
+			chirpB: first,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GroupKey(tt.a, tt.chirpA) == GroupKey(tt.b, tt.chirpB)
+			if got != tt.wantGroup {
+				t.Errorf("grouped = %v, want %v", got, tt.wantGroup)
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		name   string
+		t      Type
+		actors int64
+		want   string
+	}{
+		{name: "Single follow", t: TypeFollow, actors: 1, want: "Someone followed you"},
+		{name: "Grouped rechirps", t: TypeRechirp, actors: 5, want: "5 people rechirped your chirp"},
+		{name: "Grouped likes", t: TypeLike, actors: 2, want: "2 people liked your chirp"},
+		{name: "Single reply", t: TypeReply, actors: 1, want: "Someone replied to your chirp"},
+		{name: "Mention", t: TypeMention, actors: 1, want: "Someone mentioned you"},
+		{name: "Quote", t: TypeQuote, actors: 1, want: "Someone quoted your chirp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summary(tt.t, tt.actors); got != tt.want {
+				t.Errorf("Summary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Cursor marks a position in a list ordered by (created_at, id) descending.
+// The next page holds everything strictly older than the cursor.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Start returns a cursor positioned before every row, for fetching the
+// first page.
+func Start() Cursor {
+	return Cursor{
+		CreatedAt: time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC),
+		ID:        uuid.Max,
+	}
+}
+
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Parse decodes a cursor produced by Encode. An empty string yields Start.
+func Parse(s string) (Cursor, error) {
+	if s == "" {
+		return Start(), nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor encoding: %w", err)
+	}
+
+	createdAtStr, idStr, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return Cursor{}, errors.New("invalid cursor format")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtStr)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor time: %w", err)
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor id: %w", err)
+	}
+
+	return Cursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// ParseLimit reads a page size from a query string value, falling back to
+// DefaultLimit when empty and capping at MaxLimit.
+func ParseLimit(s string) (int32, error) {
+	if s == "" {
+		return DefaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return int32(limit), nil
+}
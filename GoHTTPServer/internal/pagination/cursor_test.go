@@ -0,0 +1,89 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := Cursor{
+		CreatedAt: time.Date(2025, time.July, 4, 12, 30, 15, 123456000, time.UTC),
+		ID:        uuid.New(),
+	}
+
+	got, err := Parse(want.Encode())
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Cursor
+		wantErr bool
+	}{
+		{
+			name:  "Empty cursor starts at the beginning",
+			input: "",
+			want:  Start(),
+		},
+		{
+			name:    "Not base64",
+			input:   "%%%",
+			wantErr: true,
+		},
+		{
+			name:    "Missing separator",
+			input:   "bm9zZXBhcmF0b3I",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && (!got.CreatedAt.Equal(tt.want.CreatedAt) || got.ID != tt.want.ID) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int32
+		wantErr bool
+	}{
+		{name: "Default", input: "", want: DefaultLimit},
+		{name: "Within range", input: "5", want: 5},
+		{name: "Capped", input: "1000", want: MaxLimit},
+		{name: "Zero", input: "0", wantErr: true},
+		{name: "Not a number", input: "ten", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLimit(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseLimit() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
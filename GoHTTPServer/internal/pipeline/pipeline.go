@@ -0,0 +1,163 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Content is a chirp on its way through the pipeline. Stages may rewrite
+// Body, record what they measured and raise flags for moderators.
+type Content struct {
+	AuthorID uuid.UUID
+	Body     string
+
+	Length    int
+	MaxLength int
+	SpamScore int
+	Flags     []Flag
+
+	stage string
+}
+
+// Flag asks a moderator to review the chirp once it's published.
+type Flag struct {
+	Stage  string
+	Reason string
+}
+
+// Flag records reason against the stage currently running.
+func (c *Content) Flag(reason string) {
+	c.Flags = append(c.Flags, Flag{Stage: c.stage, Reason: reason})
+}
+
+// Rejection is returned when a stage refuses a chirp outright. Code is a
+// stable machine-readable identifier and Message is safe to show users.
+type Rejection struct {
+	Stage   string
+	Code    string
+	Message string
+}
+
+func (r *Rejection) Error() string {
+	return fmt.Sprintf("rejected by %s: %s", r.Stage, r.Message)
+}
+
+// Reject builds the error a stage returns to stop the pipeline. The stage
+// name is filled in by Run.
+func Reject(code, message string) error {
+	return &Rejection{Code: code, Message: message}
+}
+
+// Stage is one step of content processing. Process returns a *Rejection
+// (see Reject) to refuse the chirp; any other error is treated as a failure
+// of the pipeline itself.
+type Stage interface {
+	Name() string
+	Process(ctx context.Context, c *Content) error
+}
+
+type stageFunc struct {
+	name string
+	fn   func(ctx context.Context, c *Content) error
+}
+
+func (s stageFunc) Name() string { return s.name }
+
+func (s stageFunc) Process(ctx context.Context, c *Content) error {
+	return s.fn(ctx, c)
+}
+
+// StageFunc wraps a function as a named Stage.
+func StageFunc(name string, fn func(ctx context.Context, c *Content) error) Stage {
+	return stageFunc{name: name, fn: fn}
+}
+
+// Pipeline runs stages in order.
+type Pipeline struct {
+	stages []Stage
+}
+
+func New(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Stages returns the names of the stages in the order they run.
+func (p *Pipeline) Stages() []string {
+	names := make([]string, len(p.stages))
+	for i, s := range p.stages {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// Require checks that the named stages are in the pipeline and run in the
+// order given.
+func (p *Pipeline) Require(names ...string) error {
+	stages := p.Stages()
+	last := -1
+	for _, name := range names {
+		i := slices.Index(stages, name)
+		if i < 0 {
+			return fmt.Errorf("pipeline needs a %q stage", name)
+		}
+		if i < last {
+			return fmt.Errorf("pipeline stage %q must run after %q", name, stages[last])
+		}
+		last = i
+	}
+	return nil
+}
+
+// Run passes c through every stage, stopping at the first error. A
+// *Rejection is returned with its Stage set.
+func (p *Pipeline) Run(ctx context.Context, c *Content) error {
+	for _, s := range p.stages {
+		c.stage = s.Name()
+		err := s.Process(ctx, c)
+		var rejection *Rejection
+		if errors.As(err, &rejection) {
+			rejection.Stage = s.Name()
+			return rejection
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.Name(), err)
+		}
+	}
+	c.stage = ""
+	return nil
+}
+
+// Factory builds a stage from the option given after '=' in a spec, which
+// is empty when there isn't one.
+type Factory func(option string) (Stage, error)
+
+// Registry maps stage names to the factories that build them.
+type Registry map[string]Factory
+
+// Build assembles a pipeline from a comma-separated spec such as
+// "length=140,normalize,profanity". Stages run in the order listed.
+func (reg Registry) Build(spec string) (*Pipeline, error) {
+	var stages []Stage
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, option, _ := strings.Cut(item, "=")
+		factory, ok := reg[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown pipeline stage %q", name)
+		}
+		stage, err := factory(option)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline stage %q: %w", name, err)
+		}
+		stages = append(stages, stage)
+	}
+	return New(stages...), nil
+}
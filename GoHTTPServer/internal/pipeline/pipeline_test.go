@@ -0,0 +1,189 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/profanity"
+)
+
+func testRegistry() Registry {
+	filter := profanity.New([]profanity.Term{
+		{Term: "kerfuffle", Action: profanity.ActionMask},
+		{Term: "fornax", Action: profanity.ActionFlag},
+		{Term: "gronk", Action: profanity.ActionReject},
+	})
+	reg := Builtins()
+	reg["profanity"] = func(string) (Stage, error) {
+		return Profanity(func() *profanity.Filter { return filter }), nil
+	}
+	return reg
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		wantBody      string
+		wantRejection string
+		wantFlags     []string
+	}{
+		{
+			name:     "Clean chirp",
+			body:     "  hello @alice\r\nsee https://example.com/a.  ",
+			wantBody: "hello @alice\nsee https://example.com/a.",
+		},
+		{
+			name:     "Emoji are counted as one character each",
+			body:     strings.Repeat("\U0001f600", 100),
+			wantBody: strings.Repeat("\U0001f600", 100),
+		},
+		{
+			name:          "Too long",
+			body:          strings.Repeat("a", 141),
+			wantRejection: "length",
+		},
+		{
+			name:     "Masked term",
+			body:     "what a Kerfuffle!",
+			wantBody: "what a ****!",
+		},
+		{
+			name:          "Rejected term",
+			body:          "gronk",
+			wantRejection: "profanity",
+		},
+		{
+			name:      "Flagged term",
+			body:      "fornax",
+			wantBody:  "fornax",
+			wantFlags: []string{"profanity"},
+		},
+		{
+			name:      "Spam",
+			body:      "BUY NOW https://a.com https://b.com https://c.com https://d.com !!!!!!!!!!",
+			wantBody:  "BUY NOW https://a.com https://b.com https://c.com https://d.com !!!!!!!!!!",
+			wantFlags: []string{"spam"},
+		},
+	}
+
+	p, err := testRegistry().Build(DefaultSpec)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Content{Body: tt.body}
+			err := p.Run(context.Background(), c)
+
+			if tt.wantRejection != "" {
+				var rejection *Rejection
+				if !errors.As(err, &rejection) {
+					t.Fatalf("Run() error = %v, want a rejection", err)
+				}
+				if rejection.Stage != tt.wantRejection {
+					t.Errorf("rejection stage = %q, want %q", rejection.Stage, tt.wantRejection)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if c.Body != tt.wantBody {
+				t.Errorf("Body = %q, want %q", c.Body, tt.wantBody)
+			}
+			var flagged []string
+			for _, f := range c.Flags {
+				flagged = append(flagged, f.Stage)
+			}
+			if !reflect.DeepEqual(flagged, tt.wantFlags) {
+				t.Errorf("flags from %v, want %v", flagged, tt.wantFlags)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       string
+		wantStages []string
+		wantErr    bool
+	}{
+		{
+			name:       "Default",
+			spec:       DefaultSpec,
+			wantStages: []string{"normalize", "length", "profanity", "spam"},
+		},
+		{
+			name:       "Options and spacing",
+			spec:       " length=280 , spam=5",
+			wantStages: []string{"length", "spam"},
+		},
+		{
+			name:    "Unknown stage",
+			spec:    "length,translate",
+			wantErr: true,
+		},
+		{
+			name:    "Bad option",
+			spec:    "length=lots",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := testRegistry().Build(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := p.Stages(); !reflect.DeepEqual(got, tt.wantStages) {
+				t.Errorf("Stages() = %v, want %v", got, tt.wantStages)
+			}
+		})
+	}
+}
+
+func TestRequire(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{
+			name: "Default",
+			spec: DefaultSpec,
+		},
+		{
+			name:    "Missing stage",
+			spec:    "normalize,profanity",
+			wantErr: true,
+		},
+		{
+			name:    "Wrong order",
+			spec:    "length,normalize",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := testRegistry().Build(tt.spec)
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			err = p.Require("normalize", "length")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Require() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/chirptext"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/entities"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/profanity"
+)
+
+const (
+	DefaultMaxLength     = 140
+	DefaultSpamThreshold = 3
+)
+
+// DefaultSpec is the pipeline used when none is configured.
+const DefaultSpec = "normalize,length,profanity,spam"
+
+// Builtins returns a registry of the stages that need no outside state.
+// The profanity stage depends on the server's filter, so callers register
+// it themselves with Profanity.
+func Builtins() Registry {
+	return Registry{
+		"length": func(option string) (Stage, error) {
+			n, err := intOption(option, DefaultMaxLength)
+			if err != nil {
+				return nil, err
+			}
+			return MaxLength(n), nil
+		},
+		"normalize": func(string) (Stage, error) { return Normalize(), nil },
+		"spam": func(option string) (Stage, error) {
+			n, err := intOption(option, DefaultSpamThreshold)
+			if err != nil {
+				return nil, err
+			}
+			return Spam(n), nil
+		},
+	}
+}
+
+func intOption(option string, fallback int) (int, error) {
+	if option == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(option)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("option must be a positive integer, got %q", option)
+	}
+	return n, nil
+}
+
+// MaxLength rejects bodies longer than n as measured by chirptext.Length.
+// It records the measurement on the content either way.
+func MaxLength(n int) Stage {
+	return StageFunc("length", func(ctx context.Context, c *Content) error {
+		c.Length = chirptext.Length(c.Body)
+		c.MaxLength = n
+		if c.Length > n {
+			return Reject("too_long", "Chirp is too long")
+		}
+		return nil
+	})
+}
+
+// Normalize rewrites the body with chirptext.Normalize.
+func Normalize() Stage {
+	return StageFunc("normalize", func(ctx context.Context, c *Content) error {
+		c.Body = chirptext.Normalize(c.Body)
+		return nil
+	})
+}
+
+// Profanity masks, flags or rejects banned terms using whichever filter
+// load returns at the time, so term list reloads take effect without
+// rebuilding the pipeline.
+func Profanity(load func() *profanity.Filter) Stage {
+	return StageFunc("profanity", func(ctx context.Context, c *Content) error {
+		result := load().Apply(c.Body)
+		if result.Action == profanity.ActionReject {
+			return Reject("banned_language", "Chirp contains banned language")
+		}
+		c.Body = result.Text
+
+		if result.Action == profanity.ActionFlag {
+			var terms []string
+			for _, m := range result.Matches {
+				if m.Action == profanity.ActionFlag {
+					terms = append(terms, m.Term)
+				}
+			}
+			c.Flag("matched flagged terms: " + strings.Join(terms, ", "))
+		}
+		return nil
+	})
+}
+
+const (
+	spamFreeLinks    = 2
+	spamFreeMentions = 5
+	spamRepeatRun    = 10
+	spamShoutLetters = 20
+)
+
+// Spam scores the chirp on a few cheap signals and flags it for review
+// once the score reaches threshold. Links and mentions are only counted
+// here; they're stored when the chirp is published.
+//
+// A point is added for each link past the second and each mention past the
+// fifth, for a character repeated ten or more times in a row, and for a
+// body of at least twenty letters written almost entirely in capitals.
+func Spam(threshold int) Stage {
+	return StageFunc("spam", func(ctx context.Context, c *Content) error {
+		_, mentions := entities.Extract(c.Body)
+		score := 0
+		score += max(0, len(chirptext.URLs(c.Body))-spamFreeLinks)
+		score += max(0, len(mentions)-spamFreeMentions)
+		if longestRun(c.Body) >= spamRepeatRun {
+			score++
+		}
+		if isShouting(c.Body) {
+			score++
+		}
+
+		c.SpamScore = score
+		if score >= threshold {
+			c.Flag(fmt.Sprintf("spam score %d", score))
+		}
+		return nil
+	})
+}
+
+func longestRun(s string) int {
+	longest, run := 0, 0
+	var prev rune = -1
+	for _, r := range s {
+		if r == prev {
+			run++
+		} else {
+			run = 1
+			prev = r
+		}
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+func isShouting(s string) bool {
+	letters, upper := 0, 0
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	return letters >= spamShoutLetters && upper*10 >= letters*9
+}
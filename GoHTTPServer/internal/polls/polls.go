@@ -0,0 +1,100 @@
+// Package polls checks the polls attached to chirps and the votes cast in
+// them. Storage and tallying live in the database; this package holds the
+// rules.
+package polls
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/chirptext"
+)
+
+const (
+	MinOptions = 2
+	MaxOptions = 4
+	// MaxOptionLength is measured the way chirps are, in graphemes.
+	MaxOptionLength = 25
+	MinDuration     = 5 * time.Minute
+	MaxDuration     = 7 * 24 * time.Hour
+)
+
+var (
+	ErrOptionCount     = errors.New("a poll needs 2 to 4 options")
+	ErrEmptyOption     = errors.New("poll options can't be empty")
+	ErrOptionLength    = errors.New("poll options can be at most 25 characters")
+	ErrDuplicateOption = errors.New("poll options must be different")
+	ErrClosingTime     = errors.New("a poll must close between 5 minutes and 7 days from now")
+
+	ErrNoChoice      = errors.New("pick at least one option")
+	ErrSingleChoice  = errors.New("this poll allows only one choice")
+	ErrUnknownOption = errors.New("no such option")
+	ErrRepeatChoice  = errors.New("each option can only be picked once")
+)
+
+// Options normalizes the options of a new poll and checks there's a
+// sensible number of distinct, short ones. Options are single lines.
+func Options(raw []string) ([]string, error) {
+	if len(raw) < MinOptions || len(raw) > MaxOptions {
+		return nil, ErrOptionCount
+	}
+
+	options := make([]string, 0, len(raw))
+	for _, o := range raw {
+		o = strings.Join(strings.Fields(chirptext.Normalize(o)), " ")
+		switch {
+		case o == "":
+			return nil, ErrEmptyOption
+		case chirptext.Length(o) > MaxOptionLength:
+			return nil, ErrOptionLength
+		}
+		for _, existing := range options {
+			if strings.EqualFold(existing, o) {
+				return nil, ErrDuplicateOption
+			}
+		}
+		options = append(options, o)
+	}
+	return options, nil
+}
+
+// CheckClosesAt checks a new poll's closing time.
+func CheckClosesAt(closesAt, now time.Time) error {
+	open := closesAt.Sub(now)
+	if open < MinDuration || open > MaxDuration {
+		return ErrClosingTime
+	}
+	return nil
+}
+
+// Choices checks the option indexes picked in a vote and returns them in
+// order.
+func Choices(choices []int, options int, multiple bool) ([]int, error) {
+	switch {
+	case len(choices) == 0:
+		return nil, ErrNoChoice
+	case len(choices) > 1 && !multiple:
+		return nil, ErrSingleChoice
+	}
+
+	sorted := slices.Clone(choices)
+	slices.Sort(sorted)
+	for i, c := range sorted {
+		if c < 0 || c >= options {
+			return nil, ErrUnknownOption
+		}
+		if i > 0 && sorted[i-1] == c {
+			return nil, ErrRepeatChoice
+		}
+	}
+	return sorted, nil
+}
+
+// ShowResults reports whether a viewer may see a poll's tallies. A poll
+// that hides its results shows them only to its author, to people who
+// have voted, and to everyone once it closes.
+func ShowResults(hideResults, isAuthor, voted, closed bool) bool {
+	return !hideResults || isAuthor || voted || closed
+}
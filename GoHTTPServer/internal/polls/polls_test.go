@@ -0,0 +1,116 @@
+package polls
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		want    []string
+		wantErr error
+	}{
+		{name: "Two options", options: []string{"Yes", "No"}, want: []string{"Yes", "No"}},
+		{name: "Whitespace collapsed", options: []string{"  Tacos \n on\tFriday ", "Pizza"}, want: []string{"Tacos on Friday", "Pizza"}},
+		{name: "Too few", options: []string{"Only"}, wantErr: ErrOptionCount},
+		{name: "Too many", options: []string{"a", "b", "c", "d", "e"}, wantErr: ErrOptionCount},
+		{name: "Empty", options: []string{"Yes", " \u200b "}, wantErr: ErrEmptyOption},
+		{name: "Too long", options: []string{"Yes", strings.Repeat("x", MaxOptionLength+1)}, wantErr: ErrOptionLength},
+		{name: "Emoji count once", options: []string{"Yes", strings.Repeat("\U0001f355", MaxOptionLength)}, want: []string{"Yes", strings.Repeat("\U0001f355", MaxOptionLength)}},
+		{name: "Duplicate", options: []string{"Yes", "yes"}, wantErr: ErrDuplicateOption},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Options(tt.options)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Options() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Options() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckClosesAt(t *testing.T) {
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		closesAt time.Time
+		wantErr  error
+	}{
+		{name: "In a day", closesAt: now.Add(24 * time.Hour)},
+		{name: "Shortest", closesAt: now.Add(MinDuration)},
+		{name: "Longest", closesAt: now.Add(MaxDuration)},
+		{name: "In the past", closesAt: now.Add(-time.Hour), wantErr: ErrClosingTime},
+		{name: "Too soon", closesAt: now.Add(time.Minute), wantErr: ErrClosingTime},
+		{name: "Too far", closesAt: now.Add(MaxDuration + time.Second), wantErr: ErrClosingTime},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckClosesAt(tt.closesAt, now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckClosesAt() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChoices(t *testing.T) {
+	tests := []struct {
+		name     string
+		choices  []int
+		multiple bool
+		want     []int
+		wantErr  error
+	}{
+		{name: "Single", choices: []int{1}, want: []int{1}},
+		{name: "Multiple sorted", choices: []int{2, 0}, multiple: true, want: []int{0, 2}},
+		{name: "None", choices: nil, wantErr: ErrNoChoice},
+		{name: "Several in single choice", choices: []int{0, 1}, wantErr: ErrSingleChoice},
+		{name: "Out of range", choices: []int{3}, wantErr: ErrUnknownOption},
+		{name: "Negative", choices: []int{-1}, wantErr: ErrUnknownOption},
+		{name: "Repeated", choices: []int{1, 1}, multiple: true, wantErr: ErrRepeatChoice},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Choices(tt.choices, 3, tt.multiple)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Choices() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Choices() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShowResults(t *testing.T) {
+	tests := []struct {
+		name                          string
+		hide, isAuthor, voted, closed bool
+		want                          bool
+	}{
+		{name: "Not hidden", want: true},
+		{name: "Hidden before voting", hide: true, want: false},
+		{name: "Hidden but voted", hide: true, voted: true, want: true},
+		{name: "Hidden but author", hide: true, isAuthor: true, want: true},
+		{name: "Hidden but closed", hide: true, closed: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShowResults(tt.hide, tt.isAuthor, tt.voted, tt.closed); got != tt.want {
+				t.Errorf("ShowResults() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package profanity
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Action is what happens to a chirp that contains a banned term.
+type Action string
+
+const (
+	ActionNone   Action = ""
+	ActionMask   Action = "mask"
+	ActionFlag   Action = "flag"
+	ActionReject Action = "reject"
+)
+
+const mask = "****"
+
+func ValidAction(a Action) bool {
+	return a == ActionMask || a == ActionFlag || a == ActionReject
+}
+
+// severity orders actions so the strictest one found in a text wins.
+func (a Action) severity() int {
+	switch a {
+	case ActionMask:
+		return 1
+	case ActionFlag:
+		return 2
+	case ActionReject:
+		return 3
+	}
+	return 0
+}
+
+type Term struct {
+	Term   string
+	Action Action
+}
+
+// Filter matches words against a fixed set of banned terms. It is
+// immutable, so one can be shared between goroutines and swapped out
+// wholesale when the term list changes.
+type Filter struct {
+	terms map[string]Action
+}
+
+func New(terms []Term) *Filter {
+	f := &Filter{terms: make(map[string]Action, len(terms))}
+	for _, t := range terms {
+		if key := Normalize(t.Term); key != "" {
+			f.terms[key] = t.Action
+		}
+	}
+	return f
+}
+
+type Match struct {
+	Term   string
+	Action Action
+}
+
+// Result is the outcome of filtering a text. Text has the words matching
+// mask terms masked; flagged words are kept so moderators see what was
+// written. Action is the strictest action among the matches.
+type Result struct {
+	Text    string
+	Action  Action
+	Matches []Match
+}
+
+// Apply checks every word in text. Words are split on any Unicode
+// whitespace, and each is tried both as-is and with surrounding
+// punctuation trimmed, so "Kerfuffle!" and "$harbert" are both caught.
+// The original whitespace is preserved in Result.Text.
+func (f *Filter) Apply(text string) Result {
+	result := Result{Action: ActionNone}
+	if f == nil || len(f.terms) == 0 {
+		result.Text = text
+		return result
+	}
+
+	var out strings.Builder
+	out.Grow(len(text))
+
+	i := 0
+	for i < len(text) {
+		start := i
+		for i < len(text) {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if unicode.IsSpace(r) {
+				break
+			}
+			i += size
+		}
+		if start == i {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			out.WriteRune(r)
+			i += size
+			continue
+		}
+
+		word := text[start:i]
+		out.WriteString(f.filterWord(word, &result))
+	}
+
+	result.Text = out.String()
+	return result
+}
+
+func (f *Filter) filterWord(word string, result *Result) string {
+	if action, ok := f.terms[Normalize(word)]; ok {
+		result.record(Normalize(word), action)
+		if action != ActionMask {
+			return word
+		}
+		return mask
+	}
+
+	// Leet substitutions can look like punctuation, so "$harbert!" should
+	// keep its "$" as an s but lose the "!". Each way of trimming the ends
+	// is tried, longest core first.
+	for _, coreStart := range coreBounds(word, strings.IndexFunc) {
+		for _, coreEnd := range coreBounds(word, lastRuneEnd) {
+			if coreStart >= coreEnd {
+				continue
+			}
+			core := word[coreStart:coreEnd]
+			if action, ok := f.terms[Normalize(core)]; ok {
+				result.record(Normalize(core), action)
+				if action != ActionMask {
+					return word
+				}
+				return word[:coreStart] + mask + word[coreEnd:]
+			}
+		}
+	}
+	return word
+}
+
+// coreBounds finds where a word's core could start or end, using find to
+// look for the first or last rune of it. Counting leet characters as part
+// of the word gives the widest core, counting only letters and digits
+// the narrowest. Bounds that can't be found are left out.
+func coreBounds(word string, find func(string, func(rune) bool) int) []int {
+	var bounds []int
+	for _, in := range []func(rune) bool{isLeetOrWordRune, isWordRune} {
+		if i := find(word, in); i >= 0 && !slices.Contains(bounds, i) {
+			bounds = append(bounds, i)
+		}
+	}
+	return bounds
+}
+
+// lastRuneEnd returns the offset just past the last rune in s satisfying
+// f, or -1 if there is none.
+func lastRuneEnd(s string, f func(rune) bool) int {
+	i := strings.LastIndexFunc(s, f)
+	if i < 0 {
+		return -1
+	}
+	_, size := utf8.DecodeRuneInString(s[i:])
+	return i + size
+}
+
+func (r *Result) record(term string, action Action) {
+	r.Matches = append(r.Matches, Match{Term: term, Action: action})
+	if action.severity() > r.Action.severity() {
+		r.Action = action
+	}
+}
+
+// leet maps common character substitutions back to the letter they stand
+// in for.
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'l',
+	'+': 't',
+}
+
+// Normalize reduces a word to the form terms are compared in: compatibility
+// characters (such as fullwidth letters) are folded, accents are removed,
+// case is folded and leetspeak substitutions are undone.
+func Normalize(word string) string {
+	decomposed := norm.NFKD.String(word)
+
+	var b strings.Builder
+	b.Grow(len(decomposed))
+	for _, r := range decomposed {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if l, ok := leet[r]; ok {
+			r = l
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return norm.NFC.String(b.String())
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isLeetOrWordRune(r rune) bool {
+	_, ok := leet[r]
+	return ok || isWordRune(r)
+}
@@ -0,0 +1,106 @@
+package profanity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	filter := New([]Term{
+		{Term: "kerfuffle", Action: ActionMask},
+		{Term: "sharbert", Action: ActionMask},
+		{Term: "fornax", Action: ActionFlag},
+		{Term: "gronk", Action: ActionReject},
+	})
+
+	tests := []struct {
+		name       string
+		text       string
+		wantText   string
+		wantAction Action
+	}{
+		{
+			name:       "Clean text",
+			text:       "I had something interesting for breakfast",
+			wantText:   "I had something interesting for breakfast",
+			wantAction: ActionNone,
+		},
+		{
+			name:       "Case and trailing punctuation",
+			text:       "What a Kerfuffle! Really.",
+			wantText:   "What a ****! Really.",
+			wantAction: ActionMask,
+		},
+		{
+			name:       "Newlines and tabs are preserved",
+			text:       "line one\nkerfuffle\tsharbert",
+			wantText:   "line one\n****\t****",
+			wantAction: ActionMask,
+		},
+		{
+			name:       "Leetspeak",
+			text:       "k3rfuffl3 and $harb3rt",
+			wantText:   "**** and ****",
+			wantAction: ActionMask,
+		},
+		{
+			name:       "Leetspeak next to punctuation",
+			text:       "Hey $harbert! (5harb3rt)",
+			wantText:   "Hey ****! (****)",
+			wantAction: ActionMask,
+		},
+		{
+			name:       "Accents and fullwidth letters",
+			text:       "kérfüffle ｆｏｒｎａｘ",
+			wantText:   "**** ｆｏｒｎａｘ",
+			wantAction: ActionFlag,
+		},
+		{
+			name:       "Strictest action wins",
+			text:       "fornax, gronk, kerfuffle",
+			wantText:   "fornax, gronk, ****",
+			wantAction: ActionReject,
+		},
+		{
+			name:       "Flagged words are kept for review",
+			text:       "Fornax! again",
+			wantText:   "Fornax! again",
+			wantAction: ActionFlag,
+		},
+		{
+			name:       "Words containing a term are left alone",
+			text:       "kerfuffles",
+			wantText:   "kerfuffles",
+			wantAction: ActionNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filter.Apply(tt.text)
+			if got.Text != tt.wantText {
+				t.Errorf("Apply() text = %q, want %q", got.Text, tt.wantText)
+			}
+			if got.Action != tt.wantAction {
+				t.Errorf("Apply() action = %q, want %q", got.Action, tt.wantAction)
+			}
+		})
+	}
+}
+
+func TestApplyRecordsMatches(t *testing.T) {
+	filter := New([]Term{{Term: "Fornax", Action: ActionFlag}})
+
+	got := filter.Apply("f0rnax")
+	want := []Match{{Term: "fornax", Action: ActionFlag}}
+	if !reflect.DeepEqual(got.Matches, want) {
+		t.Errorf("Apply() matches = %+v, want %+v", got.Matches, want)
+	}
+}
+
+func TestNilFilter(t *testing.T) {
+	var filter *Filter
+	if got := filter.Apply("kerfuffle"); got.Text != "kerfuffle" || got.Action != ActionNone {
+		t.Errorf("Apply() on nil filter = %+v", got)
+	}
+}
@@ -0,0 +1,143 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types.
+const (
+	ChirpCreated = "chirp.created"
+	// ChirpUpdated is sent when a chirp changes or is restored by a
+	// moderator.
+	ChirpUpdated = "chirp.updated"
+	// ChirpDeleted is sent when a chirp is deleted or hidden by moderation.
+	ChirpDeleted = "chirp.deleted"
+
+	// Private events, sent only to Event.Recipients.
+	MessageCreated = "message.created"
+	MessageRead    = "message.read"
+	Typing         = "typing"
+	// NotificationCreated is sent when a user gets a notification or one
+	// they haven't read gains another actor.
+	NotificationCreated = "notification.created"
+)
+
+// Event is something that happened, usually to a chirp. Chirp event IDs
+// increase over time, so a client can resume a stream from the last ID it
+// saw. Private events aren't stored and have no ID.
+type Event struct {
+	ID        int64
+	Type      string
+	CreatedAt time.Time
+	ChirpID   uuid.UUID
+	AuthorID  uuid.UUID
+	// RelatedAuthorID is the author of the chirp this one rechirps or
+	// quotes, if any, so viewers who block them can be skipped.
+	RelatedAuthorID uuid.UUID
+	Tags            []string
+	// Visibility is the chirp's visibility level, and MentionedIDs the
+	// users it mentions, so streams can tell who may see it.
+	Visibility   string
+	MentionedIDs []uuid.UUID
+	// Recipients are the only users a private event is for.
+	Recipients []uuid.UUID
+	// Data is the rendered payload sent to clients.
+	Data json.RawMessage
+}
+
+// Filter reports whether a subscriber wants an event.
+type Filter func(Event) bool
+
+// Hub fans events out to subscribers within one process. It never blocks
+// a publisher on a slow subscriber: a subscriber whose buffer is full is
+// dropped and its channel closed, and it's expected to reconnect and
+// replay what it missed.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*Subscription]struct{})}
+}
+
+type Subscription struct {
+	hub        *Hub
+	filter     Filter
+	events     chan Event
+	overflowed bool
+	closed     bool
+}
+
+// Subscribe registers a subscriber that receives events matching filter,
+// buffering up to buffer of them. A nil filter matches everything.
+func (h *Hub) Subscribe(buffer int, filter Filter) *Subscription {
+	sub := &Subscription{
+		hub:    h,
+		filter: filter,
+		events: make(chan Event, buffer),
+	}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// Publish delivers e to every matching subscriber.
+func (h *Hub) Publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		if sub.filter != nil && !sub.filter(e) {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			sub.overflowed = true
+			h.removeLocked(sub)
+		}
+	}
+}
+
+// Subscribers returns how many subscriptions are open.
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+func (h *Hub) removeLocked(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(h.subs, sub)
+	close(sub.events)
+}
+
+// Events returns the channel events arrive on. It's closed when the
+// subscription ends.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Overflowed reports whether the subscription was dropped for falling
+// behind.
+func (s *Subscription) Overflowed() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.overflowed
+}
+
+// Close ends the subscription. It's safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.removeLocked(s)
+}
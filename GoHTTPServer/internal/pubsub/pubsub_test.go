@@ -0,0 +1,89 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPublish(t *testing.T) {
+	alice := uuid.New()
+	bob := uuid.New()
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		events  []Event
+		wantIDs []int64
+	}{
+		{
+			name:    "No filter",
+			events:  []Event{{ID: 1, AuthorID: alice}, {ID: 2, AuthorID: bob}},
+			wantIDs: []int64{1, 2},
+		},
+		{
+			name:    "Author filter",
+			filter:  func(e Event) bool { return e.AuthorID == bob },
+			events:  []Event{{ID: 1, AuthorID: alice}, {ID: 2, AuthorID: bob}, {ID: 3, AuthorID: alice}},
+			wantIDs: []int64{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub()
+			sub := hub.Subscribe(10, tt.filter)
+			defer sub.Close()
+
+			for _, e := range tt.events {
+				hub.Publish(e)
+			}
+
+			for _, want := range tt.wantIDs {
+				got := <-sub.Events()
+				if got.ID != want {
+					t.Errorf("got event %d, want %d", got.ID, want)
+				}
+			}
+			select {
+			case e := <-sub.Events():
+				t.Errorf("unexpected event %d", e.ID)
+			default:
+			}
+		})
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	hub := NewHub()
+	slow := hub.Subscribe(2, nil)
+	fast := hub.Subscribe(10, nil)
+	defer fast.Close()
+
+	for i := int64(1); i <= 3; i++ {
+		hub.Publish(Event{ID: i})
+	}
+
+	if !slow.Overflowed() {
+		t.Fatal("slow subscriber should have overflowed")
+	}
+	if fast.Overflowed() {
+		t.Fatal("fast subscriber shouldn't have overflowed")
+	}
+	if got := hub.Subscribers(); got != 1 {
+		t.Errorf("Subscribers() = %d, want 1", got)
+	}
+
+	var received int
+	for range slow.Events() {
+		received++
+	}
+	if received != 2 {
+		t.Errorf("slow subscriber received %d buffered events, want 2", received)
+	}
+	if got := len(fast.Events()); got != 3 {
+		t.Errorf("fast subscriber has %d events, want 3", got)
+	}
+
+	slow.Close()
+}
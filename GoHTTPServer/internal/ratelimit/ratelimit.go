@@ -0,0 +1,67 @@
+// Package ratelimit counts events per key in fixed time windows.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepSize is how many keys a limiter holds before it drops the ones
+// whose window has passed.
+const sweepSize = 10_000
+
+// Limiter allows up to a fixed number of events per key in each window.
+// It's safe for concurrent use.
+type Limiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+// New returns a limiter allowing limit events per key every window.
+func New(limit int, per time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  per,
+		now:     time.Now,
+		windows: make(map[string]*window),
+	}
+}
+
+// Allow records an event for key and reports whether it's within the
+// limit. Events over the limit aren't counted.
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		if len(l.windows) >= sweepSize {
+			l.sweep(now)
+		}
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+	if w.count >= l.limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+}
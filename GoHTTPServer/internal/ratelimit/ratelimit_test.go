@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		key     string
+		want    bool
+	}{
+		{name: "First", key: "a.example", want: true},
+		{name: "Second", key: "a.example", want: true},
+		{name: "Over the limit", key: "a.example", want: false},
+		{name: "Other key", key: "b.example", want: true},
+		{name: "Still limited", advance: 59 * time.Second, key: "a.example", want: false},
+		{name: "Next window", advance: time.Second, key: "a.example", want: true},
+	}
+	for _, s := range steps {
+		now = now.Add(s.advance)
+		if got := l.Allow(s.key); got != s.want {
+			t.Errorf("%s: Allow(%q) = %v, want %v", s.name, s.key, got, s.want)
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	l := New(1, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < sweepSize; i++ {
+		l.Allow(strconv.Itoa(i))
+	}
+	now = now.Add(time.Minute)
+	l.Allow("new")
+	if len(l.windows) != 1 {
+		t.Errorf("limiter holds %d keys after a sweep, want 1", len(l.windows))
+	}
+}
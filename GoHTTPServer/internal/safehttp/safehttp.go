@@ -0,0 +1,86 @@
+// Package safehttp builds HTTP clients for fetching URLs that come from
+// users or other servers. They only dial public addresses, check every
+// redirect, and don't use a proxy.
+package safehttp
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var ErrPrivateAddress = errors.New("refusing to connect to a private address")
+
+// Options configures a client. CheckURL, if set, is called on every
+// redirect target; the request URL itself is the caller's to check.
+type Options struct {
+	Timeout     time.Duration
+	DialTimeout time.Duration
+	// MaxRedirects caps how many requests one fetch makes, counting the
+	// first.
+	MaxRedirects int
+	CheckURL     func(*url.URL) error
+	// AllowPrivate skips the address check, for development and tests
+	// against local servers.
+	AllowPrivate bool
+}
+
+// NewClient returns a client configured by o.
+func NewClient(o Options) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: o.DialTimeout,
+		// Control runs on the address actually being dialed, after DNS
+		// resolution, so a name can't resolve to a private address between
+		// a check and the connection.
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if o.AllowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublic(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: o.Timeout,
+		Transport: &http.Transport{
+			// No proxy, so the address check sees the real destination.
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   o.DialTimeout,
+			ResponseHeaderTimeout: o.Timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= o.MaxRedirects {
+				return errors.New("too many redirects")
+			}
+			if o.CheckURL != nil {
+				return o.CheckURL(req.URL)
+			}
+			return nil
+		},
+	}
+}
+
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPublic reports whether ip is a globally routable unicast address.
+func IsPublic(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		if ip4[0] == 0 || carrierGradeNAT.Contains(ip4) {
+			return false
+		}
+	}
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast()
+}
@@ -0,0 +1,91 @@
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "93.184.216.34", want: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{ip: "127.0.0.1", want: false},
+		{ip: "10.1.2.3", want: false},
+		{ip: "172.16.0.1", want: false},
+		{ip: "192.168.1.1", want: false},
+		{ip: "169.254.169.254", want: false},
+		{ip: "100.64.0.1", want: false},
+		{ip: "0.0.0.0", want: false},
+		{ip: "::1", want: false},
+		{ip: "fd00::1", want: false},
+		{ip: "fe80::1", want: false},
+		{ip: "::ffff:127.0.0.1", want: false},
+		{ip: "224.0.0.1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := IsPublic(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("IsPublic(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	if _, err := NewClient(Options{}).Do(req); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("Do() error = %v, want %v", err, ErrPrivateAddress)
+	}
+
+	resp, err := NewClient(Options{AllowPrivate: true}).Do(req)
+	if err != nil {
+		t.Fatalf("Do() with AllowPrivate error = %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestClientChecksRedirects(t *testing.T) {
+	errRefused := errors.New("refused")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/next", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr error
+	}{
+		{name: "Allowed", opts: Options{AllowPrivate: true, MaxRedirects: 2}},
+		{name: "Refused by CheckURL", opts: Options{AllowPrivate: true, MaxRedirects: 2, CheckURL: func(*url.URL) error { return errRefused }}, wantErr: errRefused},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := NewClient(tt.opts).Get(server.URL)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				resp.Body.Close()
+			}
+		})
+	}
+
+	if _, err := NewClient(Options{AllowPrivate: true}).Get(server.URL); err == nil {
+		t.Error("Get() followed a redirect past MaxRedirects")
+	}
+}
@@ -0,0 +1,76 @@
+// Package sensitive covers chirps that carry a content warning or
+// sensitive media: cleaning up the warnings authors write, how viewers
+// want such chirps shown, and filtering listings by them.
+package sensitive
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/chirptext"
+)
+
+// MaxWarningLength is measured the way chirps are, in graphemes.
+const MaxWarningLength = 100
+
+var ErrWarningLength = errors.New("content warnings can be at most 100 characters")
+
+// Warning normalizes a content warning to a single line. An empty result
+// means the chirp has no warning.
+func Warning(raw string) (string, error) {
+	warning := strings.Join(strings.Fields(chirptext.Normalize(raw)), " ")
+	if chirptext.Length(warning) > MaxWarningLength {
+		return "", ErrWarningLength
+	}
+	return warning, nil
+}
+
+// Preference is how a viewer wants sensitive chirps shown.
+type Preference string
+
+const (
+	// Hide collapses sensitive chirps behind their warning. It's the
+	// default.
+	Hide Preference = "hide"
+	// Expand shows them straight away.
+	Expand Preference = "expand"
+)
+
+// ParsePreference checks a preference sent by a client.
+func ParsePreference(s string) (Preference, error) {
+	switch p := Preference(s); p {
+	case Hide, Expand:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown sensitive content preference %q", s)
+}
+
+// Collapsed reports whether a chirp should start out collapsed for a
+// viewer with preference p.
+func Collapsed(p Preference, warning string, sensitiveMedia bool) bool {
+	return (warning != "" || sensitiveMedia) && p != Expand
+}
+
+// Filter selects chirps in a listing by whether they're sensitive. The
+// chirp queries apply it, so its values are part of their SQL.
+type Filter string
+
+const (
+	Include Filter = "include"
+	Exclude Filter = "exclude"
+	Only    Filter = "only"
+)
+
+// ParseFilter checks a filter sent by a client. An empty string means
+// Include.
+func ParseFilter(s string) (Filter, error) {
+	if s == "" {
+		return Include, nil
+	}
+	switch f := Filter(s); f {
+	case Include, Exclude, Only:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown sensitive filter %q", s)
+}
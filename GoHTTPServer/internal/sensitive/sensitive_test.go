@@ -0,0 +1,98 @@
+package sensitive
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWarning(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr error
+	}{
+		{name: "Empty", raw: "", want: ""},
+		{name: "Plain", raw: "Spoilers", want: "Spoilers"},
+		{name: "Single line", raw: "  season 2\n spoilers\t", want: "season 2 spoilers"},
+		{name: "Invisible characters only", raw: "\u200b", want: ""},
+		{name: "Longest", raw: strings.Repeat("x", MaxWarningLength), want: strings.Repeat("x", MaxWarningLength)},
+		{name: "Too long", raw: strings.Repeat("x", MaxWarningLength+1), wantErr: ErrWarningLength},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Warning(tt.raw)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Warning() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Warning() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePreference(t *testing.T) {
+	for _, s := range []string{"hide", "expand"} {
+		if _, err := ParsePreference(s); err != nil {
+			t.Errorf("ParsePreference(%q) error = %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "show", "HIDE"} {
+		if _, err := ParsePreference(s); err == nil {
+			t.Errorf("ParsePreference(%q) succeeded", s)
+		}
+	}
+}
+
+func TestCollapsed(t *testing.T) {
+	tests := []struct {
+		name           string
+		preference     Preference
+		warning        string
+		sensitiveMedia bool
+		want           bool
+	}{
+		{name: "Nothing sensitive", preference: Hide, want: false},
+		{name: "Warning hidden", preference: Hide, warning: "Spoilers", want: true},
+		{name: "Media hidden", preference: Hide, sensitiveMedia: true, want: true},
+		{name: "Warning expanded", preference: Expand, warning: "Spoilers", want: false},
+		{name: "Media expanded", preference: Expand, sensitiveMedia: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Collapsed(tt.preference, tt.warning, tt.sensitiveMedia); got != tt.want {
+				t.Errorf("Collapsed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter  string
+		want    Filter
+		wantErr bool
+	}{
+		{filter: "", want: Include},
+		{filter: "include", want: Include},
+		{filter: "exclude", want: Exclude},
+		{filter: "only", want: Only},
+		{filter: "maybe", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			got, err := ParseFilter(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseFilter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Package shortlink makes the codes for Chirpy's short links and cleans up
+// the values recorded when they're followed.
+package shortlink
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"net/url"
+	"strings"
+)
+
+// CodeLength is long enough that guessing another chirp's links is
+// impractical and collisions essentially never happen.
+const CodeLength = 8
+
+const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+var ErrInvalidDomain = errors.New("invalid domain")
+
+// NewCode returns a random code made of letters and digits.
+func NewCode() (string, error) {
+	b := make([]byte, CodeLength)
+	n := big.NewInt(int64(len(alphabet)))
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphabet[idx.Int64()]
+	}
+	return string(b), nil
+}
+
+// ValidCode reports whether code could have come from NewCode, so lookups
+// of obvious junk can skip the database.
+func ValidCode(code string) bool {
+	if len(code) != CodeLength {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if !strings.ContainsRune(alphabet, rune(code[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReferrerHost reduces a Referer header to the referring site's host, so
+// stats show where clicks came from without recording the page. Direct
+// visits and unparseable values are empty.
+func ReferrerHost(referer string) string {
+	u, err := url.Parse(referer)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// Host returns the lowercase host of a link's destination.
+func Host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+// NormalizeDomain cleans up a domain an admin wants to block. Blocking a
+// domain also blocks its subdomains.
+func NormalizeDomain(domain string) (string, error) {
+	domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if domain == "" || len(domain) > 253 || !strings.Contains(domain, ".") {
+		return "", ErrInvalidDomain
+	}
+	for _, r := range domain {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.' || r == '_') {
+			return "", ErrInvalidDomain
+		}
+	}
+	return domain, nil
+}
@@ -0,0 +1,98 @@
+package shortlink
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewCode(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		code, err := NewCode()
+		if err != nil {
+			t.Fatalf("NewCode() error = %v", err)
+		}
+		if !ValidCode(code) {
+			t.Fatalf("NewCode() = %q, which isn't valid", code)
+		}
+		if seen[code] {
+			t.Fatalf("NewCode() repeated %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestValidCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{code: "aZ09bY18", want: true},
+		{code: "short", want: false},
+		{code: "toolong12", want: false},
+		{code: "abc-1234", want: false},
+		{code: "abcdéfg", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := ValidCode(tt.code); got != tt.want {
+				t.Errorf("ValidCode(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferrerHost(t *testing.T) {
+	tests := []struct {
+		referer string
+		want    string
+	}{
+		{referer: "", want: ""},
+		{referer: "https://www.News.example/story?id=1", want: "news.example"},
+		{referer: "http://blog.example:8080/post", want: "blog.example"},
+		{referer: "android-app://com.example", want: ""},
+		{referer: "%zz", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.referer, func(t *testing.T) {
+			if got := ReferrerHost(tt.referer); got != tt.want {
+				t.Errorf("ReferrerHost(%q) = %q, want %q", tt.referer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHost(t *testing.T) {
+	if got := Host("https://Evil.Example./path"); got != "evil.example" {
+		t.Errorf("Host() = %q, want %q", got, "evil.example")
+	}
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		domain  string
+		want    string
+		wantErr error
+	}{
+		{domain: " Evil.Example. ", want: "evil.example"},
+		{domain: "sub.evil.example", want: "sub.evil.example"},
+		{domain: "", wantErr: ErrInvalidDomain},
+		{domain: "example", wantErr: ErrInvalidDomain},
+		{domain: "https://evil.example/", wantErr: ErrInvalidDomain},
+		{domain: "evil .example", wantErr: ErrInvalidDomain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			got, err := NormalizeDomain(tt.domain)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NormalizeDomain(%q) error = %v, want %v", tt.domain, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeDomain(%q) = %q, want %q", tt.domain, got, tt.want)
+			}
+		})
+	}
+}
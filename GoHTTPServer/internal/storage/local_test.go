@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStore(t.TempDir(), "/media/")
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+
+	if err := store.Put(ctx, "abc123.png", strings.NewReader("image data")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	exists, err := store.Exists(ctx, "abc123.png")
+	if err != nil || !exists {
+		t.Errorf("Exists() = %v, %v, want true, nil", exists, err)
+	}
+
+	rc, err := store.Open(ctx, "abc123.png")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "image data" {
+		t.Errorf("Open() read %q, want %q", data, "image data")
+	}
+
+	if got := store.URL("abc123.png"); got != "/media/abc123.png" {
+		t.Errorf("URL() = %q, want %q", got, "/media/abc123.png")
+	}
+
+	if err := store.Delete(ctx, "abc123.png"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Open(ctx, "abc123.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open() after Delete() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalStoreRejectsBadKeys(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStore(t.TempDir(), "/media")
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+
+	for _, key := range []string{"", "../escape", "nested/key", ".hidden"} {
+		if err := store.Put(ctx, key, strings.NewReader("x")); err == nil {
+			t.Errorf("Put(%q) succeeded, want error", key)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package trends
+
+import (
+	"sort"
+	"time"
+)
+
+// Window is a period over which trending tags are computed.
+type Window struct {
+	Name   string
+	Length time.Duration
+}
+
+var DefaultWindows = []Window{
+	{Name: "1h", Length: time.Hour},
+	{Name: "24h", Length: 24 * time.Hour},
+}
+
+const (
+	// BaselinePeriod is how far back usage is counted to learn what is
+	// normal for a tag.
+	BaselinePeriod = 7 * 24 * time.Hour
+
+	// minRecentCount keeps a tag used once or twice from topping the list.
+	minRecentCount = 3
+
+	// smoothing is added to the expected usage so tags with no history
+	// don't get an unbounded score.
+	smoothing = 1.0
+)
+
+// TagActivity is the raw usage of one tag, as aggregated from the database.
+// RecentWeight is the decay-weighted use count inside the window and
+// BaselineCount is the plain count from the rest of the baseline period.
+type TagActivity struct {
+	Tag           string
+	RecentCount   int64
+	RecentWeight  float64
+	BaselineCount int64
+}
+
+type Trend struct {
+	Tag         string
+	Score       float64
+	RecentCount int64
+}
+
+// HalfLife is the age at which a use counts half as much toward a window's
+// score as one made just now.
+func (w Window) HalfLife() time.Duration {
+	return w.Length / 2
+}
+
+// Score compares a tag's recent, decay-weighted activity with what its
+// baseline predicts for a window of the same length. A score above 1 means
+// the tag is busier than usual.
+func Score(a TagActivity, w Window) float64 {
+	baselineLength := BaselinePeriod - w.Length
+	expected := 0.0
+	if baselineLength > 0 {
+		expected = float64(a.BaselineCount) * float64(w.Length) / float64(baselineLength)
+	}
+	return a.RecentWeight / (expected + smoothing)
+}
+
+// Rank scores every tag and returns the top limit that are trending, best
+// first.
+func Rank(activity []TagActivity, w Window, limit int) []Trend {
+	trends := make([]Trend, 0, len(activity))
+	for _, a := range activity {
+		if a.RecentCount < minRecentCount {
+			continue
+		}
+		score := Score(a, w)
+		if score <= 1 {
+			continue
+		}
+		trends = append(trends, Trend{Tag: a.Tag, Score: score, RecentCount: a.RecentCount})
+	}
+
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Score != trends[j].Score {
+			return trends[i].Score > trends[j].Score
+		}
+		if trends[i].RecentCount != trends[j].RecentCount {
+			return trends[i].RecentCount > trends[j].RecentCount
+		}
+		return trends[i].Tag < trends[j].Tag
+	})
+
+	if len(trends) > limit {
+		trends = trends[:limit]
+	}
+	return trends
+}
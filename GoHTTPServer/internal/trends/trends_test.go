@@ -0,0 +1,63 @@
+package trends
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScore(t *testing.T) {
+	window := Window{Name: "1h", Length: time.Hour}
+
+	tests := []struct {
+		name     string
+		activity TagActivity
+		want     float64
+	}{
+		{
+			name:     "No history",
+			activity: TagActivity{Tag: "new", RecentCount: 4, RecentWeight: 4},
+			want:     4,
+		},
+		{
+			name: "Steady tag scores about its own rate",
+			// 167 hours of baseline at 2 uses an hour predicts 2 uses.
+			activity: TagActivity{Tag: "steady", RecentCount: 2, RecentWeight: 2, BaselineCount: 334},
+			want:     2.0 / 3.0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Score(tt.activity, window)
+			if diff := got - tt.want; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("Score() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	window := Window{Name: "1h", Length: time.Hour}
+	activity := []TagActivity{
+		{Tag: "steady", RecentCount: 10, RecentWeight: 10, BaselineCount: 1670},
+		{Tag: "spike", RecentCount: 20, RecentWeight: 18, BaselineCount: 167},
+		{Tag: "rare", RecentCount: 1, RecentWeight: 1},
+		{Tag: "fresh", RecentCount: 5, RecentWeight: 5},
+	}
+
+	got := Rank(activity, window, 10)
+
+	want := []string{"spike", "fresh"}
+	if len(got) != len(want) {
+		t.Fatalf("Rank() returned %d trends, want %d: %+v", len(got), len(want), got)
+	}
+	for i, tag := range want {
+		if got[i].Tag != tag {
+			t.Errorf("Rank()[%d] = %q, want %q", i, got[i].Tag, tag)
+		}
+	}
+
+	if limited := Rank(activity, window, 1); len(limited) != 1 {
+		t.Errorf("Rank() with limit 1 returned %d trends", len(limited))
+	}
+}
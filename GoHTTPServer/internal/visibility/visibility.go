@@ -0,0 +1,61 @@
+// Package visibility decides who can read a chirp. Queries apply the same
+// rules through the chirp_visible_to SQL function; this package is for the
+// places that filter in Go, such as live streams.
+package visibility
+
+import "fmt"
+
+// Level is who a chirp is shown to. Its author can always see it.
+type Level string
+
+const (
+	// Public chirps are shown to everyone, signed in or not.
+	Public Level = "public"
+	// Followers chirps are shown to the author's followers and to the
+	// users the chirp mentions.
+	Followers Level = "followers"
+	// Mentioned chirps are shown only to the users they mention.
+	Mentioned Level = "mentioned"
+	// Private chirps are shown only to their author.
+	Private Level = "private"
+)
+
+// Levels lists every level, from widest to narrowest.
+var Levels = []Level{Public, Followers, Mentioned, Private}
+
+// Parse checks a level sent by a client. An empty string means Public.
+func Parse(s string) (Level, error) {
+	if s == "" {
+		return Public, nil
+	}
+	switch l := Level(s); l {
+	case Public, Followers, Mentioned, Private:
+		return l, nil
+	}
+	return "", fmt.Errorf("unknown visibility %q", s)
+}
+
+// Viewer is how a reader relates to a chirp's author and body.
+type Viewer struct {
+	IsAuthor bool
+	// Follows is whether the viewer follows the author.
+	Follows bool
+	// Mentioned is whether the chirp mentions the viewer.
+	Mentioned bool
+}
+
+// CanView reports whether a chirp at level l is shown to v.
+func (l Level) CanView(v Viewer) bool {
+	if v.IsAuthor {
+		return true
+	}
+	switch l {
+	case Public:
+		return true
+	case Followers:
+		return v.Follows || v.Mentioned
+	case Mentioned:
+		return v.Mentioned
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package visibility
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Level
+		wantErr bool
+	}{
+		{name: "Empty defaults to public", in: "", want: Public},
+		{name: "Followers", in: "followers", want: Followers},
+		{name: "Private", in: "private", want: Private},
+		{name: "Unknown", in: "friends", wantErr: true},
+		{name: "Case matters", in: "Public", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanView(t *testing.T) {
+	tests := []struct {
+		name   string
+		level  Level
+		viewer Viewer
+		want   bool
+	}{
+		{name: "Public to anyone", level: Public, want: true},
+		{name: "Followers to a follower", level: Followers, viewer: Viewer{Follows: true}, want: true},
+		{name: "Followers to a mentioned user", level: Followers, viewer: Viewer{Mentioned: true}, want: true},
+		{name: "Followers to a stranger", level: Followers, want: false},
+		{name: "Mentioned to a mentioned user", level: Mentioned, viewer: Viewer{Mentioned: true}, want: true},
+		{name: "Mentioned to a follower", level: Mentioned, viewer: Viewer{Follows: true}, want: false},
+		{name: "Private to a mentioned follower", level: Private, viewer: Viewer{Follows: true, Mentioned: true}, want: false},
+		{name: "Private to the author", level: Private, viewer: Viewer{IsAuthor: true}, want: true},
+		{name: "Unknown level", level: Level("friends"), viewer: Viewer{Follows: true, Mentioned: true}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.level.CanView(tt.viewer); got != tt.want {
+				t.Errorf("CanView() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Package wsproto defines the JSON messages exchanged over the WebSocket
+// API. Every message, in both directions, is an envelope carrying the
+// protocol version, a type, an optional client-chosen ID that's echoed
+// back in the reply, and type-specific data.
+package wsproto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Version is the protocol version this server speaks. Messages with any
+// other version are rejected.
+const Version = 1
+
+// Subprotocol is the WebSocket subprotocol name for Version. Clients may
+// offer it during the handshake; it isn't required.
+const Subprotocol = "chirpy.v1"
+
+// Messages sent by the client.
+const (
+	TypeSubscribe   = "subscribe"
+	TypeUnsubscribe = "unsubscribe"
+	TypePing        = "ping"
+	TypeTyping      = "typing"
+	TypeRead        = "read"
+)
+
+// Messages sent by the server.
+const (
+	TypeWelcome = "welcome"
+	TypeAck     = "ack"
+	TypeError   = "error"
+	TypePong    = "pong"
+	TypeEvent   = "event"
+	// TypeOverflow means a subscription fell too far behind and was
+	// dropped. The client should subscribe again with the last event ID it
+	// saw.
+	TypeOverflow = "overflow"
+	// TypeGoodbye is sent before the server closes the connection, for
+	// example when it's shutting down.
+	TypeGoodbye = "goodbye"
+)
+
+// Channels a client can subscribe to.
+const (
+	// ChannelChirps streams chirp events, optionally filtered, and can be
+	// resumed from an event ID.
+	ChannelChirps = "chirps"
+	// ChannelMessages streams the caller's direct messages, typing
+	// indicators and read receipts. It takes no filters and can't be
+	// resumed; clients reload conversations over REST after reconnecting.
+	ChannelMessages = "messages"
+	// ChannelNotifications streams the caller's new notifications. Like
+	// messages it takes no filters and can't be resumed.
+	ChannelNotifications = "notifications"
+)
+
+// Error codes.
+const (
+	CodeBadMessage         = "bad_message"
+	CodeUnsupportedVersion = "unsupported_version"
+	CodeUnknownType        = "unknown_type"
+	CodeInvalid            = "invalid"
+	CodeNotFound           = "not_found"
+	CodeLimitExceeded      = "limit_exceeded"
+	CodeInternal           = "internal"
+)
+
+// Message is the envelope every message is wrapped in.
+type Message struct {
+	V    int             `json:"v"`
+	Type string          `json:"type"`
+	ID   string          `json:"id,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Error is a protocol error, sent to the client in an error message.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Errorf returns an Error with a formatted message.
+func Errorf(code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// SubscribeData asks for events on a channel. Subscription is a name the
+// client picks, unique on its connection, that's attached to every event
+// delivered for it and used to unsubscribe.
+type SubscribeData struct {
+	Subscription string    `json:"subscription"`
+	Channel      string    `json:"channel"`
+	Author       uuid.UUID `json:"author"`
+	Tag          string    `json:"tag"`
+	Timeline     bool      `json:"timeline"`
+	// LastEventID resumes the subscription after an event the client has
+	// already seen. Events from shortly before it may be sent again, and
+	// should be skipped by ID.
+	LastEventID int64 `json:"last_event_id"`
+}
+
+type UnsubscribeData struct {
+	Subscription string `json:"subscription"`
+}
+
+type TypingData struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+}
+
+type ReadData struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	MessageID      uuid.UUID `json:"message_id"`
+}
+
+type WelcomeData struct {
+	Version int       `json:"version"`
+	UserID  uuid.UUID `json:"user_id"`
+	// PingInterval is how often, in seconds, the server pings the client.
+	PingInterval int `json:"ping_interval"`
+}
+
+type EventData struct {
+	Subscription string `json:"subscription"`
+	// EventID is 0 for control events such as "reset", which don't move
+	// the client's resume position.
+	EventID int64           `json:"event_id,omitempty"`
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type OverflowData struct {
+	Subscription string `json:"subscription"`
+}
+
+type GoodbyeData struct {
+	Reason string `json:"reason"`
+}
+
+// Decode parses a message from the client and checks its envelope.
+func Decode(raw []byte) (Message, *Error) {
+	var msg Message
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return Message{}, Errorf(CodeBadMessage, "message isn't valid JSON")
+	}
+	if msg.V != Version {
+		return msg, Errorf(CodeUnsupportedVersion, "protocol version %d isn't supported, use %d", msg.V, Version)
+	}
+	if msg.Type == "" {
+		return msg, Errorf(CodeBadMessage, "message has no type")
+	}
+	return msg, nil
+}
+
+// Bind decodes the message's data into v, rejecting unknown fields so
+// typos in a client don't go unnoticed.
+func (m Message) Bind(v any) *Error {
+	if len(m.Data) == 0 {
+		return Errorf(CodeInvalid, "%s message needs data", m.Type)
+	}
+	dec := json.NewDecoder(bytes.NewReader(m.Data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return Errorf(CodeInvalid, "invalid %s data: %v", m.Type, err)
+	}
+	return nil
+}
+
+// Encode builds a server message. A nil data is left out.
+func Encode(msgType, id string, data any) ([]byte, error) {
+	msg := Message{V: Version, Type: msgType, ID: id}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		msg.Data = raw
+	}
+	return json.Marshal(msg)
+}
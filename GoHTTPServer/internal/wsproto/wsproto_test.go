@@ -0,0 +1,102 @@
+package wsproto
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		wantType string
+		wantCode string
+	}{
+		{
+			name:     "Valid",
+			raw:      `{"v":1,"type":"ping","id":"7"}`,
+			wantType: TypePing,
+		},
+		{
+			name:     "Not JSON",
+			raw:      `ping`,
+			wantCode: CodeBadMessage,
+		},
+		{
+			name:     "Missing version",
+			raw:      `{"type":"ping"}`,
+			wantCode: CodeUnsupportedVersion,
+		},
+		{
+			name:     "Future version",
+			raw:      `{"v":2,"type":"ping"}`,
+			wantCode: CodeUnsupportedVersion,
+		},
+		{
+			name:     "Missing type",
+			raw:      `{"v":1}`,
+			wantCode: CodeBadMessage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := Decode([]byte(tt.raw))
+			if tt.wantCode != "" {
+				if err == nil || err.Code != tt.wantCode {
+					t.Fatalf("Decode() error = %v, want code %q", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() unexpected error: %v", err)
+			}
+			if msg.Type != tt.wantType {
+				t.Errorf("Type = %q, want %q", msg.Type, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "Valid",
+			data: `{"subscription":"home","channel":"chirps","timeline":true}`,
+		},
+		{
+			name:    "Unknown field",
+			data:    `{"subscription":"home","chanel":"chirps"}`,
+			wantErr: true,
+		},
+		{
+			name:    "Missing data",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := Message{V: Version, Type: TypeSubscribe, Data: json.RawMessage(tt.data)}
+			var data SubscribeData
+			err := msg.Bind(&data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Bind() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	raw, err := Encode(TypePong, "7", nil)
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	if got, want := string(raw), `{"v":1,"type":"pong","id":"7"}`; got != want {
+		t.Errorf("Encode() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/linkpreview"
+)
+
+const (
+	// maxLinksPerChirp is how many of a chirp's links get previews.
+	maxLinksPerChirp = 4
+	// previewRefreshAge is how old a cached preview can get before a new
+	// chirp linking it queues a fresh fetch.
+	previewRefreshAge   = 7 * 24 * time.Hour
+	previewInterval     = 5 * time.Second
+	maxPreviewAttempts  = 3
+	firstPreviewBackoff = time.Minute
+)
+
+// Statuses a fetched preview ends in. New previews start out "pending".
+const (
+	previewReady   = "ready"
+	previewFailed  = "failed"
+	previewBlocked = "blocked"
+)
+
+// saveChirpLinks records where a chirp's short links lead and queues
+// previews for any destinations that aren't cached yet. Fetching happens
+// in the background so creating a chirp never waits on another site.
+func saveChirpLinks(ctx context.Context, q *database.Queries, chirp database.Chirp, links []shortLink) error {
+	seen := map[string]bool{}
+	for _, l := range links {
+		if len(seen) == maxLinksPerChirp {
+			break
+		}
+		link, err := linkpreview.Normalize(l.URL)
+		if err != nil || seen[link] {
+			continue
+		}
+
+		previewID, err := q.UpsertLinkPreview(ctx, database.UpsertLinkPreviewParams{
+			URL:         link,
+			StaleBefore: time.Now().UTC().Add(-previewRefreshAge),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			// Cached and still fresh, so the upsert left it alone.
+			previewID, err = q.GetLinkPreviewID(ctx, link)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = q.CreateChirpLink(ctx, database.CreateChirpLinkParams{
+			ChirpID:       chirp.ID,
+			Position:      int32(len(seen)),
+			LinkPreviewID: previewID,
+		})
+		if err != nil {
+			return err
+		}
+		seen[link] = true
+	}
+	return nil
+}
+
+// runLinkPreviewFetcher fetches queued link previews until ctx is
+// cancelled.
+func (cfg *apiConfig) runLinkPreviewFetcher(ctx context.Context) {
+	ticker := time.NewTicker(previewInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			fetched, err := cfg.fetchNextLinkPreview(ctx)
+			if err != nil {
+				log.Printf("Couldn't process link preview: %v", err)
+				break
+			}
+			if !fetched {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchNextLinkPreview fetches the next due preview. Network failures are
+// retried with backoff until maxPreviewAttempts; a page that's blocked by
+// policy, private, or not HTML fails straight away. It reports false when
+// nothing was due.
+func (cfg *apiConfig) fetchNextLinkPreview(ctx context.Context) (bool, error) {
+	tx, err := cfg.DBConn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	pending, err := qtx.ClaimPendingLinkPreview(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	preview, fetchErr := cfg.LinkPreviews.Fetch(ctx, pending.URL)
+	params := database.SaveLinkPreviewParams{ID: pending.ID}
+	switch {
+	case fetchErr == nil:
+		params.Status = previewReady
+		params.Title = preview.Title
+		params.Description = preview.Description
+		params.ImageURL = preview.ImageURL
+		params.SiteName = preview.SiteName
+	case errors.Is(fetchErr, linkpreview.ErrBlocked):
+		params.Status = previewBlocked
+		params.LastError = fetchErr.Error()
+	case errors.Is(fetchErr, linkpreview.ErrPrivateAddress),
+		errors.Is(fetchErr, linkpreview.ErrBadURL),
+		errors.Is(fetchErr, linkpreview.ErrNotHTML),
+		pending.Attempts+1 >= maxPreviewAttempts:
+		params.Status = previewFailed
+		params.LastError = fetchErr.Error()
+	default:
+		err = qtx.RetryLinkPreview(ctx, database.RetryLinkPreviewParams{
+			ID:            pending.ID,
+			NextAttemptAt: time.Now().UTC().Add(firstPreviewBackoff << pending.Attempts),
+			LastError:     fetchErr.Error(),
+		})
+		if err != nil {
+			return false, err
+		}
+		return true, tx.Commit()
+	}
+
+	// A page with nothing to show isn't worth a card.
+	if params.Status == previewReady && params.Title == "" && params.Description == "" && params.ImageURL == "" {
+		params.Status = previewFailed
+		params.LastError = "page has no preview metadata"
+	}
+	if err := qtx.SaveLinkPreview(ctx, params); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -1,12 +1,23 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/linkpreview"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/mail"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pubsub"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/ratelimit"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/storage"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -17,6 +28,15 @@ func main() {
 	platform := os.Getenv("PLATFORM")
 	jwtSecret := os.Getenv("JWT_SECRET")
 	polkaKey := os.Getenv("POLKA_KEY")
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "./media"
+	}
+	pipelineSpec := os.Getenv("CHIRP_PIPELINE")
+	publicURL := strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+	if publicURL == "" {
+		publicURL = "http://localhost:8080"
+	}
 	db, err := sql.Open("postgres", dbURL)
 
 	if err != nil {
@@ -26,13 +46,51 @@ func main() {
 	// Initialize SQLC Queries
 	dbQueries := database.New(db)
 
+	mediaStore, err := storage.NewLocalStore(mediaDir, "/media")
+	if err != nil {
+		log.Fatalf("Failed to set up media storage: %v", err)
+	}
+
 	const filepathRoot = "."
-	const port = "8080"
-	apiCfg := apiConfig{
-		DB:        dbQueries,
-		Platform:  platform,
-		JWTSecret: jwtSecret,
-		PolkaKey:  polkaKey,
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	apiCfg := &apiConfig{
+		DB:         dbQueries,
+		DBConn:     db,
+		Platform:   platform,
+		JWTSecret:  jwtSecret,
+		PolkaKey:   polkaKey,
+		PublicURL:  publicURL,
+		Media:      mediaStore,
+		Events:     pubsub.NewHub(),
+		Private:    pubsub.NewHub(),
+		Live:       newLiveClients(),
+		HTTPClient: newFederationClient(platform),
+		LinkPreviews: linkpreview.NewFetcher(linkpreview.Policy{
+			Allow: linkpreview.ParseDomains(os.Getenv("LINK_PREVIEW_ALLOW")),
+			Deny:  linkpreview.ParseDomains(os.Getenv("LINK_PREVIEW_DENY")),
+		}),
+		KeyFetchesPerHost: ratelimit.New(keyFetchesPerHost, keyFetchWindow),
+		KeyFetches:        ratelimit.New(keyFetchesTotal, keyFetchWindow),
+	}
+
+	apiCfg.Mail = mail.LogSender{}
+	if smtpAddr := os.Getenv("SMTP_ADDR"); smtpAddr != "" {
+		apiCfg.Mail, err = mail.NewSMTPSender(smtpAddr, os.Getenv("SMTP_FROM"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+		if err != nil {
+			log.Fatalf("Invalid SMTP settings: %v", err)
+		}
+	}
+
+	apiCfg.Pipeline, err = apiCfg.newContentPipeline(pipelineSpec)
+	if err != nil {
+		log.Fatalf("Invalid CHIRP_PIPELINE: %v", err)
+	}
+	apiCfg.MessagePipeline, err = apiCfg.newContentPipeline(messagePipelineSpec)
+	if err != nil {
+		log.Fatalf("Couldn't build message pipeline: %v", err)
 	}
 
 	mux := http.NewServeMux()
@@ -42,14 +100,78 @@ func main() {
 	mux.HandleFunc("GET  /api/healthz", apiCfg.healthHandler)
 	mux.HandleFunc("GET /admin/metrics", apiCfg.metricsHandler)
 	mux.HandleFunc("POST /admin/reset", apiCfg.resetHandler)
+	mux.HandleFunc("PUT /admin/users/{userID}/role", apiCfg.setUserRoleHandler)
+	mux.HandleFunc("GET /admin/banned-terms", apiCfg.getBannedTermsHandler)
+	mux.HandleFunc("POST /admin/banned-terms", apiCfg.createBannedTermHandler)
+	mux.HandleFunc("PUT /admin/banned-terms/{termID}", apiCfg.updateBannedTermHandler)
+	mux.HandleFunc("DELETE /admin/banned-terms/{termID}", apiCfg.deleteBannedTermHandler)
+	mux.HandleFunc("GET /admin/blocked-link-domains", apiCfg.getBlockedLinkDomainsHandler)
+	mux.HandleFunc("POST /admin/blocked-link-domains", apiCfg.blockLinkDomainHandler)
+	mux.HandleFunc("DELETE /admin/blocked-link-domains/{domain}", apiCfg.unblockLinkDomainHandler)
+	mux.HandleFunc("GET /admin/moderation/cases", apiCfg.getModerationQueueHandler)
+	mux.HandleFunc("GET /admin/moderation/cases/{caseID}", apiCfg.getModerationCaseHandler)
+	mux.HandleFunc("POST /admin/moderation/cases/{caseID}/claim", apiCfg.claimCaseHandler)
+	mux.HandleFunc("POST /admin/moderation/cases/{caseID}/resolve", apiCfg.resolveCaseHandler)
+	mux.HandleFunc("GET /admin/moderation/audit", apiCfg.getModerationAuditHandler)
+	mux.HandleFunc("POST /admin/users/{userID}/suspension", apiCfg.suspendUserHandler)
+	mux.HandleFunc("DELETE /admin/users/{userID}/suspension", apiCfg.unsuspendUserHandler)
+	mux.HandleFunc("PUT /admin/chirps/{chirpID}/sensitivity", apiCfg.setChirpSensitivityHandler)
 
 	mux.HandleFunc("POST  /api/chirps", apiCfg.createChirpHandler)
+	mux.HandleFunc("POST /api/chirps/validate", apiCfg.validateChirpHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/reports", apiCfg.createReportHandler)
+	mux.HandleFunc("POST /api/likes/{chirpID}", apiCfg.likeChirpHandler)
+	mux.HandleFunc("DELETE /api/likes/{chirpID}", apiCfg.unlikeChirpHandler)
 	mux.HandleFunc("GET  /api/chirps", apiCfg.getChirpsHandler)
 	mux.HandleFunc("GET  /api/chirps/{chirpID}", apiCfg.getChirpHandler)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.deleteChirpHandler)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/links", apiCfg.getChirpLinksHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/poll/votes", apiCfg.votePollHandler)
+	mux.HandleFunc("GET /api/chirps/scheduled", apiCfg.getScheduledChirpsHandler)
+	mux.HandleFunc("PATCH /api/chirps/scheduled/{scheduledID}", apiCfg.updateScheduledChirpHandler)
+	mux.HandleFunc("DELETE /api/chirps/scheduled/{scheduledID}", apiCfg.deleteScheduledChirpHandler)
+
+	mux.HandleFunc("POST /api/media", apiCfg.uploadMediaHandler)
+	mux.HandleFunc("GET /api/media/{mediaID}", apiCfg.getMediaHandler)
+	mux.HandleFunc("GET /api/media/{mediaID}/thumbnail", apiCfg.getMediaThumbnailHandler)
 
 	mux.HandleFunc("POST /api/users", apiCfg.createUserHandler)
-	mux.HandleFunc("PUT /api/users", apiCfg.updateHandler)
+	mux.HandleFunc("PATCH /api/users", apiCfg.updateAccountHandler)
+	mux.HandleFunc("GET /api/users/email/confirm", apiCfg.confirmEmailHandler)
+	mux.HandleFunc("GET /api/users/{handle}", apiCfg.getProfileHandler)
+	mux.HandleFunc("PUT /api/profile", apiCfg.updateProfileHandler)
+	mux.HandleFunc("PUT /api/profile/handle", apiCfg.changeHandleHandler)
+	mux.HandleFunc("GET /api/profile/handles", apiCfg.getHandleHistoryHandler)
+	mux.HandleFunc("GET /api/handles/{handle}", apiCfg.handleAvailabilityHandler)
+
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.followHandler)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.unfollowHandler)
+	mux.HandleFunc("GET /api/users/{userID}/followers", apiCfg.getFollowersHandler)
+	mux.HandleFunc("GET /api/users/{userID}/following", apiCfg.getFollowingHandler)
+	mux.HandleFunc("GET /api/timeline", apiCfg.timelineHandler)
+	mux.HandleFunc("POST /api/users/{userID}/block", apiCfg.blockHandler)
+	mux.HandleFunc("DELETE /api/users/{userID}/block", apiCfg.unblockHandler)
+	mux.HandleFunc("POST /api/users/{userID}/mute", apiCfg.muteHandler)
+	mux.HandleFunc("DELETE /api/users/{userID}/mute", apiCfg.unmuteHandler)
+	mux.HandleFunc("GET /api/blocks", apiCfg.getBlocksHandler)
+	mux.HandleFunc("GET /api/mutes", apiCfg.getMutesHandler)
+	mux.HandleFunc("GET /api/users/{userID}/mentions", apiCfg.getMentionsHandler)
+	mux.HandleFunc("GET /api/tags/{tag}/chirps", apiCfg.getTagChirpsHandler)
+	mux.HandleFunc("GET /api/trends", apiCfg.getTrendsHandler)
+	mux.HandleFunc("GET /api/notifications", apiCfg.getNotificationsHandler)
+	mux.HandleFunc("POST /api/notifications/read", apiCfg.markAllNotificationsReadHandler)
+	mux.HandleFunc("POST /api/notifications/{notificationID}/read", apiCfg.markNotificationReadHandler)
+	mux.HandleFunc("GET /api/notifications/preferences", apiCfg.getNotificationPreferencesHandler)
+	mux.HandleFunc("PUT /api/notifications/preferences", apiCfg.updateNotificationPreferencesHandler)
+	mux.HandleFunc("GET /api/preferences/content", apiCfg.getContentPreferencesHandler)
+	mux.HandleFunc("PUT /api/preferences/content", apiCfg.updateContentPreferencesHandler)
+	mux.HandleFunc("POST /api/conversations", apiCfg.createConversationHandler)
+	mux.HandleFunc("GET /api/conversations", apiCfg.getConversationsHandler)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", apiCfg.sendMessageHandler)
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", apiCfg.getMessagesHandler)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/read", apiCfg.markConversationReadHandler)
+	mux.HandleFunc("GET /api/stream", apiCfg.streamHandler)
+	mux.HandleFunc("GET /api/ws", apiCfg.wsHandler)
 
 	mux.HandleFunc("POST /api/login", apiCfg.loginHandler)
 	mux.HandleFunc("POST  /api/refresh", apiCfg.RefreshHandler)
@@ -57,11 +179,68 @@ func main() {
 
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.polkaHandler)
 
+	mux.HandleFunc("GET /users/{userID}/feed.atom", apiCfg.userAtomFeedHandler)
+	mux.HandleFunc("GET /users/{userID}/feed.rss", apiCfg.userRSSFeedHandler)
+	mux.HandleFunc("GET /tags/{tag}/feed.atom", apiCfg.tagAtomFeedHandler)
+	mux.HandleFunc("GET /tags/{tag}/feed.rss", apiCfg.tagRSSFeedHandler)
+
+	mux.HandleFunc("GET /api/oembed", apiCfg.oembedHandler)
+	mux.HandleFunc("GET /embed/chirps/{chirpID}", apiCfg.embedChirpHandler)
+	mux.HandleFunc("GET /l/{code}", apiCfg.shortLinkRedirectHandler)
+
+	mux.HandleFunc("GET /.well-known/webfinger", apiCfg.webfingerHandler)
+	mux.HandleFunc("GET /users/{userID}", apiCfg.actorHandler)
+	mux.HandleFunc("GET /users/{userID}/outbox", apiCfg.outboxHandler)
+	mux.HandleFunc("GET /users/{userID}/followers", apiCfg.followersCollectionHandler)
+	mux.HandleFunc("POST /users/{userID}/inbox", apiCfg.inboxHandler)
+	mux.HandleFunc("POST /inbox", apiCfg.inboxHandler)
+	mux.HandleFunc("GET /chirps/{chirpID}", apiCfg.noteHandler)
+	mux.HandleFunc("POST /api/federation/follows", apiCfg.createRemoteFollowHandler)
+	mux.HandleFunc("GET /api/federation/follows", apiCfg.getRemoteFollowsHandler)
+	mux.HandleFunc("DELETE /api/federation/follows/{actorID}", apiCfg.deleteRemoteFollowHandler)
+	mux.HandleFunc("GET /api/federation/notes", apiCfg.getRemoteNotesHandler)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := apiCfg.promoteAdmins(ctx, os.Getenv("ADMIN_EMAILS")); err != nil {
+		log.Printf("Couldn't promote ADMIN_EMAILS: %v", err)
+	}
+	if err := apiCfg.reloadProfanityFilter(ctx); err != nil {
+		log.Printf("Couldn't load banned terms: %v", err)
+	}
+
+	go apiCfg.runTrendAggregator(ctx)
+	go apiCfg.runChirpScheduler(ctx)
+	go apiCfg.runFederationDelivery(ctx)
+	go apiCfg.runLinkPreviewFetcher(ctx)
+	go apiCfg.runProfanityReloader(ctx)
+	go apiCfg.runEventListener(ctx, dbURL)
+
 	srv := &http.Server{
 		Addr:    ":" + port,
 		Handler: mux,
 	}
 
-	log.Printf("Serving on port: %s\n", port)
-	log.Fatal(srv.ListenAndServe())
+	go func() {
+		log.Printf("Serving on port: %s\n", port)
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	// Streams and WebSockets are closed first so clients can reconnect to
+	// another instance, then in-flight requests are allowed to finish.
+	if err := apiCfg.Live.shutdown(shutdownCtx); err != nil {
+		log.Printf("Live connections didn't close in time: %v", err)
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Shutdown didn't finish cleanly: %v", err)
+	}
 }
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/media"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/storage"
+)
+
+const maxChirpMedia = 4
+
+func (cfg *apiConfig) uploadMediaHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	user, err := cfg.DB.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found", err)
+		return
+	}
+	if respondIfSuspended(w, user) {
+		return
+	}
+
+	// Leave some room for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, media.MaxUploadBytes+(1<<20))
+
+	file, _, err := r.FormFile("file")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "File is too large", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't read uploaded file", err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, media.MaxUploadBytes+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't read uploaded file", err)
+		return
+	}
+	if len(data) > media.MaxUploadBytes {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "File is too large", nil)
+		return
+	}
+
+	processed, err := media.Process(data)
+	if errors.Is(err, media.ErrUnsupportedType) {
+		respondWithError(w, http.StatusUnsupportedMediaType, "Only JPEG, PNG and GIF images are supported", err)
+		return
+	}
+	if errors.Is(err, media.ErrTooLarge) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Image dimensions are too large", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't process image", err)
+		return
+	}
+
+	// Blobs are keyed by content hash, so identical uploads from different
+	// users share storage.
+	storageKey := processed.Sha256 + processed.Extension
+	thumbnailKey := processed.Sha256 + "_thumb" + processed.ThumbnailExtension
+
+	if err := cfg.storeBlob(r.Context(), storageKey, processed.Data); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store file", err)
+		return
+	}
+	if err := cfg.storeBlob(r.Context(), thumbnailKey, processed.Thumbnail); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store thumbnail", err)
+		return
+	}
+
+	mediaFile, err := cfg.DB.CreateMediaFile(r.Context(), database.CreateMediaFileParams{
+		UserID:       userID,
+		Sha256:       processed.Sha256,
+		ContentType:  processed.ContentType,
+		SizeBytes:    int64(len(processed.Data)),
+		Width:        int32(processed.Width),
+		Height:       int32(processed.Height),
+		StorageKey:   storageKey,
+		ThumbnailKey: thumbnailKey,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save media", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, cfg.toMediaResponse(mediaFile))
+}
+
+// storeBlob writes data under key unless a blob with that key is already
+// stored.
+func (cfg *apiConfig) storeBlob(ctx context.Context, key string, data []byte) error {
+	exists, err := cfg.Media.Exists(ctx, key)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	return cfg.Media.Put(ctx, key, bytes.NewReader(data))
+}
+
+// parseChirpMedia validates the media IDs attached to a new chirp. Every ID
+// must be an upload owned by the author, and duplicates are dropped.
+func (cfg *apiConfig) parseChirpMedia(ctx context.Context, userID uuid.UUID, mediaIDStrings []string) ([]uuid.UUID, error) {
+	if len(mediaIDStrings) > maxChirpMedia {
+		return nil, fmt.Errorf("a chirp can have at most %d attachments", maxChirpMedia)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(mediaIDStrings))
+	mediaIDs := make([]uuid.UUID, 0, len(mediaIDStrings))
+	for _, s := range mediaIDStrings {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid media ID %q", s)
+		}
+		if !seen[id] {
+			seen[id] = true
+			mediaIDs = append(mediaIDs, id)
+		}
+	}
+
+	if len(mediaIDs) == 0 {
+		return nil, nil
+	}
+
+	files, err := cfg.DB.GetMediaFilesByIDs(ctx, mediaIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := 0
+	for _, f := range files {
+		if f.UserID == userID {
+			owned++
+		}
+	}
+	if owned != len(mediaIDs) {
+		return nil, errors.New("media not found")
+	}
+
+	return mediaIDs, nil
+}
+
+func (cfg *apiConfig) toMediaResponse(f database.MediaFile) mediaResponse {
+	return mediaResponse{
+		ID:           f.ID,
+		URL:          "/api/media/" + f.ID.String(),
+		ThumbnailURL: "/api/media/" + f.ID.String() + "/thumbnail",
+		ContentType:  f.ContentType,
+		Width:        f.Width,
+		Height:       f.Height,
+		SizeBytes:    f.SizeBytes,
+	}
+}
+
+func (cfg *apiConfig) getMediaHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.serveMedia(w, r, false)
+}
+
+func (cfg *apiConfig) getMediaThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.serveMedia(w, r, true)
+}
+
+// serveMedia sends an uploaded file, or its thumbnail. Blobs aren't public:
+// the viewer has to be the uploader, or able to see an avatar or chirp the
+// file is used in, so hiding, deleting or narrowing a chirp also takes its
+// media out of reach.
+func (cfg *apiConfig) serveMedia(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	mediaID, err := uuid.Parse(r.PathValue("mediaID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid media ID", err)
+		return
+	}
+
+	visible, err := cfg.DB.CanViewMedia(r.Context(), database.CanViewMediaParams{
+		MediaID:  mediaID,
+		ViewerID: cfg.viewerID(r),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load media", err)
+		return
+	}
+	if !visible {
+		respondWithError(w, http.StatusNotFound, "Media not found", nil)
+		return
+	}
+
+	files, err := cfg.DB.GetMediaFilesByIDs(r.Context(), []uuid.UUID{mediaID})
+	if err != nil || len(files) == 0 {
+		respondWithError(w, http.StatusNotFound, "Media not found", err)
+		return
+	}
+	key, contentType := files[0].StorageKey, files[0].ContentType
+	if thumbnail {
+		key, contentType = files[0].ThumbnailKey, mime.TypeByExtension(filepath.Ext(files[0].ThumbnailKey))
+	}
+
+	blob, err := cfg.Media.Open(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Media not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load media", err)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Access can be taken away, so shared caches mustn't keep a copy
+	// and browsers only keep one briefly.
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if _, err := io.Copy(w, blob); err != nil {
+		log.Printf("Couldn't send media %s: %v", mediaID, err)
+	}
+}
@@ -0,0 +1,769 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/moderation"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pagination"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pubsub"
+)
+
+// Audit log actions.
+const (
+	auditChirpHidden      = "chirp_hidden"
+	auditCaseClaimed      = "case_claimed"
+	auditCaseResolved     = "case_resolved"
+	auditStrikeIssued     = "strike_issued"
+	auditUserSuspended    = "user_suspended"
+	auditUserUnsuspended  = "user_unsuspended"
+	auditChirpSensitivity = "chirp_sensitivity_set"
+)
+
+type reportRequest struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+type reportResponse struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	ReporterID uuid.UUID `json:"reporter_id"`
+	Reason     string    `json:"reason"`
+	Details    string    `json:"details"`
+}
+
+type flagResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Source     string     `json:"source"`
+	Reason     string     `json:"reason"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+}
+
+type caseResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ChirpID    uuid.UUID  `json:"chirp_id"`
+	Status     string     `json:"status"`
+	ClaimedBy  *uuid.UUID `json:"claimed_by,omitempty"`
+	ClaimedAt  *time.Time `json:"claimed_at,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	Decision   string     `json:"decision,omitempty"`
+}
+
+type queueItemResponse struct {
+	caseResponse
+	AuthorID    uuid.UUID `json:"author_id"`
+	Body        string    `json:"body"`
+	ReportCount int64     `json:"report_count"`
+	FlagCount   int64     `json:"flag_count"`
+}
+
+type caseDetailResponse struct {
+	caseResponse
+	Chirp   chirpResponse    `json:"chirp"`
+	Hidden  bool             `json:"hidden"`
+	Reports []reportResponse `json:"reports"`
+	Flags   []flagResponse   `json:"flags"`
+}
+
+type resolveCaseRequest struct {
+	Decision string `json:"decision"`
+	Note     string `json:"note"`
+}
+
+type suspendUserRequest struct {
+	Hours  int    `json:"hours"`
+	Reason string `json:"reason"`
+}
+
+type suspensionResponse struct {
+	UserID         uuid.UUID  `json:"user_id"`
+	SuspendedUntil *time.Time `json:"suspended_until"`
+}
+
+type auditEntryResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ModeratorID  *uuid.UUID `json:"moderator_id"`
+	Action       string     `json:"action"`
+	CaseID       *uuid.UUID `json:"case_id,omitempty"`
+	TargetUserID *uuid.UUID `json:"target_user_id,omitempty"`
+	Note         string     `json:"note"`
+}
+
+type auditPageResponse struct {
+	Entries    []auditEntryResponse `json:"entries"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// respondIfSuspended writes a 403 and returns true when user is currently
+// suspended.
+func respondIfSuspended(w http.ResponseWriter, user database.User) bool {
+	if !user.SuspendedUntil.Valid || !user.SuspendedUntil.Time.After(time.Now().UTC()) {
+		return false
+	}
+	msg := fmt.Sprintf("Account suspended until %s", user.SuspendedUntil.Time.Format(time.RFC3339))
+	respondWithError(w, http.StatusForbidden, msg, nil)
+	return true
+}
+
+// createReportHandler lets a user report someone else's chirp. Reports
+// gather on the chirp's open moderation case, and once enough people have
+// reported it the chirp is hidden until a moderator looks at it. Only
+// reports from established accounts the author hasn't blocked count
+// towards hiding, so a brigade of fresh or blocked accounts can't take a
+// chirp down on its own.
+func (cfg *apiConfig) createReportHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	user, err := cfg.DB.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found", err)
+		return
+	}
+	if respondIfSuspended(w, user) {
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	var req reportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if !moderation.ValidReason(moderation.Reason(req.Reason)) {
+		respondWithError(w, http.StatusBadRequest, "Unknown report reason", nil)
+		return
+	}
+	if len([]rune(req.Details)) > moderation.MaxReportDetails {
+		respondWithError(w, http.StatusBadRequest, "Report details are too long", nil)
+		return
+	}
+
+	chirp, err := cfg.DB.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if chirp.UserID == userID {
+		respondWithError(w, http.StatusBadRequest, "You can't report your own chirp", nil)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save report", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	modCase, err := qtx.OpenModerationCase(r.Context(), chirp.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save report", err)
+		return
+	}
+
+	report, err := qtx.CreateReport(r.Context(), database.CreateReportParams{
+		CaseID:     modCase.ID,
+		ChirpID:    chirp.ID,
+		ReporterID: userID,
+		Reason:     req.Reason,
+		Details:    req.Details,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "You have already reported this chirp", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save report", err)
+		return
+	}
+
+	reports, err := qtx.CountTrustedCaseReports(r.Context(), database.CountTrustedCaseReportsParams{
+		CaseID:       modCase.ID,
+		JoinedBefore: time.Now().UTC().Add(-moderation.AutoHideMinAccountAge),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save report", err)
+		return
+	}
+	if reports >= moderation.AutoHideReports {
+		if err := qtx.HideChirp(r.Context(), chirp.ID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't save report", err)
+			return
+		}
+		if err := recordChirpEvent(r.Context(), qtx, pubsub.ChirpDeleted, chirp); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't save report", err)
+			return
+		}
+		err = recordModerationAction(r.Context(), qtx, uuid.Nil, auditChirpHidden, modCase.ID, chirp.UserID,
+			fmt.Sprintf("hidden pending review after %d reports", reports))
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't save report", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save report", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, toReportResponse(report))
+}
+
+// getModerationQueueHandler lists cases with the given status, oldest
+// first, so moderators work through the queue in the order it filled.
+func (cfg *apiConfig) getModerationQueueHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireRole(w, r, roleModerator, roleAdmin); !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "open"
+	}
+	if status != "open" && status != "claimed" && status != "resolved" {
+		respondWithError(w, http.StatusBadRequest, "status must be open, claimed or resolved", nil)
+		return
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	rows, err := cfg.DB.GetModerationQueue(r.Context(), database.GetModerationQueueParams{
+		Status:   status,
+		PageSize: limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve moderation queue", err)
+		return
+	}
+
+	items := make([]queueItemResponse, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, queueItemResponse{
+			caseResponse: toCaseResponse(database.ModerationCase{
+				ID:         row.ID,
+				CreatedAt:  row.CreatedAt,
+				UpdatedAt:  row.UpdatedAt,
+				ChirpID:    row.ChirpID,
+				Status:     row.Status,
+				ClaimedBy:  row.ClaimedBy,
+				ClaimedAt:  row.ClaimedAt,
+				ResolvedAt: row.ResolvedAt,
+				Decision:   row.Decision,
+			}),
+			AuthorID:    row.AuthorID,
+			Body:        row.Body,
+			ReportCount: row.ReportCount,
+			FlagCount:   row.FlagCount,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, items)
+}
+
+func (cfg *apiConfig) getModerationCaseHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireRole(w, r, roleModerator, roleAdmin); !ok {
+		return
+	}
+
+	caseID, err := uuid.Parse(r.PathValue("caseID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid case ID", err)
+		return
+	}
+
+	modCase, err := cfg.DB.GetModerationCase(r.Context(), caseID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Case not found", err)
+		return
+	}
+
+	chirp, err := cfg.DB.GetChirp(r.Context(), modCase.ChirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp", err)
+		return
+	}
+	chirpResp, err := cfg.buildChirpResponse(r.Context(), uuid.Nil, chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp", err)
+		return
+	}
+
+	reports, err := cfg.DB.GetCaseReports(r.Context(), modCase.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load reports", err)
+		return
+	}
+	flags, err := cfg.DB.GetCaseFlags(r.Context(), uuid.NullUUID{UUID: modCase.ID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load flags", err)
+		return
+	}
+
+	response := caseDetailResponse{
+		caseResponse: toCaseResponse(modCase),
+		Chirp:        chirpResp,
+		Hidden:       chirp.HiddenAt.Valid,
+		Reports:      make([]reportResponse, 0, len(reports)),
+		Flags:        make([]flagResponse, 0, len(flags)),
+	}
+	for _, report := range reports {
+		response.Reports = append(response.Reports, toReportResponse(report))
+	}
+	for _, flag := range flags {
+		response.Flags = append(response.Flags, flagResponse{
+			ID:         flag.ID,
+			CreatedAt:  flag.CreatedAt,
+			Source:     flag.Source,
+			Reason:     flag.Reason,
+			ReviewedAt: nullTimePtr(flag.ReviewedAt),
+		})
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// claimCaseHandler assigns an open case to the calling moderator so two
+// people don't review the same chirp. Claiming a case you already hold is
+// a no-op.
+func (cfg *apiConfig) claimCaseHandler(w http.ResponseWriter, r *http.Request) {
+	moderator, ok := cfg.requireRole(w, r, roleModerator, roleAdmin)
+	if !ok {
+		return
+	}
+
+	caseID, err := uuid.Parse(r.PathValue("caseID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid case ID", err)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't claim case", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	modCase, err := qtx.ClaimModerationCase(r.Context(), database.ClaimModerationCaseParams{
+		ModeratorID: moderator.ID,
+		ID:          caseID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.respondCaseUnavailable(w, r, caseID)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't claim case", err)
+		return
+	}
+
+	if err := recordModerationAction(r.Context(), qtx, moderator.ID, auditCaseClaimed, modCase.ID, uuid.Nil, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't claim case", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't claim case", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, toCaseResponse(modCase))
+}
+
+// resolveCaseHandler closes a case the caller has claimed. Dismissing
+// restores the chirp, hiding keeps it hidden, and removing also gives the
+// author a strike, which may suspend them.
+func (cfg *apiConfig) resolveCaseHandler(w http.ResponseWriter, r *http.Request) {
+	moderator, ok := cfg.requireRole(w, r, roleModerator, roleAdmin)
+	if !ok {
+		return
+	}
+
+	caseID, err := uuid.Parse(r.PathValue("caseID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid case ID", err)
+		return
+	}
+
+	var req resolveCaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	decision := moderation.Decision(req.Decision)
+	if !moderation.ValidDecision(decision) {
+		respondWithError(w, http.StatusBadRequest, "decision must be dismiss, hide or remove", nil)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve case", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	modCase, err := qtx.ResolveModerationCase(r.Context(), database.ResolveModerationCaseParams{
+		Decision:    req.Decision,
+		ID:          caseID,
+		ModeratorID: moderator.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.respondCaseUnavailable(w, r, caseID)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve case", err)
+		return
+	}
+
+	chirp, err := qtx.GetChirp(r.Context(), modCase.ChirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve case", err)
+		return
+	}
+
+	var eventType string
+	if decision == moderation.DecisionDismiss {
+		err = qtx.UnhideChirp(r.Context(), chirp.ID)
+		if chirp.HiddenAt.Valid {
+			eventType = pubsub.ChirpUpdated
+		}
+	} else {
+		err = qtx.HideChirp(r.Context(), chirp.ID)
+		if !chirp.HiddenAt.Valid {
+			eventType = pubsub.ChirpDeleted
+		}
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve case", err)
+		return
+	}
+
+	if eventType != "" {
+		if err := recordChirpEvent(r.Context(), qtx, eventType, chirp); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't resolve case", err)
+			return
+		}
+	}
+
+	if err := qtx.MarkCaseFlagsReviewed(r.Context(), uuid.NullUUID{UUID: modCase.ID, Valid: true}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve case", err)
+		return
+	}
+
+	note := req.Decision
+	if req.Note != "" {
+		note += ": " + req.Note
+	}
+	err = recordModerationAction(r.Context(), qtx, moderator.ID, auditCaseResolved, modCase.ID, chirp.UserID, note)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve case", err)
+		return
+	}
+
+	if decision == moderation.DecisionRemove {
+		err := issueStrike(r.Context(), qtx, moderator.ID, chirp.UserID, modCase.ID, "chirp removed by moderator")
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't resolve case", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve case", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, toCaseResponse(modCase))
+}
+
+// respondCaseUnavailable explains why a claim or resolve matched no case.
+func (cfg *apiConfig) respondCaseUnavailable(w http.ResponseWriter, r *http.Request, caseID uuid.UUID) {
+	modCase, err := cfg.DB.GetModerationCase(r.Context(), caseID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Case not found", err)
+		return
+	}
+	switch modCase.Status {
+	case "resolved":
+		respondWithError(w, http.StatusConflict, "Case is already resolved", nil)
+	case "claimed":
+		respondWithError(w, http.StatusConflict, "Case is claimed by another moderator", nil)
+	default:
+		respondWithError(w, http.StatusConflict, "Claim the case before resolving it", nil)
+	}
+}
+
+// issueStrike records a strike against userID and suspends them if that
+// takes their active strikes past the limit. An existing longer suspension
+// is left as it is.
+func issueStrike(ctx context.Context, q *database.Queries, moderatorID, userID, caseID uuid.UUID, reason string) error {
+	_, err := q.CreateStrike(ctx, database.CreateStrikeParams{
+		UserID:    userID,
+		CaseID:    uuid.NullUUID{UUID: caseID, Valid: true},
+		IssuedBy:  uuid.NullUUID{UUID: moderatorID, Valid: true},
+		Reason:    reason,
+		ExpiresAt: time.Now().UTC().Add(moderation.StrikeLifetime),
+	})
+	if err != nil {
+		return err
+	}
+	if err := recordModerationAction(ctx, q, moderatorID, auditStrikeIssued, caseID, userID, reason); err != nil {
+		return err
+	}
+
+	strikes, err := q.CountActiveStrikes(ctx, userID)
+	if err != nil {
+		return err
+	}
+	length := moderation.SuspensionFor(strikes)
+	if length == 0 {
+		return nil
+	}
+
+	user, err := q.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	until := time.Now().UTC().Add(length)
+	if user.SuspendedUntil.Valid && user.SuspendedUntil.Time.After(until) {
+		return nil
+	}
+	if _, err := suspendUser(ctx, q, userID, until); err != nil {
+		return err
+	}
+	note := fmt.Sprintf("%d active strikes, suspended until %s", strikes, until.Format(time.RFC3339))
+	return recordModerationAction(ctx, q, moderatorID, auditUserSuspended, caseID, userID, note)
+}
+
+// suspendUser suspends userID until the given time and signs them out
+// everywhere, so the suspension stops their open sessions, not just new
+// logins.
+func suspendUser(ctx context.Context, q *database.Queries, userID uuid.UUID, until time.Time) (database.User, error) {
+	user, err := q.SuspendUser(ctx, database.SuspendUserParams{
+		ID:             userID,
+		SuspendedUntil: sql.NullTime{Time: until, Valid: true},
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+	if _, err := q.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return database.User{}, err
+	}
+	return user, nil
+}
+
+func (cfg *apiConfig) suspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	moderator, ok := cfg.requireRole(w, r, roleModerator, roleAdmin)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	var req suspendUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.Hours <= 0 {
+		respondWithError(w, http.StatusBadRequest, "hours must be a positive number", nil)
+		return
+	}
+	until := time.Now().UTC().Add(time.Duration(req.Hours) * time.Hour)
+
+	cfg.setSuspension(w, r, moderator.ID, userID, sql.NullTime{Time: until, Valid: true}, req.Reason)
+}
+
+func (cfg *apiConfig) unsuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	moderator, ok := cfg.requireRole(w, r, roleModerator, roleAdmin)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	cfg.setSuspension(w, r, moderator.ID, userID, sql.NullTime{}, "")
+}
+
+// setSuspension suspends userID until the given time, or lifts their
+// suspension when until is null, and records who did it.
+func (cfg *apiConfig) setSuspension(w http.ResponseWriter, r *http.Request, moderatorID, userID uuid.UUID, until sql.NullTime, reason string) {
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update suspension", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	var user database.User
+	action := auditUserUnsuspended
+	if until.Valid {
+		action = auditUserSuspended
+		user, err = suspendUser(r.Context(), qtx, userID, until.Time)
+	} else {
+		user, err = qtx.UnsuspendUser(r.Context(), userID)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update suspension", err)
+		return
+	}
+
+	if err := recordModerationAction(r.Context(), qtx, moderatorID, action, uuid.Nil, userID, reason); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update suspension", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update suspension", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, suspensionResponse{
+		UserID:         user.ID,
+		SuspendedUntil: nullTimePtr(user.SuspendedUntil),
+	})
+}
+
+// getModerationAuditHandler pages through moderator decisions, newest
+// first.
+func (cfg *apiConfig) getModerationAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireRole(w, r, roleModerator, roleAdmin); !ok {
+		return
+	}
+
+	cursor, err := pagination.Parse(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+		return
+	}
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	actions, err := cfg.DB.GetModerationActions(r.Context(), database.GetModerationActionsParams{
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve audit log", err)
+		return
+	}
+
+	response := auditPageResponse{Entries: make([]auditEntryResponse, 0, len(actions))}
+	for _, a := range actions {
+		response.Entries = append(response.Entries, auditEntryResponse{
+			ID:           a.ID,
+			CreatedAt:    a.CreatedAt,
+			ModeratorID:  nullUUIDPtr(a.ModeratorID),
+			Action:       a.Action,
+			CaseID:       nullUUIDPtr(a.CaseID),
+			TargetUserID: nullUUIDPtr(a.TargetUserID),
+			Note:         a.Note,
+		})
+	}
+	if len(actions) == int(limit) {
+		last := actions[len(actions)-1]
+		response.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// recordModerationAction appends to the audit log. A nil moderatorID marks
+// an automatic action, and nil case or target IDs are stored as null.
+func recordModerationAction(ctx context.Context, q *database.Queries, moderatorID uuid.UUID, action string, caseID, targetUserID uuid.UUID, note string) error {
+	return q.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		ModeratorID:  uuid.NullUUID{UUID: moderatorID, Valid: moderatorID != uuid.Nil},
+		Action:       action,
+		CaseID:       uuid.NullUUID{UUID: caseID, Valid: caseID != uuid.Nil},
+		TargetUserID: uuid.NullUUID{UUID: targetUserID, Valid: targetUserID != uuid.Nil},
+		Note:         note,
+	})
+}
+
+func toReportResponse(r database.Report) reportResponse {
+	return reportResponse{
+		ID:         r.ID,
+		CreatedAt:  r.CreatedAt,
+		ChirpID:    r.ChirpID,
+		ReporterID: r.ReporterID,
+		Reason:     r.Reason,
+		Details:    r.Details,
+	}
+}
+
+func toCaseResponse(c database.ModerationCase) caseResponse {
+	return caseResponse{
+		ID:         c.ID,
+		CreatedAt:  c.CreatedAt,
+		UpdatedAt:  c.UpdatedAt,
+		ChirpID:    c.ChirpID,
+		Status:     c.Status,
+		ClaimedBy:  nullUUIDPtr(c.ClaimedBy),
+		ClaimedAt:  nullTimePtr(c.ClaimedAt),
+		ResolvedAt: nullTimePtr(c.ResolvedAt),
+		Decision:   c.Decision.String,
+	}
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/notify"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pagination"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pubsub"
+)
+
+// maxNotificationActors is how many of a grouped notification's actors are
+// listed; the rest are only counted.
+const maxNotificationActors = 3
+
+// notification is an event one user should hear about.
+type notification struct {
+	Recipient uuid.UUID
+	Actor     uuid.UUID
+	Type      notify.Type
+	// ChirpID is the chirp the notification is about, if any.
+	ChirpID uuid.UUID
+	// ActorChirpID is the actor's chirp that caused it, such as their
+	// rechirp.
+	ActorChirpID uuid.UUID
+}
+
+// notifyUser records a notification, folding it into the recipient's
+// unread one for the same group if there is one. Nothing is recorded for
+// users acting on their own chirps, when the recipient can't see the
+// actor's chirp, when the recipient has turned the type off, or when
+// either side has blocked the other or the recipient has muted the actor.
+// Recorded notifications are also sent to the recipient's live
+// connections once q's transaction commits.
+func notifyUser(ctx context.Context, q *database.Queries, n notification) error {
+	if n.Recipient == n.Actor {
+		return nil
+	}
+
+	if n.ActorChirpID != uuid.Nil {
+		_, err := q.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+			ID:       n.ActorChirpID,
+			ViewerID: n.Recipient,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var chirpID uuid.NullUUID
+	if n.ChirpID != uuid.Nil {
+		chirpID = uuid.NullUUID{UUID: n.ChirpID, Valid: true}
+	}
+	saved, err := q.UpsertNotification(ctx, database.UpsertNotificationParams{
+		UserID:   n.Recipient,
+		Type:     string(n.Type),
+		ChirpID:  chirpID,
+		GroupKey: notify.GroupKey(n.Type, n.ChirpID),
+		ActorID:  n.Actor,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var actorChirpID uuid.NullUUID
+	if n.ActorChirpID != uuid.Nil {
+		actorChirpID = uuid.NullUUID{UUID: n.ActorChirpID, Valid: true}
+	}
+	err = q.AddNotificationActor(ctx, database.AddNotificationActorParams{
+		NotificationID: saved.ID,
+		ActorID:        n.Actor,
+		ChirpID:        actorChirpID,
+	})
+	if err != nil {
+		return err
+	}
+
+	actorCount, err := q.CountNotificationActors(ctx, saved.ID)
+	if err != nil {
+		return err
+	}
+	// Only the newest actor is sent, keeping the event small; clients
+	// reload the list over REST for the rest.
+	return publishPrivateEvent(ctx, q, privateEvent{
+		Type:       pubsub.NotificationCreated,
+		Recipients: []uuid.UUID{n.Recipient},
+		Data: notificationResponse{
+			ID:         saved.ID,
+			Type:       saved.Type,
+			CreatedAt:  saved.CreatedAt,
+			UpdatedAt:  saved.UpdatedAt,
+			ChirpID:    nullUUIDPtr(saved.ChirpID),
+			Summary:    notify.Summary(n.Type, actorCount),
+			ActorCount: actorCount,
+			Actors: []notificationActor{{
+				UserID:    n.Actor,
+				ChirpID:   nullUUIDPtr(actorChirpID),
+				CreatedAt: saved.UpdatedAt,
+			}},
+		},
+	})
+}
+
+type notificationActor struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	ChirpID   *uuid.UUID `json:"chirp_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type notificationResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Type      string     `json:"type"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Read      bool       `json:"read"`
+	ChirpID   *uuid.UUID `json:"chirp_id,omitempty"`
+	Summary   string     `json:"summary"`
+	// ActorCount is how many people the notification is grouped from;
+	// Actors lists the most recent few.
+	ActorCount int64               `json:"actor_count"`
+	Actors     []notificationActor `json:"actors"`
+}
+
+type notificationListResponse struct {
+	Notifications []notificationResponse `json:"notifications"`
+	UnreadCount   int64                  `json:"unread_count"`
+	NextCursor    string                 `json:"next_cursor,omitempty"`
+}
+
+// getNotificationsHandler lists the caller's notifications, most recently
+// active first. ?unread=true leaves out ones already read.
+func (cfg *apiConfig) getNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, cursor, limit, ok := cfg.parseOwnListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.DB.GetNotifications(r.Context(), database.GetNotificationsParams{
+		UserID:          userID,
+		UnreadOnly:      r.URL.Query().Get("unread") == "true",
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve notifications", err)
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	actorRows, err := cfg.DB.GetNotificationActors(r.Context(), ids)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve notifications", err)
+		return
+	}
+	actors := make(map[uuid.UUID][]notificationActor, len(rows))
+	for _, a := range actorRows {
+		if len(actors[a.NotificationID]) == maxNotificationActors {
+			continue
+		}
+		actors[a.NotificationID] = append(actors[a.NotificationID], notificationActor{
+			UserID:    a.ActorID,
+			ChirpID:   nullUUIDPtr(a.ChirpID),
+			CreatedAt: a.CreatedAt,
+		})
+	}
+
+	unread, err := cfg.DB.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve notifications", err)
+		return
+	}
+
+	response := notificationListResponse{
+		Notifications: make([]notificationResponse, 0, len(rows)),
+		UnreadCount:   unread,
+	}
+	for _, row := range rows {
+		response.Notifications = append(response.Notifications, notificationResponse{
+			ID:         row.ID,
+			Type:       row.Type,
+			CreatedAt:  row.CreatedAt,
+			UpdatedAt:  row.UpdatedAt,
+			Read:       row.ReadAt.Valid,
+			ChirpID:    nullUUIDPtr(row.ChirpID),
+			Summary:    notify.Summary(notify.Type(row.Type), row.ActorCount),
+			ActorCount: row.ActorCount,
+			Actors:     actors[row.ID],
+		})
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		response.NextCursor = pagination.Cursor{CreatedAt: last.UpdatedAt, ID: last.ID}.Encode()
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) markNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	notificationID, err := uuid.Parse(r.PathValue("notificationID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid notification ID", err)
+		return
+	}
+
+	updated, err := cfg.DB.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update notification", err)
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, http.StatusNotFound, "Notification not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) markAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	if _, err := cfg.DB.MarkAllNotificationsRead(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update notifications", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type notificationPreferences struct {
+	// Preferences maps each notification type to whether it's on.
+	Preferences map[notify.Type]bool `json:"preferences"`
+}
+
+func (cfg *apiConfig) getNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	cfg.respondWithNotificationPreferences(w, r, userID)
+}
+
+// updateNotificationPreferencesHandler turns notification types on or off.
+// Types left out of the request keep their current setting.
+func (cfg *apiConfig) updateNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	var req notificationPreferences
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	for t := range req.Preferences {
+		if !notify.ValidType(t) {
+			respondWithError(w, http.StatusBadRequest, "Unknown notification type: "+string(t), nil)
+			return
+		}
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update preferences", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	for t, enabled := range req.Preferences {
+		err := qtx.SetNotificationPreference(r.Context(), database.SetNotificationPreferenceParams{
+			UserID:  userID,
+			Type:    string(t),
+			Enabled: enabled,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update preferences", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update preferences", err)
+		return
+	}
+
+	cfg.respondWithNotificationPreferences(w, r, userID)
+}
+
+// respondWithNotificationPreferences writes every type's setting. Types
+// the user hasn't changed are on.
+func (cfg *apiConfig) respondWithNotificationPreferences(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	rows, err := cfg.DB.GetNotificationPreferences(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve preferences", err)
+		return
+	}
+
+	response := notificationPreferences{Preferences: make(map[notify.Type]bool, len(notify.Types))}
+	for _, t := range notify.Types {
+		response.Preferences[t] = true
+	}
+	for _, row := range rows {
+		if notify.ValidType(notify.Type(row.Type)) {
+			response.Preferences[notify.Type(row.Type)] = row.Enabled
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
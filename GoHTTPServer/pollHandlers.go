@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pipeline"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/polls"
+)
+
+var errPollBannedLanguage = errors.New("poll options contain banned language")
+
+// pollDraft is a poll that has been validated and is ready to store with
+// its chirp.
+type pollDraft struct {
+	Options        []string
+	ClosesAt       time.Time
+	MultipleChoice bool
+	HideResults    bool
+}
+
+type pollVoteRequest struct {
+	// Choices are indexes into the poll's options.
+	Choices []int `json:"choices"`
+}
+
+// parsePoll checks a poll sent with a new chirp. Options go through the
+// banned term filter like the body does, and any flags are added to
+// content.
+func (cfg *apiConfig) parsePoll(req *pollRequest, content *pipeline.Content) (*pollDraft, error) {
+	options, err := polls.Options(req.Options)
+	if err != nil {
+		return nil, err
+	}
+	closesAt := req.ClosesAt.UTC()
+	if err := polls.CheckClosesAt(closesAt, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	if !cfg.filterExtraText(content, "poll", options) {
+		return nil, errPollBannedLanguage
+	}
+
+	return &pollDraft{
+		Options:        options,
+		ClosesAt:       closesAt,
+		MultipleChoice: req.MultipleChoice,
+		HideResults:    req.HideResults,
+	}, nil
+}
+
+func savePoll(ctx context.Context, q *database.Queries, chirp database.Chirp, poll *pollDraft) error {
+	err := q.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:        chirp.ID,
+		ClosesAt:       poll.ClosesAt,
+		MultipleChoice: poll.MultipleChoice,
+		HideResults:    poll.HideResults,
+	})
+	if err != nil {
+		return err
+	}
+	for i, o := range poll.Options {
+		err := q.CreatePollOption(ctx, database.CreatePollOptionParams{
+			ChirpID:  chirp.ID,
+			Position: int32(i),
+			Text:     o,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// votePollHandler casts the caller's vote in a chirp's poll. Everyone gets
+// one vote, which can't be changed. Voting on a rechirp votes in the
+// original's poll.
+func (cfg *apiConfig) votePollHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
+		return
+	}
+
+	user, err := cfg.DB.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found", err)
+		return
+	}
+	if respondIfSuspended(w, user) {
+		return
+	}
+
+	var req pollVoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	chirp, err := cfg.resolveOriginalChirp(r.Context(), userID, r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't record vote", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	poll, err := qtx.GetPollForVote(r.Context(), chirp.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp doesn't have a poll", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't record vote", err)
+		return
+	}
+	if !poll.ClosesAt.After(time.Now().UTC()) {
+		respondWithError(w, http.StatusConflict, "Poll is closed", nil)
+		return
+	}
+
+	choices, err := polls.Choices(req.Choices, int(poll.OptionCount), poll.MultipleChoice)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	voted, err := qtx.HasVotedInPoll(r.Context(), database.HasVotedInPollParams{
+		ChirpID: chirp.ID,
+		UserID:  userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't record vote", err)
+		return
+	}
+	if voted {
+		respondWithError(w, http.StatusConflict, "You've already voted in this poll", nil)
+		return
+	}
+
+	for _, c := range choices {
+		err := qtx.CreatePollVote(r.Context(), database.CreatePollVoteParams{
+			ChirpID:  chirp.ID,
+			Position: int32(c),
+			UserID:   userID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't record vote", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't record vote", err)
+		return
+	}
+
+	response, err := cfg.buildChirpResponse(r.Context(), userID, chirp)
+	if err != nil || response.Poll == nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load poll", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, response.Poll)
+}
+
+// loadPolls fetches the polls on the given chirps, with tallies and the
+// viewer's own votes. Tallies are left out where the poll hides them from
+// this viewer.
+func (cfg *apiConfig) loadPolls(ctx context.Context, viewerID uuid.UUID, byID map[uuid.UUID]database.Chirp, ids []uuid.UUID) (map[uuid.UUID]*pollResponse, error) {
+	rows, err := cfg.DB.GetPollsByChirpIDs(ctx, ids)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	pollIDs := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		pollIDs = append(pollIDs, row.ChirpID)
+	}
+	tallies, err := cfg.DB.GetPollOptionTallies(ctx, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+	votes, err := cfg.DB.GetViewerPollVotes(ctx, database.GetViewerPollVotesParams{
+		ChirpIds: pollIDs,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ownVotes := make(map[uuid.UUID][]int32, len(votes))
+	for _, v := range votes {
+		ownVotes[v.ChirpID] = append(ownVotes[v.ChirpID], v.Position)
+	}
+
+	now := time.Now().UTC()
+	result := make(map[uuid.UUID]*pollResponse, len(rows))
+	for _, row := range rows {
+		own := ownVotes[row.ChirpID]
+		if own == nil {
+			own = []int32{}
+		}
+		closed := !row.ClosesAt.After(now)
+		resp := &pollResponse{
+			ClosesAt:       row.ClosesAt,
+			Closed:         closed,
+			MultipleChoice: row.MultipleChoice,
+			HideResults:    row.HideResults,
+			Options:        []pollOptionResponse{},
+			OwnVotes:       own,
+		}
+		if polls.ShowResults(row.HideResults, byID[row.ChirpID].UserID == viewerID, len(own) > 0, closed) {
+			voters := row.Voters
+			resp.Voters = &voters
+		}
+		result[row.ChirpID] = resp
+	}
+
+	for _, t := range tallies {
+		resp := result[t.ChirpID]
+		option := pollOptionResponse{Text: t.Text}
+		if resp.Voters != nil {
+			votes := t.Votes
+			option.Votes = &votes
+		}
+		resp.Options = append(resp.Options, option)
+	}
+	return result, nil
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/handles"
+)
+
+const (
+	maxDisplayName = 50
+	maxBio         = 160
+	// maxHandleChanges is how many times a user can change their handle
+	// within handles.RedirectPeriod, so no one can hold on to a string of
+	// released handles.
+	maxHandleChanges = 3
+)
+
+type profileResponse struct {
+	ID             uuid.UUID      `json:"id"`
+	Handle         string         `json:"handle"`
+	DisplayName    string         `json:"display_name"`
+	Bio            string         `json:"bio"`
+	Avatar         *mediaResponse `json:"avatar,omitempty"`
+	PinnedChirp    *chirpResponse `json:"pinned_chirp,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	FollowerCount  int64          `json:"follower_count"`
+	FollowingCount int64          `json:"following_count"`
+	// ChirpCount only counts chirps the viewer can see.
+	ChirpCount int64 `json:"chirp_count"`
+}
+
+// getProfileHandler shows a user's public profile by handle. A handle the
+// user changed away from recently redirects to their current one.
+func (cfg *apiConfig) getProfileHandler(w http.ResponseWriter, r *http.Request) {
+	handle := r.PathValue("handle")
+	viewerID := cfg.viewerID(r)
+
+	user, err := cfg.DB.GetUserByHandle(r.Context(), handle)
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.redirectOldHandle(w, r, handle)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load profile", err)
+		return
+	}
+
+	blocked, err := cfg.DB.IsBlockedBetween(r.Context(), database.IsBlockedBetweenParams{
+		UserA: viewerID,
+		UserB: user.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load profile", err)
+		return
+	}
+	if blocked {
+		respondWithError(w, http.StatusNotFound, "User not found", nil)
+		return
+	}
+
+	cfg.setFeedLinks(w, userFeedPath(user.ID, "atom"), userFeedPath(user.ID, "rss"), "Chirps by "+feedAuthorName(user))
+	cfg.respondWithProfile(w, r, viewerID, user)
+}
+
+// redirectOldHandle sends the client to the current handle of whoever
+// recently released handle, or responds 404 if no one did.
+func (cfg *apiConfig) redirectOldHandle(w http.ResponseWriter, r *http.Request, handle string) {
+	redirect, err := cfg.DB.GetHandleRedirect(r.Context(), database.GetHandleRedirectParams{
+		Handle: handle,
+		Since:  time.Now().UTC().Add(-handles.RedirectPeriod),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User not found", nil)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load profile", err)
+		return
+	}
+
+	http.Redirect(w, r, "/api/users/"+redirect.Handle.String, http.StatusMovedPermanently)
+}
+
+type profileRequest struct {
+	// Fields left out keep their current value. An empty avatar or pinned
+	// chirp ID clears it.
+	DisplayName   *string `json:"display_name"`
+	Bio           *string `json:"bio"`
+	AvatarMediaID *string `json:"avatar_media_id"`
+	PinnedChirpID *string `json:"pinned_chirp_id"`
+}
+
+// updateProfileHandler changes the caller's display name, bio, avatar and
+// pinned chirp. The avatar must be one of their uploads, and the pinned
+// chirp one of their own chirps.
+func (cfg *apiConfig) updateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	var req profileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	user, err := cfg.DB.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found", err)
+		return
+	}
+	if respondIfSuspended(w, user) {
+		return
+	}
+
+	params := database.UpdateProfileParams{
+		ID:            userID,
+		DisplayName:   user.DisplayName,
+		Bio:           user.Bio,
+		AvatarMediaID: user.AvatarMediaID,
+		PinnedChirpID: user.PinnedChirpID,
+	}
+
+	if req.DisplayName != nil {
+		params.DisplayName = strings.TrimSpace(*req.DisplayName)
+		if utf8.RuneCountInString(params.DisplayName) > maxDisplayName {
+			respondWithError(w, http.StatusBadRequest, "Display name is too long", nil)
+			return
+		}
+	}
+
+	if req.Bio != nil {
+		params.Bio = strings.TrimSpace(*req.Bio)
+		if utf8.RuneCountInString(params.Bio) > maxBio {
+			respondWithError(w, http.StatusBadRequest, "Bio is too long", nil)
+			return
+		}
+	}
+
+	if req.AvatarMediaID != nil {
+		params.AvatarMediaID = uuid.NullUUID{}
+		if *req.AvatarMediaID != "" {
+			mediaID, err := uuid.Parse(*req.AvatarMediaID)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid avatar media ID", err)
+				return
+			}
+			files, err := cfg.DB.GetMediaFilesByIDs(r.Context(), []uuid.UUID{mediaID})
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Couldn't update profile", err)
+				return
+			}
+			if len(files) == 0 || files[0].UserID != userID {
+				respondWithError(w, http.StatusBadRequest, "Avatar media not found", nil)
+				return
+			}
+			params.AvatarMediaID = uuid.NullUUID{UUID: mediaID, Valid: true}
+		}
+	}
+
+	if req.PinnedChirpID != nil {
+		params.PinnedChirpID = uuid.NullUUID{}
+		if *req.PinnedChirpID != "" {
+			chirpID, err := uuid.Parse(*req.PinnedChirpID)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid pinned chirp ID", err)
+				return
+			}
+			chirp, err := cfg.DB.GetChirp(r.Context(), chirpID)
+			if err != nil || chirp.UserID != userID || chirp.HiddenAt.Valid {
+				respondWithError(w, http.StatusBadRequest, "You can only pin your own chirps", err)
+				return
+			}
+			params.PinnedChirpID = uuid.NullUUID{UUID: chirpID, Valid: true}
+		}
+	}
+
+	user, err = cfg.DB.UpdateProfile(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update profile", err)
+		return
+	}
+
+	cfg.respondWithProfile(w, r, userID, user)
+}
+
+type handleRequest struct {
+	Handle string `json:"handle"`
+}
+
+// changeHandleHandler sets or changes the caller's handle. Their old handle
+// redirects to the new one, and stays theirs to reclaim, for
+// handles.RedirectPeriod. Changing only the case of a handle isn't
+// recorded as a change.
+func (cfg *apiConfig) changeHandleHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	var req handleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := handles.Validate(req.Handle); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	user, err := cfg.DB.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found", err)
+		return
+	}
+	if respondIfSuspended(w, user) {
+		return
+	}
+	if user.Handle.String == req.Handle {
+		cfg.respondWithProfile(w, r, userID, user)
+		return
+	}
+
+	renamed := user.Handle.Valid && handles.Normalize(user.Handle.String) != handles.Normalize(req.Handle)
+	since := time.Now().UTC().Add(-handles.RedirectPeriod)
+
+	if renamed {
+		history, err := cfg.DB.GetHandleHistory(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't change handle", err)
+			return
+		}
+		recent := 0
+		for _, h := range history {
+			if h.ReleasedAt.After(since) {
+				recent++
+			}
+		}
+		if recent >= maxHandleChanges {
+			respondWithError(w, http.StatusTooManyRequests, "You've changed your handle too often recently", nil)
+			return
+		}
+	}
+
+	reserved, err := cfg.DB.IsHandleReserved(r.Context(), database.IsHandleReservedParams{
+		Handle: req.Handle,
+		UserID: userID,
+		Since:  since,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't change handle", err)
+		return
+	}
+	if reserved {
+		respondWithError(w, http.StatusConflict, "That handle is taken", nil)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't change handle", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	if renamed {
+		err := qtx.RecordHandleChange(r.Context(), database.RecordHandleChangeParams{
+			UserID: userID,
+			Handle: user.Handle.String,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't change handle", err)
+			return
+		}
+	}
+
+	user, err = qtx.SetUserHandle(r.Context(), database.SetUserHandleParams{
+		ID:     userID,
+		Handle: sql.NullString{String: req.Handle, Valid: true},
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "That handle is taken", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't change handle", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't change handle", err)
+		return
+	}
+
+	cfg.respondWithProfile(w, r, userID, user)
+}
+
+type handleHistoryEntry struct {
+	Handle     string    `json:"handle"`
+	ReleasedAt time.Time `json:"released_at"`
+	// RedirectsUntil is when the handle stops pointing at the user and
+	// anyone can claim it.
+	RedirectsUntil time.Time `json:"redirects_until"`
+}
+
+// getHandleHistoryHandler lists the handles the caller has changed away
+// from, most recent first.
+func (cfg *apiConfig) getHandleHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	rows, err := cfg.DB.GetHandleHistory(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve handle history", err)
+		return
+	}
+
+	history := make([]handleHistoryEntry, 0, len(rows))
+	for _, row := range rows {
+		history = append(history, handleHistoryEntry{
+			Handle:         row.Handle,
+			ReleasedAt:     row.ReleasedAt,
+			RedirectsUntil: row.ReleasedAt.Add(handles.RedirectPeriod),
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, history)
+}
+
+type handleAvailability struct {
+	Handle    string `json:"handle"`
+	Available bool   `json:"available"`
+	// Reason says why an unavailable handle can't be used.
+	Reason string `json:"reason,omitempty"`
+}
+
+// handleAvailabilityHandler reports whether a handle can be claimed. When
+// the caller is signed in, their own current and recently released handles
+// count as available.
+func (cfg *apiConfig) handleAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	handle := r.PathValue("handle")
+	viewerID := cfg.viewerID(r)
+	response := handleAvailability{Handle: handle}
+
+	if err := handles.Validate(handle); err != nil {
+		response.Reason = err.Error()
+		respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+
+	owner, err := cfg.DB.GetUserByHandle(r.Context(), handle)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check handle", err)
+		return
+	}
+	if err == nil && owner.ID != viewerID {
+		response.Reason = "that handle is taken"
+		respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+
+	reserved, err := cfg.DB.IsHandleReserved(r.Context(), database.IsHandleReservedParams{
+		Handle: handle,
+		UserID: viewerID,
+		Since:  time.Now().UTC().Add(-handles.RedirectPeriod),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check handle", err)
+		return
+	}
+	if reserved {
+		response.Reason = "that handle was recently released and is still reserved"
+		respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+
+	response.Available = true
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// respondWithProfile writes user's profile as viewerID sees it. The pinned
+// chirp is left out if the viewer can't see it.
+func (cfg *apiConfig) respondWithProfile(w http.ResponseWriter, r *http.Request, viewerID uuid.UUID, user database.User) {
+	response := profileResponse{
+		ID:          user.ID,
+		Handle:      user.Handle.String,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		CreatedAt:   user.CreatedAt,
+	}
+
+	counts, err := cfg.DB.GetFollowCounts(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load profile", err)
+		return
+	}
+	response.FollowerCount = counts.FollowerCount
+	response.FollowingCount = counts.FollowingCount
+
+	response.ChirpCount, err = cfg.DB.CountVisibleChirpsByAuthor(r.Context(), database.CountVisibleChirpsByAuthorParams{
+		UserID:   user.ID,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load profile", err)
+		return
+	}
+
+	if user.AvatarMediaID.Valid {
+		files, err := cfg.DB.GetMediaFilesByIDs(r.Context(), []uuid.UUID{user.AvatarMediaID.UUID})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't load profile", err)
+			return
+		}
+		if len(files) > 0 {
+			avatar := cfg.toMediaResponse(files[0])
+			response.Avatar = &avatar
+		}
+	}
+
+	if user.PinnedChirpID.Valid {
+		pinned, err := cfg.DB.GetChirpsByIDs(r.Context(), database.GetChirpsByIDsParams{
+			Ids:      []uuid.UUID{user.PinnedChirpID.UUID},
+			ViewerID: viewerID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't load profile", err)
+			return
+		}
+		if len(pinned) > 0 {
+			chirp, err := cfg.buildChirpResponse(r.Context(), viewerID, pinned[0])
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Couldn't load profile", err)
+				return
+			}
+			response.PinnedChirp = &chirp
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
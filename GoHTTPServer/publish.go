@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/activitypub"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/entities"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/notify"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pipeline"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pubsub"
+)
+
+// chirpDraft is a chirp that has been validated and is ready to store.
+type chirpDraft struct {
+	Params   database.CreateChirpParams
+	MediaIDs []uuid.UUID
+	// Flags are reasons a moderator should look at the chirp once it's
+	// published.
+	Flags []pipeline.Flag
+	Poll  *pollDraft
+}
+
+// createChirp inserts a chirp along with its media attachments, poll,
+// review flags, and the hashtags, mentions and links found in its body.
+// Links are swapped for short links before the body is stored. Everything
+// is written in one transaction so a chirp is never visible half-built.
+func (cfg *apiConfig) createChirp(ctx context.Context, draft chirpDraft) (database.Chirp, error) {
+	tx, err := cfg.DBConn.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	defer tx.Rollback()
+
+	chirp, err := cfg.insertChirp(ctx, cfg.DB.WithTx(tx), draft)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	return chirp, tx.Commit()
+}
+
+// insertChirp does the work of createChirp in a transaction the caller
+// controls.
+func (cfg *apiConfig) insertChirp(ctx context.Context, qtx *database.Queries, draft chirpDraft) (database.Chirp, error) {
+	body, links, err := cfg.shortenLinks(draft.Params.Body)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	draft.Params.Body = body
+
+	chirp, err := qtx.CreateChirp(ctx, draft.Params)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := saveShortLinks(ctx, qtx, chirp, links); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if draft.Poll != nil {
+		if err := savePoll(ctx, qtx, chirp, draft.Poll); err != nil {
+			return database.Chirp{}, err
+		}
+	}
+
+	for i, mediaID := range draft.MediaIDs {
+		err := qtx.CreateChirpAttachment(ctx, database.CreateChirpAttachmentParams{
+			ChirpID:  chirp.ID,
+			MediaID:  mediaID,
+			Position: int32(i),
+		})
+		if err != nil {
+			return database.Chirp{}, err
+		}
+	}
+
+	if len(draft.Flags) > 0 {
+		if err := saveChirpFlags(ctx, qtx, chirp, draft.Flags); err != nil {
+			return database.Chirp{}, err
+		}
+	}
+
+	if err := saveChirpEntities(ctx, qtx, chirp); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := saveChirpLinks(ctx, qtx, chirp, links); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := notifyOriginalAuthor(ctx, qtx, chirp); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := notifyRepliedAuthor(ctx, qtx, chirp); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := recordChirpEvent(ctx, qtx, pubsub.ChirpCreated, chirp); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := cfg.federateChirp(ctx, qtx, activitypub.TypeCreate, chirp); err != nil {
+		return database.Chirp{}, err
+	}
+
+	return chirp, nil
+}
+
+// originalAuthor returns the author of the chirp that chirp rechirps or
+// quotes, if there is one and it still exists.
+func originalAuthor(ctx context.Context, q *database.Queries, chirp database.Chirp) (uuid.NullUUID, error) {
+	original := chirp.RechirpOf
+	if !original.Valid {
+		original = chirp.QuoteOf
+	}
+	if !original.Valid {
+		return uuid.NullUUID{}, nil
+	}
+
+	originalChirp, err := q.GetChirp(ctx, original.UUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.NullUUID{}, nil
+	}
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: originalChirp.UserID, Valid: true}, nil
+}
+
+// notifyOriginalAuthor lets the author of a rechirped or quoted chirp know.
+func notifyOriginalAuthor(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	author, err := originalAuthor(ctx, q, chirp)
+	if err != nil || !author.Valid {
+		return err
+	}
+
+	n := notification{
+		Recipient:    author.UUID,
+		Actor:        chirp.UserID,
+		ActorChirpID: chirp.ID,
+	}
+	if chirp.RechirpOf.Valid {
+		n.Type = notify.TypeRechirp
+		n.ChirpID = chirp.RechirpOf.UUID
+	} else {
+		n.Type = notify.TypeQuote
+		n.ChirpID = chirp.ID
+	}
+	return notifyUser(ctx, q, n)
+}
+
+// notifyRepliedAuthor lets the author of the chirp being replied to know.
+func notifyRepliedAuthor(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	if !chirp.ReplyTo.Valid {
+		return nil
+	}
+	original, err := q.GetChirp(ctx, chirp.ReplyTo.UUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return notifyUser(ctx, q, notification{
+		Recipient:    original.UserID,
+		Actor:        chirp.UserID,
+		Type:         notify.TypeReply,
+		ChirpID:      chirp.ID,
+		ActorChirpID: chirp.ID,
+	})
+}
+
+// recordChirpEvent adds an event to the chirp event log. It should be
+// called in the same transaction as the change it describes, so the event
+// is only streamed if the change is committed, and before a deleted chirp
+// is removed, so its mentions can still be read.
+func recordChirpEvent(ctx context.Context, q *database.Queries, eventType string, chirp database.Chirp) error {
+	hashtags, _ := entities.Extract(chirp.Body)
+	tags := make([]string, 0, len(hashtags))
+	for _, h := range hashtags {
+		tags = append(tags, h.Tag)
+	}
+
+	related, err := originalAuthor(ctx, q, chirp)
+	if err != nil {
+		return err
+	}
+
+	mentions, err := q.GetMentionEntities(ctx, []uuid.UUID{chirp.ID})
+	if err != nil {
+		return err
+	}
+	mentioned := make([]uuid.UUID, 0, len(mentions))
+	for _, m := range mentions {
+		mentioned = append(mentioned, m.UserID)
+	}
+
+	_, err = q.CreateChirpEvent(ctx, database.CreateChirpEventParams{
+		Type:            eventType,
+		ChirpID:         chirp.ID,
+		AuthorID:        chirp.UserID,
+		RelatedAuthorID: related,
+		Tags:            tags,
+		Visibility:      chirp.Visibility,
+		MentionedIds:    mentioned,
+	})
+	return err
+}
+
+// saveChirpFlags opens a moderation case for a chirp the content pipeline
+// flagged and attaches each flag to it.
+func saveChirpFlags(ctx context.Context, q *database.Queries, chirp database.Chirp, flags []pipeline.Flag) error {
+	modCase, err := q.OpenModerationCase(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, flag := range flags {
+		err := q.CreateChirpFlag(ctx, database.CreateChirpFlagParams{
+			ChirpID: chirp.ID,
+			CaseID:  uuid.NullUUID{UUID: modCase.ID, Valid: true},
+			Source:  flag.Stage,
+			Reason:  flag.Reason,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveChirpEntities stores the hashtags and mentions in a chirp's body.
+// Mentions that don't match a user's handle are left as plain text.
+func saveChirpEntities(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	hashtags, mentions := entities.Extract(chirp.Body)
+
+	for _, h := range hashtags {
+		hashtag, err := q.UpsertHashtag(ctx, h.Tag)
+		if err != nil {
+			return err
+		}
+		err = q.CreateChirpHashtag(ctx, database.CreateChirpHashtagParams{
+			ChirpID:     chirp.ID,
+			HashtagID:   hashtag.ID,
+			StartOffset: int32(h.Start),
+			EndOffset:   int32(h.End),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	usernames := make([]string, 0, len(mentions))
+	for _, m := range mentions {
+		usernames = append(usernames, m.Username)
+	}
+	users, err := q.GetUsersByMentionNames(ctx, usernames)
+	if err != nil {
+		return err
+	}
+
+	// Handles are unique regardless of case, so each name matches at most
+	// one user.
+	byName := make(map[string]uuid.UUID, len(users))
+	for _, u := range users {
+		byName[u.Username] = u.ID
+	}
+
+	for _, m := range mentions {
+		userID, ok := byName[m.Username]
+		if !ok {
+			continue
+		}
+		err := q.CreateChirpMention(ctx, database.CreateChirpMentionParams{
+			ChirpID:     chirp.ID,
+			UserID:      userID,
+			StartOffset: int32(m.Start),
+			EndOffset:   int32(m.End),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = notifyUser(ctx, q, notification{
+			Recipient:    userID,
+			Actor:        chirp.UserID,
+			Type:         notify.TypeMention,
+			ChirpID:      chirp.ID,
+			ActorChirpID: chirp.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -33,6 +33,15 @@ func (cfg *apiConfig) RefreshHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	user, err := cfg.DB.GetUser(r.Context(), refreshToken.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
+		return
+	}
+	if respondIfSuspended(w, user) {
+		return
+	}
+
 	jwtStr, err := auth.MakeJWT(refreshToken.UserID, cfg.JWTSecret, time.Hour)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Could not create JWT", err)
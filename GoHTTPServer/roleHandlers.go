@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+)
+
+type roleRequest struct {
+	Role string `json:"role"`
+}
+
+type roleResponse struct {
+	UserID uuid.UUID `json:"user_id"`
+	Role   string    `json:"role"`
+}
+
+// setUserRoleHandler lets an admin make someone a user, moderator or admin.
+// Admins can't change their own role, so the last one can't lock everyone
+// out by accident.
+func (cfg *apiConfig) setUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	admin, ok := cfg.requireRole(w, r, roleAdmin)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+	if userID == admin.ID {
+		respondWithError(w, http.StatusBadRequest, "You can't change your own role", nil)
+		return
+	}
+
+	var req roleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.Role != roleUser && req.Role != roleModerator && req.Role != roleAdmin {
+		respondWithError(w, http.StatusBadRequest, "Role must be user, moderator or admin", nil)
+		return
+	}
+
+	user, err := cfg.DB.SetUserRole(r.Context(), database.SetUserRoleParams{
+		ID:   userID,
+		Role: req.Role,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update role", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, roleResponse{UserID: user.ID, Role: user.Role})
+}
+
+// promoteAdmins makes the accounts with the given comma-separated emails
+// admins. It's how the first admin is created, since only admins can hand
+// out roles. Emails aren't verified at signup, so the accounts should be
+// registered before their emails are listed.
+func (cfg *apiConfig) promoteAdmins(ctx context.Context, emailList string) error {
+	var emails []string
+	for _, email := range strings.Split(emailList, ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	if len(emails) == 0 {
+		return nil
+	}
+
+	promoted, err := cfg.DB.PromoteAdmins(ctx, emails)
+	if err != nil {
+		return err
+	}
+	for _, email := range promoted {
+		log.Printf("Made %s an admin", email)
+	}
+	return nil
+}
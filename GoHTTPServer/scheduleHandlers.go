@@ -0,0 +1,477 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pagination"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pipeline"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/visibility"
+)
+
+const (
+	// schedulerInterval is how often each server instance looks for
+	// scheduled chirps that are due.
+	schedulerInterval = 15 * time.Second
+	// maxScheduleAhead is how far in the future a chirp can be scheduled.
+	maxScheduleAhead = 365 * 24 * time.Hour
+
+	scheduledStatusDraft     = "draft"
+	scheduledStatusScheduled = "scheduled"
+)
+
+type scheduledChirpResponse struct {
+	ID         uuid.UUID   `json:"id"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	Body       string      `json:"body"`
+	Visibility string      `json:"visibility"`
+	QuoteOf    *uuid.UUID  `json:"quote_of,omitempty"`
+	MediaIDs   []uuid.UUID `json:"media_ids"`
+	// ContentWarning and SensitiveMedia carry over to the published chirp.
+	ContentWarning string `json:"content_warning,omitempty"`
+	SensitiveMedia bool   `json:"sensitive_media"`
+	// Status is "draft" or "scheduled".
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	// Failure says why the last attempt to publish didn't work.
+	Failure string `json:"failure,omitempty"`
+}
+
+type scheduledChirpListResponse struct {
+	Chirps     []scheduledChirpResponse `json:"chirps"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+}
+
+func newScheduledChirpResponse(s database.ScheduledChirp) scheduledChirpResponse {
+	response := scheduledChirpResponse{
+		ID:         s.ID,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+		Body:       s.Body,
+		Visibility: s.Visibility,
+		QuoteOf:    nullUUIDPtr(s.QuoteOf),
+		MediaIDs:   s.MediaIds,
+		Status:     scheduledStatusDraft,
+		Failure:    s.Failure,
+
+		ContentWarning: s.ContentWarning,
+		SensitiveMedia: s.SensitiveMedia,
+	}
+	if response.MediaIDs == nil {
+		response.MediaIDs = []uuid.UUID{}
+	}
+	if s.PublishAt.Valid {
+		response.Status = scheduledStatusScheduled
+		response.PublishAt = &s.PublishAt.Time
+	}
+	return response
+}
+
+// checkPublishAt reports why t can't be used as a publish time.
+func checkPublishAt(t time.Time) error {
+	now := time.Now()
+	if !t.After(now) {
+		return errors.New("publish_at must be in the future")
+	}
+	if t.After(now.Add(maxScheduleAhead)) {
+		return errors.New("publish_at can be at most a year away")
+	}
+	return nil
+}
+
+// saveScheduledChirp stores a chirp from createChirpHandler as a draft, or
+// to be published at publishAt, instead of publishing it now.
+func (cfg *apiConfig) saveScheduledChirp(w http.ResponseWriter, r *http.Request, params database.CreateScheduledChirpParams) {
+	if params.PublishAt.Valid {
+		if err := checkPublishAt(params.PublishAt.Time); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		params.PublishAt.Time = params.PublishAt.Time.UTC()
+	}
+
+	scheduled, err := cfg.DB.CreateScheduledChirp(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, newScheduledChirpResponse(scheduled))
+}
+
+// getScheduledChirpsHandler lists the caller's drafts and scheduled
+// chirps, newest first. ?status=draft or ?status=scheduled narrows the
+// list to one kind.
+func (cfg *apiConfig) getScheduledChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, cursor, limit, ok := cfg.parseOwnListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != scheduledStatusDraft && status != scheduledStatusScheduled {
+		respondWithError(w, http.StatusBadRequest, "status must be draft or scheduled", nil)
+		return
+	}
+
+	rows, err := cfg.DB.GetScheduledChirps(r.Context(), database.GetScheduledChirpsParams{
+		UserID:          userID,
+		Status:          status,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
+	}
+
+	response := scheduledChirpListResponse{Chirps: make([]scheduledChirpResponse, 0, len(rows))}
+	for _, row := range rows {
+		response.Chirps = append(response.Chirps, newScheduledChirpResponse(row))
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		response.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+type scheduledChirpUpdate struct {
+	// Fields left out are unchanged. An empty quote_of stops quoting.
+	Body       *string    `json:"body"`
+	Visibility *string    `json:"visibility"`
+	QuoteOf    *string    `json:"quote_of"`
+	MediaIDs   *[]string  `json:"media_ids"`
+	PublishAt  *time.Time `json:"publish_at"`
+	// An empty content_warning removes the warning.
+	ContentWarning *string `json:"content_warning"`
+	SensitiveMedia *bool   `json:"sensitive_media"`
+	// Draft true turns a scheduled chirp back into a draft.
+	Draft bool `json:"draft"`
+}
+
+// updateScheduledChirpHandler edits a draft or scheduled chirp. Once the
+// scheduler has published it, it's gone and this responds 404.
+func (cfg *apiConfig) updateScheduledChirpHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	scheduledID, err := uuid.Parse(r.PathValue("scheduledID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid scheduled chirp ID", err)
+		return
+	}
+
+	var req scheduledChirpUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.Draft && req.PublishAt != nil {
+		respondWithError(w, http.StatusBadRequest, "A draft can't have a publish time", nil)
+		return
+	}
+
+	current, err := cfg.DB.GetScheduledChirpForUser(r.Context(), database.GetScheduledChirpForUserParams{
+		ID:     scheduledID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Scheduled chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+
+	params := database.UpdateScheduledChirpParams{
+		ID:         scheduledID,
+		UserID:     userID,
+		Body:       current.Body,
+		QuoteOf:    current.QuoteOf,
+		Visibility: current.Visibility,
+		MediaIds:   current.MediaIds,
+		PublishAt:  current.PublishAt,
+
+		ContentWarning: current.ContentWarning,
+		SensitiveMedia: current.SensitiveMedia,
+	}
+
+	if req.Body != nil {
+		content := &pipeline.Content{AuthorID: userID, Body: *req.Body}
+		if !cfg.runChirpPipeline(w, r, content) {
+			return
+		}
+		params.Body = content.Body
+	}
+
+	if req.Visibility != nil {
+		level, err := visibility.Parse(*req.Visibility)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Unknown visibility", err)
+			return
+		}
+		params.Visibility = string(level)
+	}
+
+	if req.ContentWarning != nil {
+		// Flags are worked out again when the chirp is published, so only
+		// the masked warning is kept here.
+		params.ContentWarning, err = cfg.parseContentWarning(*req.ContentWarning, &pipeline.Content{AuthorID: userID})
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+	if req.SensitiveMedia != nil {
+		params.SensitiveMedia = *req.SensitiveMedia
+	}
+
+	if req.QuoteOf != nil {
+		params.QuoteOf = uuid.NullUUID{}
+		if *req.QuoteOf != "" {
+			original, err := cfg.resolveOriginalChirp(r.Context(), userID, *req.QuoteOf)
+			if err != nil {
+				respondWithError(w, http.StatusNotFound, "Quoted chirp not found", err)
+				return
+			}
+			params.QuoteOf = uuid.NullUUID{UUID: original.ID, Valid: true}
+		}
+	}
+
+	if req.MediaIDs != nil {
+		params.MediaIds, err = cfg.parseChirpMedia(r.Context(), userID, *req.MediaIDs)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+
+	if req.Draft {
+		params.PublishAt = sql.NullTime{}
+	}
+	if req.PublishAt != nil {
+		if err := checkPublishAt(*req.PublishAt); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		params.PublishAt = sql.NullTime{Time: req.PublishAt.UTC(), Valid: true}
+	}
+
+	if params.QuoteOf.Valid && params.Body == "" {
+		respondWithError(w, http.StatusBadRequest, "Quote chirps need a body", nil)
+		return
+	}
+
+	// If the scheduler is publishing the chirp right now, this waits for it
+	// to finish and then finds the row gone.
+	updated, err := cfg.DB.UpdateScheduledChirp(r.Context(), params)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Scheduled chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newScheduledChirpResponse(updated))
+}
+
+// deleteScheduledChirpHandler cancels a scheduled chirp or throws away a
+// draft.
+func (cfg *apiConfig) deleteScheduledChirpHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	scheduledID, err := uuid.Parse(r.PathValue("scheduledID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid scheduled chirp ID", err)
+		return
+	}
+
+	deleted, err := cfg.DB.DeleteScheduledChirp(r.Context(), database.DeleteScheduledChirpParams{
+		ID:     scheduledID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete chirp", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Scheduled chirp not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runChirpScheduler publishes scheduled chirps as they fall due until ctx
+// is cancelled. Every instance runs one; they share the work through row
+// locks, and anything that came due while no instance was running is
+// published on the first pass.
+func (cfg *apiConfig) runChirpScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			published, err := cfg.publishNextScheduledChirp(ctx)
+			if err != nil {
+				log.Printf("Couldn't publish scheduled chirp: %v", err)
+				break
+			}
+			if !published {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scheduleFailure is one of the reasons a scheduled chirp can't be published
+// that its author has to fix, as opposed to errors worth retrying.
+type scheduleFailure struct {
+	reason string
+}
+
+func (e *scheduleFailure) Error() string { return e.reason }
+
+// publishNextScheduledChirp publishes the oldest due chirp no other
+// instance is already publishing. The row stays locked until the chirp is
+// created and the row deleted in the same transaction, so each scheduled
+// chirp is published once. It reports false when nothing was due.
+func (cfg *apiConfig) publishNextScheduledChirp(ctx context.Context) (bool, error) {
+	tx, err := cfg.DBConn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	scheduled, err := qtx.ClaimDueScheduledChirp(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = cfg.publishScheduledChirp(ctx, qtx, scheduled)
+	if err == nil {
+		return true, tx.Commit()
+	}
+
+	var failed *scheduleFailure
+	if !errors.As(err, &failed) && !isForeignKeyViolation(err) {
+		return false, fmt.Errorf("scheduled chirp %s: %w", scheduled.ID, err)
+	}
+
+	// The attempt may have left the transaction unusable, so the failure is
+	// recorded in a fresh one.
+	tx.Rollback()
+	if err := cfg.DB.FailScheduledChirp(ctx, database.FailScheduledChirpParams{
+		ID:      scheduled.ID,
+		Failure: publishFailure(err),
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func publishFailure(err error) string {
+	var failed *scheduleFailure
+	if errors.As(err, &failed) {
+		return failed.reason
+	}
+	return "A quoted chirp or attached media no longer exists"
+}
+
+// publishScheduledChirp creates the chirp for scheduled in qtx and removes
+// it from the schedule. The author's account, the quoted chirp and the
+// content pipeline are checked again, since they may have changed since
+// the chirp was written.
+func (cfg *apiConfig) publishScheduledChirp(ctx context.Context, qtx *database.Queries, scheduled database.ScheduledChirp) error {
+	user, err := qtx.GetUser(ctx, scheduled.UserID)
+	if err != nil {
+		return err
+	}
+	if user.SuspendedUntil.Valid && user.SuspendedUntil.Time.After(time.Now().UTC()) {
+		return &scheduleFailure{reason: "Your account was suspended when this chirp was due"}
+	}
+
+	if scheduled.QuoteOf.Valid {
+		if _, err := cfg.resolveOriginalChirp(ctx, scheduled.UserID, scheduled.QuoteOf.UUID.String()); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &scheduleFailure{reason: "The quoted chirp is no longer available"}
+			}
+			return err
+		}
+	}
+
+	content := &pipeline.Content{AuthorID: scheduled.UserID, Body: scheduled.Body}
+	err = cfg.Pipeline.Run(ctx, content)
+	var rejection *pipeline.Rejection
+	if errors.As(err, &rejection) {
+		return &scheduleFailure{reason: rejection.Message}
+	}
+	if err != nil {
+		return err
+	}
+	warning, err := cfg.parseContentWarning(scheduled.ContentWarning, content)
+	if err != nil {
+		return &scheduleFailure{reason: "Content warning rejected: " + err.Error()}
+	}
+
+	_, err = cfg.insertChirp(ctx, qtx, chirpDraft{
+		Params: database.CreateChirpParams{
+			Body:           content.Body,
+			UserID:         scheduled.UserID,
+			QuoteOf:        scheduled.QuoteOf,
+			Visibility:     scheduled.Visibility,
+			ContentWarning: warning,
+			SensitiveMedia: scheduled.SensitiveMedia,
+		},
+		MediaIDs: scheduled.MediaIds,
+		Flags:    content.Flags,
+	})
+	if err != nil {
+		return err
+	}
+
+	return qtx.RemovePublishedScheduledChirp(ctx, scheduled.ID)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pipeline"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pubsub"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/sensitive"
+)
+
+var errWarningBannedLanguage = errors.New("content warning contains banned language")
+
+type contentPreferences struct {
+	// SensitiveContent is "hide" to collapse sensitive chirps behind their
+	// warning, or "expand" to show them straight away.
+	SensitiveContent string `json:"sensitive_content"`
+}
+
+type chirpSensitivityRequest struct {
+	ContentWarning string `json:"content_warning"`
+	SensitiveMedia bool   `json:"sensitive_media"`
+	// Note is kept in the moderation audit log.
+	Note string `json:"note"`
+}
+
+// parseContentWarning checks a content warning written by a chirp's
+// author. It goes through the banned term filter like the body does.
+func (cfg *apiConfig) parseContentWarning(raw string, content *pipeline.Content) (string, error) {
+	warning, err := sensitive.Warning(raw)
+	if err != nil || warning == "" {
+		return warning, err
+	}
+	texts := []string{warning}
+	if !cfg.filterExtraText(content, "content warning", texts) {
+		return "", errWarningBannedLanguage
+	}
+	return texts[0], nil
+}
+
+// sensitivePreference returns how the viewer wants sensitive chirps shown.
+// Signed out viewers get the default.
+func (cfg *apiConfig) sensitivePreference(ctx context.Context, viewerID uuid.UUID) (sensitive.Preference, error) {
+	if viewerID == uuid.Nil {
+		return sensitive.Hide, nil
+	}
+	preference, err := cfg.DB.GetSensitiveContentPreference(ctx, viewerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sensitive.Hide, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return sensitive.Preference(preference), nil
+}
+
+func (cfg *apiConfig) getContentPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	preference, err := cfg.DB.GetSensitiveContentPreference(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve preferences", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, contentPreferences{SensitiveContent: preference})
+}
+
+func (cfg *apiConfig) updateContentPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	var req contentPreferences
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	preference, err := sensitive.ParsePreference(req.SensitiveContent)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "sensitive_content must be hide or expand", err)
+		return
+	}
+
+	saved, err := cfg.DB.SetSensitiveContentPreference(r.Context(), database.SetSensitiveContentPreferenceParams{
+		ID:               userID,
+		SensitiveContent: string(preference),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update preferences", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, contentPreferences{SensitiveContent: saved})
+}
+
+// setChirpSensitivityHandler lets a moderator add, change or remove a
+// chirp's content warning and sensitive media flag after it's posted.
+func (cfg *apiConfig) setChirpSensitivityHandler(w http.ResponseWriter, r *http.Request) {
+	moderator, ok := cfg.requireRole(w, r, roleModerator, roleAdmin)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	var req chirpSensitivityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	warning, err := sensitive.Warning(req.ContentWarning)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	chirp, err := qtx.SetChirpSensitivity(r.Context(), database.SetChirpSensitivityParams{
+		ID:             chirpID,
+		ContentWarning: warning,
+		SensitiveMedia: req.SensitiveMedia,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+
+	if !chirp.HiddenAt.Valid {
+		if err := recordChirpEvent(r.Context(), qtx, pubsub.ChirpUpdated, chirp); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+			return
+		}
+	}
+
+	note := fmt.Sprintf("chirp %s: content warning %q, sensitive media %t", chirp.ID, warning, req.SensitiveMedia)
+	if req.Note != "" {
+		note += ": " + req.Note
+	}
+	err = recordModerationAction(r.Context(), qtx, moderator.ID, auditChirpSensitivity, uuid.Nil, chirp.UserID, note)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+
+	response, err := cfg.buildChirpResponse(r.Context(), moderator.ID, chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
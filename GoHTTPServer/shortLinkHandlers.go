@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/chirptext"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/shortlink"
+)
+
+// shortLink is a URL from a chirp's body and the code that replaced it.
+type shortLink struct {
+	Code string
+	URL  string
+}
+
+type shortLinkStatsResponse struct {
+	Code          string             `json:"code"`
+	ShortURL      string             `json:"short_url"`
+	URL           string             `json:"url"`
+	Clicks        int64              `json:"clicks"`
+	LastClickedAt *time.Time         `json:"last_clicked_at"`
+	Blocked       bool               `json:"blocked"`
+	Referrers     []referrerResponse `json:"referrers"`
+}
+
+// referrerResponse counts clicks from one site. An empty Referrer is
+// direct visits.
+type referrerResponse struct {
+	Referrer string `json:"referrer"`
+	Clicks   int64  `json:"clicks"`
+}
+
+type blockLinkDomainRequest struct {
+	Domain string `json:"domain"`
+	Reason string `json:"reason"`
+}
+
+type blockedLinkDomainResponse struct {
+	Domain    string     `json:"domain"`
+	CreatedAt time.Time  `json:"created_at"`
+	BlockedBy *uuid.UUID `json:"blocked_by"`
+	Reason    string     `json:"reason"`
+}
+
+func (cfg *apiConfig) shortURL(code string) string {
+	return cfg.PublicURL + "/l/" + code
+}
+
+// shortenLinks replaces the URLs in body with short links. Links to this
+// server are left alone, and a URL repeated in the body shares one code.
+// URLs already count as a fixed length, so this never changes whether a
+// chirp fits.
+func (cfg *apiConfig) shortenLinks(body string) (string, []shortLink, error) {
+	base, err := url.Parse(cfg.PublicURL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var links []shortLink
+	codes := map[string]string{}
+	var codeErr error
+	shortened := chirptext.ReplaceURLs(body, func(link string) string {
+		if u, err := url.Parse(link); codeErr != nil || err != nil || strings.EqualFold(u.Host, base.Host) {
+			return link
+		}
+		if code, ok := codes[link]; ok {
+			return cfg.shortURL(code)
+		}
+		code, err := shortlink.NewCode()
+		if err != nil {
+			codeErr = err
+			return link
+		}
+		codes[link] = code
+		links = append(links, shortLink{Code: code, URL: link})
+		return cfg.shortURL(code)
+	})
+	if codeErr != nil {
+		return "", nil, codeErr
+	}
+	return shortened, links, nil
+}
+
+func saveShortLinks(ctx context.Context, q *database.Queries, chirp database.Chirp, links []shortLink) error {
+	for i, l := range links {
+		err := q.CreateShortLink(ctx, database.CreateShortLinkParams{
+			Code:     l.Code,
+			ChirpID:  chirp.ID,
+			Position: int32(i),
+			URL:      l.URL,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shortLinkRedirectHandler sends a short link's visitor on to where it
+// leads and counts the click. Links to blocked domains stop working.
+func (cfg *apiConfig) shortLinkRedirectHandler(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if !shortlink.ValidCode(code) {
+		respondWithError(w, http.StatusNotFound, "Link not found", nil)
+		return
+	}
+
+	link, err := cfg.DB.GetShortLinkByCode(r.Context(), code)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Link not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve link", err)
+		return
+	}
+
+	blocked, err := cfg.DB.IsLinkDomainBlocked(r.Context(), shortlink.Host(link.URL))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve link", err)
+		return
+	}
+	if blocked {
+		respondWithError(w, http.StatusGone, "This link has been blocked", nil)
+		return
+	}
+
+	// A click that fails to count still gets its redirect.
+	err = cfg.DB.RecordShortLinkClick(r.Context(), database.RecordShortLinkClickParams{
+		ID:       link.ID,
+		Referrer: shortlink.ReferrerHost(r.Referer()),
+	})
+	if err != nil {
+		log.Printf("Couldn't record click on %s: %v", link.Code, err)
+	}
+
+	// Not cacheable, so every click reaches the server and is counted.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	http.Redirect(w, r, link.URL, http.StatusFound)
+}
+
+// getChirpLinksHandler shows a chirp's author how its links are doing.
+func (cfg *apiConfig) getChirpLinksHandler(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	chirp, err := cfg.DB.GetChirp(r.Context(), chirpID)
+	if err != nil || chirp.HiddenAt.Valid {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "You can only see stats for your own chirps", nil)
+		return
+	}
+
+	links, err := cfg.DB.GetChirpShortLinks(r.Context(), chirp.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve links", err)
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(links))
+	for _, l := range links {
+		ids = append(ids, l.ID)
+	}
+	referrers, err := cfg.DB.GetShortLinkReferrers(r.Context(), ids)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve links", err)
+		return
+	}
+	referrersByLink := make(map[uuid.UUID][]referrerResponse, len(links))
+	for _, ref := range referrers {
+		referrersByLink[ref.ShortLinkID] = append(referrersByLink[ref.ShortLinkID], referrerResponse{
+			Referrer: ref.Referrer,
+			Clicks:   ref.Clicks,
+		})
+	}
+
+	response := make([]shortLinkStatsResponse, 0, len(links))
+	for _, l := range links {
+		blocked, err := cfg.DB.IsLinkDomainBlocked(r.Context(), shortlink.Host(l.URL))
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve links", err)
+			return
+		}
+		stats := shortLinkStatsResponse{
+			Code:      l.Code,
+			ShortURL:  cfg.shortURL(l.Code),
+			URL:       l.URL,
+			Clicks:    l.Clicks,
+			Blocked:   blocked,
+			Referrers: referrersByLink[l.ID],
+		}
+		if l.LastClickedAt.Valid {
+			stats.LastClickedAt = &l.LastClickedAt.Time
+		}
+		if stats.Referrers == nil {
+			stats.Referrers = []referrerResponse{}
+		}
+		response = append(response, stats)
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) getBlockedLinkDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireRole(w, r, roleAdmin); !ok {
+		return
+	}
+
+	rows, err := cfg.DB.GetBlockedLinkDomains(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve blocked domains", err)
+		return
+	}
+
+	domains := make([]blockedLinkDomainResponse, 0, len(rows))
+	for _, row := range rows {
+		domains = append(domains, toBlockedLinkDomainResponse(row))
+	}
+	respondWithJSON(w, http.StatusOK, domains)
+}
+
+// blockLinkDomainHandler stops short links to a domain, and its
+// subdomains, from redirecting. It applies to links already posted.
+func (cfg *apiConfig) blockLinkDomainHandler(w http.ResponseWriter, r *http.Request) {
+	admin, ok := cfg.requireRole(w, r, roleAdmin)
+	if !ok {
+		return
+	}
+
+	var req blockLinkDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	domain, err := shortlink.NormalizeDomain(req.Domain)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Domain must be a host name like example.com", err)
+		return
+	}
+
+	row, err := cfg.DB.BlockLinkDomain(r.Context(), database.BlockLinkDomainParams{
+		Domain:    domain,
+		BlockedBy: uuid.NullUUID{UUID: admin.ID, Valid: true},
+		Reason:    strings.TrimSpace(req.Reason),
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Domain is already blocked", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't block domain", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, toBlockedLinkDomainResponse(row))
+}
+
+func (cfg *apiConfig) unblockLinkDomainHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireRole(w, r, roleAdmin); !ok {
+		return
+	}
+
+	domain, err := shortlink.NormalizeDomain(r.PathValue("domain"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Blocked domain not found", err)
+		return
+	}
+
+	deleted, err := cfg.DB.UnblockLinkDomain(r.Context(), domain)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unblock domain", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Blocked domain not found", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toBlockedLinkDomainResponse(d database.BlockedLinkDomain) blockedLinkDomainResponse {
+	return blockedLinkDomainResponse{
+		Domain:    d.Domain,
+		CreatedAt: d.CreatedAt,
+		BlockedBy: nullUUIDPtr(d.BlockedBy),
+		Reason:    d.Reason,
+	}
+}
@@ -0,0 +1,41 @@
+-- name: GetBannedTerms :many
+SELECT * FROM banned_terms
+ORDER BY term ASC;
+
+-- name: GetBannedTermsVersion :one
+SELECT
+    COUNT(*) AS term_count,
+    COALESCE(MAX(updated_at), '1970-01-01')::timestamp AS last_updated_at
+FROM banned_terms;
+
+-- name: CreateBannedTerm :one
+INSERT INTO banned_terms (id, created_at, updated_at, term, action)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2
+)
+RETURNING *;
+
+-- name: UpdateBannedTerm :one
+UPDATE banned_terms
+SET action = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteBannedTerm :execrows
+DELETE FROM banned_terms
+WHERE id = $1;
+
+-- name: CreateChirpFlag :exec
+INSERT INTO chirp_flags (id, created_at, chirp_id, case_id, source, reason)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+);
@@ -0,0 +1,43 @@
+-- name: BlockUser :exec
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: RemoveFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = sqlc.arg(user_a) AND followee_id = sqlc.arg(user_b))
+OR (follower_id = sqlc.arg(user_b) AND followee_id = sqlc.arg(user_a));
+
+-- name: IsBlockedBetween :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = sqlc.arg(user_a) AND blocked_id = sqlc.arg(user_b))
+    OR (blocker_id = sqlc.arg(user_b) AND blocked_id = sqlc.arg(user_a))
+) AS blocked;
+
+-- name: GetBlockedUsers :many
+SELECT blocked_id AS user_id, created_at FROM blocks
+WHERE blocker_id = sqlc.arg(user_id)
+AND (created_at, blocked_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, blocked_id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: MuteUser :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetMutedUsers :many
+SELECT muted_id AS user_id, created_at FROM mutes
+WHERE muter_id = sqlc.arg(user_id)
+AND (created_at, muted_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, muted_id DESC
+LIMIT sqlc.arg(page_size);
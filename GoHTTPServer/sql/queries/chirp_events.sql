@@ -0,0 +1,56 @@
+-- name: CreateChirpEvent :one
+INSERT INTO chirp_events (created_at, type, chirp_id, author_id, related_author_id, tags, visibility, mentioned_ids)
+VALUES (
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+RETURNING *;
+
+-- name: GetChirpEvent :one
+SELECT * FROM chirp_events
+WHERE id = $1;
+
+-- name: GetChirpEventsAfter :many
+SELECT * FROM chirp_events
+WHERE id > sqlc.arg(after_id)
+ORDER BY id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetChirpEventResumeID :one
+-- IDs are taken when an event is written but become visible when its
+-- transaction commits, so an event with a lower ID can appear after
+-- after_id was seen. This returns the ID to replay from so that events
+-- written up to window_seconds before after_id are included again.
+SELECT COALESCE(MIN(id) - 1, sqlc.arg(after_id))::bigint AS resume_id
+FROM chirp_events
+WHERE id <= sqlc.arg(after_id)
+AND created_at >= (
+    SELECT created_at FROM chirp_events WHERE id = sqlc.arg(after_id)
+) - sqlc.arg(window_seconds)::float8 * INTERVAL '1 second';
+
+-- name: GetChirpEventBounds :one
+SELECT
+    COALESCE(MIN(id), 0)::bigint AS oldest_id,
+    COALESCE(MAX(id), 0)::bigint AS latest_id
+FROM chirp_events;
+
+-- name: DeleteChirpEventsBefore :exec
+DELETE FROM chirp_events
+WHERE created_at < $1;
+
+-- name: GetFollowingIDs :many
+SELECT followee_id FROM follows
+WHERE follower_id = $1;
+
+-- name: GetStreamExclusions :many
+SELECT blocked_id AS user_id FROM blocks WHERE blocker_id = sqlc.arg(viewer_id)
+UNION
+SELECT blocker_id FROM blocks WHERE blocked_id = sqlc.arg(viewer_id)
+UNION
+SELECT muted_id FROM mutes WHERE muter_id = sqlc.arg(viewer_id);
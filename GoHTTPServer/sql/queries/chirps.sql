@@ -1,27 +1,142 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of, quote_of, visibility, content_warning, sensitive_media, reply_to)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
 )
 RETURNING *;
 
 -- name: GetChirps :many
 SELECT * FROM chirps
+WHERE hidden_at IS NULL
+AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, sqlc.arg(viewer_id))
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg(viewer_id))
+    OR (blocks.blocker_id = sqlc.arg(viewer_id) AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg(viewer_id)
+    AND mutes.muted_id = chirps.user_id
+)
+AND (
+    sqlc.arg(sensitive)::text = 'include'
+    OR chirp_is_sensitive(chirps.content_warning, chirps.sensitive_media, chirps.rechirp_of) = (sqlc.arg(sensitive)::text = 'only')
+)
+AND (
+    sqlc.arg(content_warning)::text = ''
+    OR strpos(lower(chirp_shown_warning(chirps.content_warning, chirps.rechirp_of)), lower(sqlc.arg(content_warning)::text)) > 0
+)
 ORDER BY created_at ASC;
 
 -- name: GetChirp :one
 SELECT * FROM chirps
 WHERE id = $1;
 
+-- name: GetVisibleChirp :one
+SELECT * FROM chirps
+WHERE id = sqlc.arg(id)
+AND hidden_at IS NULL
+AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, sqlc.arg(viewer_id));
+
 -- name: DeleteChirp :exec
 DELETE FROM chirps
 WHERE id = $1;
 
 -- name: GetChirpsByAuthor :many
 SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+AND hidden_at IS NULL
+AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, sqlc.arg(viewer_id))
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg(viewer_id))
+    OR (blocks.blocker_id = sqlc.arg(viewer_id) AND blocks.blocked_id = chirps.user_id)
+)
+AND (
+    sqlc.arg(sensitive)::text = 'include'
+    OR chirp_is_sensitive(chirps.content_warning, chirps.sensitive_media, chirps.rechirp_of) = (sqlc.arg(sensitive)::text = 'only')
+)
+AND (
+    sqlc.arg(content_warning)::text = ''
+    OR strpos(lower(chirp_shown_warning(chirps.content_warning, chirps.rechirp_of)), lower(sqlc.arg(content_warning)::text)) > 0
+)
+ORDER BY created_at ASC;
+
+-- name: GetRecentPublicChirpsByAuthor :many
+-- An author's newest public chirps, leaving out rechirps, for feeds and
+-- the ActivityPub outbox.
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+AND hidden_at IS NULL
+AND visibility = 'public'
+AND rechirp_of IS NULL
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: CountPublicChirpsByAuthor :one
+SELECT COUNT(*) FROM chirps
 WHERE user_id = $1
-ORDER BY created_at ASC;
\ No newline at end of file
+AND hidden_at IS NULL
+AND visibility = 'public'
+AND rechirp_of IS NULL;
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::uuid[])
+AND hidden_at IS NULL
+AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, sqlc.arg(viewer_id))
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg(viewer_id))
+    OR (blocks.blocker_id = sqlc.arg(viewer_id) AND blocks.blocked_id = chirps.user_id)
+);
+
+-- name: GetExistingChirpIDs :many
+-- Returns which of the given chirps still exist, whoever can see them.
+SELECT id FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: GetRechirpsOf :many
+SELECT * FROM chirps
+WHERE rechirp_of = $1;
+
+-- name: GetRechirpCounts :many
+SELECT rechirp_of, COUNT(*) AS rechirp_count FROM chirps
+WHERE rechirp_of = ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY rechirp_of;
+
+-- name: GetTimeline :many
+SELECT chirps.* FROM chirps
+WHERE (
+    chirps.user_id = sqlc.arg(user_id)
+    OR chirps.user_id IN (
+        SELECT followee_id FROM follows
+        WHERE follower_id = sqlc.arg(user_id)
+    )
+)
+AND chirps.hidden_at IS NULL
+AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, sqlc.arg(user_id))
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg(user_id))
+    OR (blocks.blocker_id = sqlc.arg(user_id) AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg(user_id)
+    AND mutes.muted_id = chirps.user_id
+)
+AND (chirps.created_at, chirps.id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_size);
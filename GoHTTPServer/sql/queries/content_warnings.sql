@@ -0,0 +1,15 @@
+-- name: SetChirpSensitivity :one
+UPDATE chirps
+SET content_warning = $2, sensitive_media = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetSensitiveContentPreference :one
+SELECT sensitive_content FROM users
+WHERE id = $1;
+
+-- name: SetSensitiveContentPreference :one
+UPDATE users
+SET sensitive_content = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING sensitive_content;
@@ -0,0 +1,95 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, created_by, is_group, title, direct_key)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: GetDirectConversation :one
+SELECT * FROM conversations
+WHERE direct_key = $1;
+
+-- name: AddConversationMember :exec
+INSERT INTO conversation_members (conversation_id, user_id, joined_at)
+VALUES ($1, $2, NOW());
+
+-- name: GetConversationForMember :one
+SELECT conversations.* FROM conversations
+JOIN conversation_members m ON m.conversation_id = conversations.id
+WHERE conversations.id = sqlc.arg(id) AND m.user_id = sqlc.arg(user_id);
+
+-- name: GetConversations :many
+SELECT
+    conversations.*,
+    (
+        SELECT COUNT(*) FROM direct_messages d
+        WHERE d.conversation_id = conversations.id
+        AND d.sender_id <> sqlc.arg(user_id)
+        AND (m.last_read_at IS NULL OR d.created_at > m.last_read_at)
+    ) AS unread_count
+FROM conversations
+JOIN conversation_members m ON m.conversation_id = conversations.id
+WHERE m.user_id = sqlc.arg(user_id)
+AND (conversations.updated_at, conversations.id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY conversations.updated_at DESC, conversations.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetConversationMembers :many
+SELECT * FROM conversation_members
+WHERE conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+ORDER BY joined_at ASC, user_id ASC;
+
+-- name: CreateDirectMessage :one
+INSERT INTO direct_messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetDirectMessage :one
+SELECT * FROM direct_messages
+WHERE id = $1;
+
+-- name: GetDirectMessages :many
+SELECT direct_messages.* FROM direct_messages
+WHERE direct_messages.conversation_id = sqlc.arg(conversation_id)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.arg(viewer_id) AND b.blocked_id = direct_messages.sender_id)
+    OR (b.blocker_id = direct_messages.sender_id AND b.blocked_id = sqlc.arg(viewer_id))
+)
+AND (direct_messages.created_at, direct_messages.id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY direct_messages.created_at DESC, direct_messages.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_members
+SET last_read_message_id = sqlc.arg(message_id)::uuid, last_read_at = sqlc.arg(read_at)::timestamp
+WHERE conversation_id = sqlc.arg(conversation_id)
+AND user_id = sqlc.arg(user_id)
+AND (last_read_at IS NULL OR last_read_at < sqlc.arg(read_at)::timestamp);
+
+-- name: GetMessageRecipients :many
+SELECT m.user_id FROM conversation_members m
+WHERE m.conversation_id = sqlc.arg(conversation_id)
+AND m.user_id <> sqlc.arg(sender_id)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = m.user_id AND b.blocked_id = sqlc.arg(sender_id))
+    OR (b.blocker_id = sqlc.arg(sender_id) AND b.blocked_id = m.user_id)
+);
@@ -0,0 +1,144 @@
+-- name: GetActorKey :one
+SELECT * FROM actor_keys
+WHERE user_id = $1;
+
+-- name: CreateActorKey :exec
+INSERT INTO actor_keys (user_id, created_at, public_key_pem, private_key_pem)
+VALUES ($1, NOW(), $2, $3)
+ON CONFLICT (user_id) DO NOTHING;
+
+-- name: UpsertRemoteActor :one
+INSERT INTO remote_actors (id, created_at, updated_at, uri, username, domain, display_name, inbox_url, shared_inbox_url, key_id, public_key_pem)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+ON CONFLICT (uri) DO UPDATE
+SET username = EXCLUDED.username,
+    domain = EXCLUDED.domain,
+    display_name = EXCLUDED.display_name,
+    inbox_url = EXCLUDED.inbox_url,
+    shared_inbox_url = EXCLUDED.shared_inbox_url,
+    key_id = EXCLUDED.key_id,
+    public_key_pem = EXCLUDED.public_key_pem,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetRemoteActor :one
+SELECT * FROM remote_actors
+WHERE id = $1;
+
+-- name: GetRemoteActorByKeyID :one
+SELECT * FROM remote_actors
+WHERE key_id = $1
+ORDER BY updated_at DESC
+LIMIT 1;
+
+-- name: AddRemoteFollower :exec
+INSERT INTO remote_followers (user_id, actor_id, created_at, follow_uri)
+VALUES ($1, $2, NOW(), $3)
+ON CONFLICT (user_id, actor_id) DO UPDATE
+SET follow_uri = EXCLUDED.follow_uri;
+
+-- name: RemoveRemoteFollower :exec
+DELETE FROM remote_followers
+WHERE user_id = $1
+AND actor_id = $2;
+
+-- name: CountRemoteFollowers :one
+SELECT COUNT(*) FROM remote_followers
+WHERE user_id = $1;
+
+-- name: CreateRemoteFollow :exec
+INSERT INTO remote_follows (user_id, actor_id, created_at, follow_uri)
+VALUES ($1, $2, NOW(), $3)
+ON CONFLICT (user_id, actor_id) DO UPDATE
+SET follow_uri = EXCLUDED.follow_uri;
+
+-- name: AcceptRemoteFollow :execrows
+UPDATE remote_follows
+SET accepted_at = NOW()
+WHERE actor_id = $1
+AND follow_uri = $2;
+
+-- name: DeleteRemoteFollow :one
+DELETE FROM remote_follows
+WHERE user_id = $1
+AND actor_id = $2
+RETURNING follow_uri;
+
+-- name: GetRemoteFollows :many
+SELECT remote_actors.id, remote_actors.uri, remote_actors.username, remote_actors.domain, remote_actors.display_name,
+    remote_follows.created_at, remote_follows.accepted_at
+FROM remote_follows
+JOIN remote_actors ON remote_actors.id = remote_follows.actor_id
+WHERE remote_follows.user_id = $1
+ORDER BY remote_follows.created_at DESC;
+
+-- name: IsRemoteActorFollowed :one
+SELECT EXISTS (
+    SELECT 1 FROM remote_follows
+    WHERE actor_id = $1
+    AND accepted_at IS NOT NULL
+);
+
+-- name: CreateRemoteNote :exec
+INSERT INTO remote_notes (id, created_at, uri, actor_id, published_at, url, body)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5)
+ON CONFLICT (uri) DO NOTHING;
+
+-- name: DeleteRemoteNote :exec
+DELETE FROM remote_notes
+WHERE uri = $1
+AND actor_id = $2;
+
+-- name: GetRemoteNotesForUser :many
+SELECT remote_notes.id, remote_notes.created_at, remote_notes.uri, remote_notes.url, remote_notes.body, remote_notes.published_at,
+    remote_actors.uri AS actor_uri, remote_actors.username, remote_actors.domain, remote_actors.display_name
+FROM remote_notes
+JOIN remote_actors ON remote_actors.id = remote_notes.actor_id
+JOIN remote_follows ON remote_follows.actor_id = remote_notes.actor_id
+WHERE remote_follows.user_id = sqlc.arg(user_id)
+AND remote_follows.accepted_at IS NOT NULL
+AND (remote_notes.created_at, remote_notes.id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY remote_notes.created_at DESC, remote_notes.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: EnqueueDelivery :exec
+INSERT INTO federation_deliveries (id, created_at, user_id, inbox_url, activity, next_attempt_at)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, NOW());
+
+-- name: EnqueueFollowerDeliveries :exec
+INSERT INTO federation_deliveries (id, created_at, user_id, inbox_url, activity, next_attempt_at)
+SELECT gen_random_uuid(), NOW(), sqlc.arg(user_id), inboxes.inbox_url, sqlc.arg(activity), NOW()
+FROM (
+    SELECT DISTINCT COALESCE(NULLIF(remote_actors.shared_inbox_url, ''), remote_actors.inbox_url) AS inbox_url
+    FROM remote_followers
+    JOIN remote_actors ON remote_actors.id = remote_followers.actor_id
+    WHERE remote_followers.user_id = sqlc.arg(user_id)
+) AS inboxes;
+
+-- name: ClaimDueDelivery :one
+SELECT * FROM federation_deliveries
+WHERE next_attempt_at <= NOW()
+ORDER BY next_attempt_at ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: DeleteDelivery :exec
+DELETE FROM federation_deliveries
+WHERE id = $1;
+
+-- name: RetryDelivery :exec
+UPDATE federation_deliveries
+SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3
+WHERE id = $1;
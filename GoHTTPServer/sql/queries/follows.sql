@@ -0,0 +1,48 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollowers :many
+-- Leaves out users who have a block with the viewer either way, or whom
+-- the viewer has muted.
+SELECT follower_id AS user_id, created_at FROM follows
+WHERE followee_id = sqlc.arg(user_id)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = follows.follower_id AND blocks.blocked_id = sqlc.arg(viewer_id))
+    OR (blocks.blocker_id = sqlc.arg(viewer_id) AND blocks.blocked_id = follows.follower_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg(viewer_id) AND mutes.muted_id = follows.follower_id
+)
+AND (created_at, follower_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetFollowing :many
+-- Filtered for the viewer the same way as GetFollowers.
+SELECT followee_id AS user_id, created_at FROM follows
+WHERE follower_id = sqlc.arg(user_id)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = follows.followee_id AND blocks.blocked_id = sqlc.arg(viewer_id))
+    OR (blocks.blocker_id = sqlc.arg(viewer_id) AND blocks.blocked_id = follows.followee_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg(viewer_id) AND mutes.muted_id = follows.followee_id
+)
+AND (created_at, followee_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetFollowCounts :one
+SELECT
+    (SELECT COUNT(*) FROM follows WHERE followee_id = sqlc.arg(user_id)) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follower_id = sqlc.arg(user_id)) AS following_count;
@@ -0,0 +1,50 @@
+-- name: UpsertLinkPreview :one
+-- A preview fetched before stale_before is queued to be fetched again.
+INSERT INTO link_previews (id, created_at, updated_at, url, next_attempt_at)
+VALUES (gen_random_uuid(), NOW(), NOW(), sqlc.arg(url), NOW())
+ON CONFLICT (url) DO UPDATE
+SET status = 'pending', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+WHERE link_previews.status <> 'pending'
+  AND link_previews.fetched_at < sqlc.arg(stale_before)::timestamp
+RETURNING id;
+
+-- name: GetLinkPreviewID :one
+SELECT id FROM link_previews
+WHERE url = $1;
+
+-- name: CreateChirpLink :exec
+INSERT INTO chirp_links (chirp_id, position, link_preview_id)
+VALUES ($1, $2, $3);
+
+-- name: ClaimPendingLinkPreview :one
+SELECT * FROM link_previews
+WHERE status = 'pending' AND next_attempt_at <= NOW()
+ORDER BY next_attempt_at ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: SaveLinkPreview :exec
+UPDATE link_previews
+SET status = $2, title = $3, description = $4, image_url = $5, site_name = $6,
+    last_error = $7, fetched_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: RetryLinkPreview :exec
+UPDATE link_previews
+SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetChirpLinkPreviews :many
+SELECT chirp_links.chirp_id, link_previews.url, link_previews.title,
+       link_previews.description, link_previews.image_url, link_previews.site_name
+FROM chirp_links
+JOIN link_previews ON link_previews.id = chirp_links.link_preview_id
+WHERE chirp_links.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+  AND link_previews.status = 'ready'
+  AND NOT EXISTS (
+      -- No cards for destinations admins have blocked.
+      SELECT 1 FROM blocked_link_domains
+      WHERE split_part(split_part(link_previews.url, '/', 3), ':', 1) = domain
+         OR right(split_part(split_part(link_previews.url, '/', 3), ':', 1), length(domain) + 1) = '.' || domain
+  )
+ORDER BY chirp_links.chirp_id, chirp_links.position;
@@ -0,0 +1,62 @@
+-- name: CreateMediaFile :one
+INSERT INTO media_files (
+    id,
+    created_at,
+    user_id,
+    sha256,
+    content_type,
+    size_bytes,
+    width,
+    height,
+    storage_key,
+    thumbnail_key
+)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6, $7, $8
+)
+ON CONFLICT (user_id, sha256) DO UPDATE SET sha256 = EXCLUDED.sha256
+RETURNING *;
+
+-- name: GetMediaFilesByIDs :many
+SELECT * FROM media_files
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: CreateChirpAttachment :exec
+INSERT INTO chirp_attachments (chirp_id, media_id, position)
+VALUES ($1, $2, $3);
+
+-- name: GetChirpAttachments :many
+SELECT chirp_attachments.chirp_id, chirp_attachments.position, media_files.*
+FROM chirp_attachments
+JOIN media_files ON media_files.id = chirp_attachments.media_id
+WHERE chirp_attachments.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_attachments.chirp_id, chirp_attachments.position;
+
+-- name: CanViewMedia :one
+-- Whether the viewer may download a media file: their own upload, someone's
+-- avatar, or an attachment on a chirp they can read. Blocks in either
+-- direction hide avatars and attachments as they hide profiles and chirps.
+SELECT EXISTS (
+    SELECT 1 FROM media_files
+    WHERE media_files.id = sqlc.arg(media_id)
+    AND media_files.user_id = sqlc.arg(viewer_id)
+) OR EXISTS (
+    SELECT 1 FROM users
+    WHERE users.avatar_media_id = sqlc.arg(media_id)
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks
+        WHERE (blocks.blocker_id = users.id AND blocks.blocked_id = sqlc.arg(viewer_id))
+        OR (blocks.blocker_id = sqlc.arg(viewer_id) AND blocks.blocked_id = users.id)
+    )
+) OR EXISTS (
+    SELECT 1 FROM chirp_attachments
+    JOIN chirps ON chirps.id = chirp_attachments.chirp_id
+    WHERE chirp_attachments.media_id = sqlc.arg(media_id)
+    AND chirps.hidden_at IS NULL
+    AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, sqlc.arg(viewer_id))
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks
+        WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg(viewer_id))
+        OR (blocks.blocker_id = sqlc.arg(viewer_id) AND blocks.blocked_id = chirps.user_id)
+    )
+) AS visible;
@@ -0,0 +1,145 @@
+-- name: OpenModerationCase :one
+INSERT INTO moderation_cases (id, created_at, updated_at, chirp_id, status)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    'open'
+)
+ON CONFLICT (chirp_id) WHERE status <> 'resolved'
+DO UPDATE SET updated_at = NOW()
+RETURNING *;
+
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, case_id, chirp_id, reporter_id, reason, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: CountTrustedCaseReports :one
+-- Counts the reports on a case from accounts that joined before the cutoff
+-- and that the chirp's author hasn't blocked.
+SELECT COUNT(*) FROM reports
+JOIN users ON users.id = reports.reporter_id
+JOIN chirps ON chirps.id = reports.chirp_id
+WHERE reports.case_id = sqlc.arg(case_id)
+AND users.created_at < sqlc.arg(joined_before)::timestamp
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE blocks.blocker_id = chirps.user_id
+    AND blocks.blocked_id = reports.reporter_id
+);
+
+-- name: HideChirp :exec
+UPDATE chirps
+SET hidden_at = COALESCE(hidden_at, NOW())
+WHERE id = $1;
+
+-- name: UnhideChirp :exec
+UPDATE chirps
+SET hidden_at = NULL
+WHERE id = $1;
+
+-- name: GetModerationQueue :many
+SELECT
+    moderation_cases.*,
+    chirps.user_id AS author_id,
+    chirps.body,
+    (SELECT COUNT(*) FROM reports WHERE reports.case_id = moderation_cases.id) AS report_count,
+    (SELECT COUNT(*) FROM chirp_flags WHERE chirp_flags.case_id = moderation_cases.id) AS flag_count
+FROM moderation_cases
+JOIN chirps ON chirps.id = moderation_cases.chirp_id
+WHERE moderation_cases.status = sqlc.arg(status)
+ORDER BY moderation_cases.created_at ASC, moderation_cases.id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetModerationCase :one
+SELECT * FROM moderation_cases
+WHERE id = $1;
+
+-- name: GetCaseReports :many
+SELECT * FROM reports
+WHERE case_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetCaseFlags :many
+SELECT * FROM chirp_flags
+WHERE case_id = $1
+ORDER BY created_at ASC;
+
+-- name: ClaimModerationCase :one
+UPDATE moderation_cases
+SET status = 'claimed', claimed_by = sqlc.arg(moderator_id)::uuid, claimed_at = NOW(), updated_at = NOW()
+WHERE id = sqlc.arg(id)
+AND (status = 'open' OR (status = 'claimed' AND claimed_by = sqlc.arg(moderator_id)::uuid))
+RETURNING *;
+
+-- name: ResolveModerationCase :one
+UPDATE moderation_cases
+SET status = 'resolved', decision = sqlc.arg(decision)::text, resolved_at = NOW(), updated_at = NOW()
+WHERE id = sqlc.arg(id)
+AND status = 'claimed'
+AND claimed_by = sqlc.arg(moderator_id)::uuid
+RETURNING *;
+
+-- name: MarkCaseFlagsReviewed :exec
+UPDATE chirp_flags
+SET reviewed_at = NOW()
+WHERE case_id = $1
+AND reviewed_at IS NULL;
+
+-- name: CreateStrike :one
+INSERT INTO strikes (id, created_at, user_id, case_id, issued_by, reason, expires_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: CountActiveStrikes :one
+SELECT COUNT(*) FROM strikes
+WHERE user_id = $1
+AND expires_at > NOW();
+
+-- name: SuspendUser :one
+UPDATE users
+SET suspended_until = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UnsuspendUser :one
+UPDATE users
+SET suspended_until = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateModerationAction :exec
+INSERT INTO moderation_actions (id, created_at, moderator_id, action, case_id, target_user_id, note)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: GetModerationActions :many
+SELECT * FROM moderation_actions
+WHERE (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
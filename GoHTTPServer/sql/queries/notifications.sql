@@ -0,0 +1,80 @@
+-- name: UpsertNotification :one
+INSERT INTO notifications (id, created_at, updated_at, user_id, type, chirp_id, group_key)
+SELECT
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    sqlc.arg(user_id)::uuid,
+    sqlc.arg(type)::text,
+    sqlc.narg(chirp_id)::uuid,
+    sqlc.arg(group_key)::text
+WHERE NOT EXISTS (
+    SELECT 1 FROM notification_preferences p
+    WHERE p.user_id = sqlc.arg(user_id) AND p.type = sqlc.arg(type) AND NOT p.enabled
+)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.arg(user_id) AND b.blocked_id = sqlc.arg(actor_id))
+    OR (b.blocker_id = sqlc.arg(actor_id) AND b.blocked_id = sqlc.arg(user_id))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes m
+    WHERE m.muter_id = sqlc.arg(user_id) AND m.muted_id = sqlc.arg(actor_id)
+)
+ON CONFLICT (user_id, group_key) WHERE read_at IS NULL
+DO UPDATE SET updated_at = NOW()
+RETURNING *;
+
+-- name: AddNotificationActor :exec
+INSERT INTO notification_actors (notification_id, actor_id, chirp_id, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: CountNotificationActors :one
+SELECT COUNT(*) FROM notification_actors
+WHERE notification_id = $1;
+
+-- name: GetNotifications :many
+SELECT
+    notifications.*,
+    (SELECT COUNT(*) FROM notification_actors a WHERE a.notification_id = notifications.id) AS actor_count
+FROM notifications
+WHERE notifications.user_id = sqlc.arg(user_id)
+AND EXISTS (SELECT 1 FROM notification_actors a WHERE a.notification_id = notifications.id)
+AND (NOT sqlc.arg(unread_only)::bool OR notifications.read_at IS NULL)
+AND (notifications.updated_at, notifications.id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY notifications.updated_at DESC, notifications.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetNotificationActors :many
+SELECT notification_id, actor_id, chirp_id, created_at FROM notification_actors
+WHERE notification_id = ANY(sqlc.arg(notification_ids)::uuid[])
+ORDER BY created_at DESC;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications n
+WHERE n.user_id = $1
+AND n.read_at IS NULL
+AND EXISTS (SELECT 1 FROM notification_actors a WHERE a.notification_id = n.id);
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: GetNotificationPreferences :many
+SELECT * FROM notification_preferences
+WHERE user_id = $1;
+
+-- name: SetNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, type, enabled)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled;
+
+-- name: PublishUserEvent :exec
+SELECT pg_notify('user_events', sqlc.arg(payload)::text);
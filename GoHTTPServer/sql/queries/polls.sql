@@ -0,0 +1,49 @@
+-- name: CreatePoll :exec
+INSERT INTO polls (chirp_id, created_at, closes_at, multiple_choice, hide_results)
+VALUES ($1, NOW(), $2, $3, $4);
+
+-- name: CreatePollOption :exec
+INSERT INTO poll_options (chirp_id, position, text)
+VALUES ($1, $2, $3);
+
+-- name: GetPollForVote :one
+-- Locks the poll so a user's concurrent votes can't both get in.
+SELECT polls.*,
+       (SELECT COUNT(*) FROM poll_options
+        WHERE poll_options.chirp_id = polls.chirp_id)::int AS option_count
+FROM polls
+WHERE polls.chirp_id = $1
+FOR UPDATE;
+
+-- name: HasVotedInPoll :one
+SELECT EXISTS (
+    SELECT 1 FROM poll_votes
+    WHERE chirp_id = $1 AND user_id = $2
+);
+
+-- name: CreatePollVote :exec
+INSERT INTO poll_votes (chirp_id, position, user_id, created_at)
+VALUES ($1, $2, $3, NOW());
+
+-- name: GetPollsByChirpIDs :many
+SELECT polls.*,
+       (SELECT COUNT(DISTINCT poll_votes.user_id) FROM poll_votes
+        WHERE poll_votes.chirp_id = polls.chirp_id) AS voters
+FROM polls
+WHERE polls.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetPollOptionTallies :many
+SELECT poll_options.chirp_id, poll_options.position, poll_options.text,
+       COUNT(poll_votes.user_id) AS votes
+FROM poll_options
+LEFT JOIN poll_votes ON poll_votes.chirp_id = poll_options.chirp_id
+    AND poll_votes.position = poll_options.position
+WHERE poll_options.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY poll_options.chirp_id, poll_options.position
+ORDER BY poll_options.chirp_id, poll_options.position;
+
+-- name: GetViewerPollVotes :many
+SELECT chirp_id, position FROM poll_votes
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+  AND user_id = sqlc.arg(viewer_id)
+ORDER BY chirp_id, position;
@@ -0,0 +1,47 @@
+-- name: GetUserByHandle :one
+SELECT * FROM users
+WHERE lower(handle) = lower(sqlc.arg(handle));
+
+-- name: GetHandleRedirect :one
+SELECT users.id, users.handle FROM handle_history
+JOIN users ON users.id = handle_history.user_id
+WHERE lower(handle_history.handle) = lower(sqlc.arg(handle))
+AND handle_history.released_at > sqlc.arg(since)::timestamp
+AND users.handle IS NOT NULL
+ORDER BY handle_history.released_at DESC
+LIMIT 1;
+
+-- name: IsHandleReserved :one
+SELECT EXISTS (
+    SELECT 1 FROM handle_history
+    WHERE lower(handle) = lower(sqlc.arg(handle))
+    AND user_id <> sqlc.arg(user_id)
+    AND released_at > sqlc.arg(since)::timestamp
+) AS reserved;
+
+-- name: SetUserHandle :one
+UPDATE users
+SET handle = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: RecordHandleChange :exec
+INSERT INTO handle_history (id, user_id, handle, released_at)
+VALUES (gen_random_uuid(), $1, $2, NOW());
+
+-- name: GetHandleHistory :many
+SELECT handle, released_at FROM handle_history
+WHERE user_id = $1
+ORDER BY released_at DESC;
+
+-- name: UpdateProfile :one
+UPDATE users
+SET display_name = $2, bio = $3, avatar_media_id = $4, pinned_chirp_id = $5, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CountVisibleChirpsByAuthor :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+AND hidden_at IS NULL
+AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, sqlc.arg(viewer_id));
@@ -0,0 +1,62 @@
+-- name: CreateScheduledChirp :one
+INSERT INTO scheduled_chirps (id, created_at, updated_at, user_id, body, quote_of, visibility, media_ids, publish_at, content_warning, sensitive_media)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING *;
+
+-- name: GetScheduledChirps :many
+SELECT * FROM scheduled_chirps
+WHERE user_id = sqlc.arg(user_id)
+AND (
+    sqlc.arg(status)::text = ''
+    OR (sqlc.arg(status)::text = 'draft' AND publish_at IS NULL)
+    OR (sqlc.arg(status)::text = 'scheduled' AND publish_at IS NOT NULL)
+)
+AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetScheduledChirpForUser :one
+SELECT * FROM scheduled_chirps
+WHERE id = $1
+AND user_id = $2;
+
+-- name: UpdateScheduledChirp :one
+UPDATE scheduled_chirps
+SET body = $3, quote_of = $4, visibility = $5, media_ids = $6, publish_at = $7,
+    content_warning = $8, sensitive_media = $9, failure = '', updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+RETURNING *;
+
+-- name: DeleteScheduledChirp :execrows
+DELETE FROM scheduled_chirps
+WHERE id = $1
+AND user_id = $2;
+
+-- name: ClaimDueScheduledChirp :one
+SELECT * FROM scheduled_chirps
+WHERE publish_at <= NOW()
+ORDER BY publish_at ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: RemovePublishedScheduledChirp :exec
+DELETE FROM scheduled_chirps
+WHERE id = $1;
+
+-- name: FailScheduledChirp :exec
+UPDATE scheduled_chirps
+SET publish_at = NULL, failure = $2, updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,49 @@
+-- name: CreateShortLink :exec
+INSERT INTO short_links (id, created_at, code, chirp_id, position, url)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4);
+
+-- name: GetShortLinkByCode :one
+SELECT * FROM short_links
+WHERE code = $1;
+
+-- name: RecordShortLinkClick :exec
+WITH link AS (
+    UPDATE short_links
+    SET clicks = clicks + 1, last_clicked_at = NOW()
+    WHERE id = sqlc.arg(id)
+    RETURNING id
+)
+INSERT INTO short_link_referrers (short_link_id, referrer, clicks)
+SELECT link.id, sqlc.arg(referrer), 1 FROM link
+ON CONFLICT (short_link_id, referrer) DO UPDATE
+SET clicks = short_link_referrers.clicks + 1;
+
+-- name: GetChirpShortLinks :many
+SELECT * FROM short_links
+WHERE chirp_id = $1
+ORDER BY position ASC;
+
+-- name: GetShortLinkReferrers :many
+SELECT * FROM short_link_referrers
+WHERE short_link_id = ANY(sqlc.arg(short_link_ids)::uuid[])
+ORDER BY clicks DESC, referrer ASC;
+
+-- name: IsLinkDomainBlocked :one
+SELECT EXISTS (
+    SELECT 1 FROM blocked_link_domains
+    WHERE domain = sqlc.arg(host)::text
+       OR right(sqlc.arg(host)::text, length(domain) + 1) = '.' || domain
+);
+
+-- name: BlockLinkDomain :one
+INSERT INTO blocked_link_domains (domain, created_at, blocked_by, reason)
+VALUES ($1, NOW(), $2, $3)
+RETURNING *;
+
+-- name: GetBlockedLinkDomains :many
+SELECT * FROM blocked_link_domains
+ORDER BY domain ASC;
+
+-- name: UnblockLinkDomain :execrows
+DELETE FROM blocked_link_domains
+WHERE domain = $1;
@@ -0,0 +1,63 @@
+-- name: GetHashtagActivity :many
+-- Trends are the same for everyone, so only chirps anyone may read count:
+-- public ones that moderation hasn't hidden, as in the public feeds.
+SELECT
+    hashtags.tag,
+    COUNT(*) FILTER (WHERE chirps.created_at >= sqlc.arg(window_start)::timestamp) AS recent_count,
+    COALESCE(
+        SUM(EXP(-LN(2) * EXTRACT(EPOCH FROM (sqlc.arg(computed_at)::timestamp - chirps.created_at)) / sqlc.arg(half_life_seconds)::float8))
+        FILTER (WHERE chirps.created_at >= sqlc.arg(window_start)::timestamp),
+        0
+    )::float8 AS recent_weight,
+    COUNT(*) FILTER (WHERE chirps.created_at < sqlc.arg(window_start)::timestamp) AS baseline_count
+FROM chirp_hashtags
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirps.created_at >= sqlc.arg(baseline_start)::timestamp
+AND chirps.hidden_at IS NULL
+AND chirps.visibility = 'public'
+GROUP BY hashtags.tag
+HAVING COUNT(*) FILTER (WHERE chirps.created_at >= sqlc.arg(window_start)::timestamp) > 0;
+
+-- name: CreateTrendSnapshot :exec
+INSERT INTO trend_snapshots (id, window_name, computed_at, rank, tag, score, recent_count)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+);
+
+-- name: CreateTrendRun :exec
+INSERT INTO trend_runs (window_name, computed_at)
+VALUES ($1, $2);
+
+-- name: GetLatestTrendRun :one
+SELECT computed_at FROM trend_runs
+WHERE window_name = $1
+ORDER BY computed_at DESC
+LIMIT 1;
+
+-- name: GetTrendSnapshots :many
+SELECT * FROM trend_snapshots
+WHERE window_name = $1
+AND computed_at = $2
+ORDER BY rank ASC;
+
+-- name: GetLastTrendComputedAt :one
+SELECT COALESCE(MAX(computed_at), '1970-01-01')::timestamp AS last_computed_at
+FROM trend_runs;
+
+-- name: DeleteTrendSnapshotsBefore :exec
+DELETE FROM trend_snapshots
+WHERE computed_at < $1;
+
+-- name: DeleteTrendRunsBefore :exec
+DELETE FROM trend_runs
+WHERE computed_at < $1;
+
+-- name: TryTrendsLock :one
+SELECT pg_try_advisory_xact_lock(sqlc.arg(lock_id)::bigint) AS locked;
@@ -1,12 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
-
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -28,3 +28,56 @@ RETURNING *;
 UPDATE users SET is_chirpy_red = true, updated_at = NOW()
 WHERE id = $1
 RETURNING *;
+
+-- name: SetUserRole :one
+UPDATE users SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: PromoteAdmins :many
+-- Makes the users with the given emails admins, returning the emails of
+-- those who weren't already.
+UPDATE users SET role = 'admin', updated_at = NOW()
+WHERE email = ANY(sqlc.arg(emails)::text[])
+AND role <> 'admin'
+RETURNING email;
+
+-- name: GetUser :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: GetUsersByIDs :many
+SELECT * FROM users
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: UpdateUserEmail :one
+UPDATE users
+SET email = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateEmailChange :exec
+INSERT INTO email_changes (token_hash, user_id, new_email, created_at, expires_at)
+VALUES ($1, $2, $3, NOW(), $4);
+
+-- name: DeleteEmailChanges :exec
+DELETE FROM email_changes
+WHERE user_id = $1;
+
+-- name: TakeEmailChange :one
+DELETE FROM email_changes
+WHERE token_hash = $1
+RETURNING *;
+
+-- name: GetPendingEmail :one
+SELECT new_email FROM email_changes
+WHERE user_id = $1
+AND expires_at > NOW()
+ORDER BY created_at DESC
+LIMIT 1;
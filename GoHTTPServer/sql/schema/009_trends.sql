@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE trend_snapshots (
+    id UUID PRIMARY KEY,
+    window_name TEXT NOT NULL,
+    computed_at TIMESTAMP NOT NULL,
+    rank INTEGER NOT NULL,
+    tag TEXT NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    recent_count BIGINT NOT NULL
+);
+
+CREATE INDEX trend_snapshots_window_idx ON trend_snapshots (window_name, computed_at DESC);
+
+CREATE INDEX chirps_created_idx ON chirps (created_at);
+
+-- One row per window each time trends are computed, including runs that
+-- ranked nothing, so an empty run replaces the previous snapshot.
+CREATE TABLE trend_runs (
+    window_name TEXT NOT NULL,
+    computed_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (window_name, computed_at)
+);
+
+CREATE INDEX trend_runs_computed_idx ON trend_runs (computed_at);
+
+-- +goose Down
+DROP TABLE trend_runs;
+DROP INDEX chirps_created_idx;
+DROP TABLE trend_snapshots;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE media_files (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sha256 TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    storage_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    UNIQUE (user_id, sha256)
+);
+
+CREATE TABLE chirp_attachments (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    media_id UUID NOT NULL REFERENCES media_files(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (chirp_id, position),
+    UNIQUE (chirp_id, media_id)
+);
+
+-- +goose Down
+DROP TABLE chirp_attachments;
+DROP TABLE media_files;
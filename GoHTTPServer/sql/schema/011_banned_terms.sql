@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE banned_terms (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    term TEXT NOT NULL UNIQUE,
+    action TEXT NOT NULL CHECK (action IN ('mask', 'flag', 'reject'))
+);
+
+INSERT INTO banned_terms (id, created_at, updated_at, term, action)
+VALUES
+    (gen_random_uuid(), NOW(), NOW(), 'kerfuffle', 'mask'),
+    (gen_random_uuid(), NOW(), NOW(), 'sharbert', 'mask'),
+    (gen_random_uuid(), NOW(), NOW(), 'fornax', 'mask');
+
+CREATE TABLE chirp_flags (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    source TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    reviewed_at TIMESTAMP
+);
+
+CREATE INDEX chirp_flags_unreviewed_idx ON chirp_flags (created_at)
+WHERE reviewed_at IS NULL;
+
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+CHECK (role IN ('user', 'moderator', 'admin'));
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN role;
+
+DROP TABLE chirp_flags;
+DROP TABLE banned_terms;
@@ -0,0 +1,80 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN hidden_at TIMESTAMP;
+
+ALTER TABLE users
+ADD COLUMN suspended_until TIMESTAMP;
+
+CREATE TABLE moderation_cases (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    status TEXT NOT NULL CHECK (status IN ('open', 'claimed', 'resolved')),
+    claimed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    claimed_at TIMESTAMP,
+    resolved_at TIMESTAMP,
+    decision TEXT CHECK (decision IN ('dismiss', 'hide', 'remove'))
+);
+
+-- A chirp has at most one unresolved case; new reports and flags join it.
+CREATE UNIQUE INDEX moderation_cases_open_chirp_idx ON moderation_cases (chirp_id)
+WHERE status <> 'resolved';
+
+CREATE INDEX moderation_cases_status_idx ON moderation_cases (status, created_at);
+
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    case_id UUID NOT NULL REFERENCES moderation_cases(id) ON DELETE CASCADE,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL,
+    UNIQUE (chirp_id, reporter_id)
+);
+
+CREATE INDEX reports_case_idx ON reports (case_id);
+
+ALTER TABLE chirp_flags
+ADD COLUMN case_id UUID REFERENCES moderation_cases(id) ON DELETE CASCADE;
+
+CREATE TABLE strikes (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    case_id UUID REFERENCES moderation_cases(id) ON DELETE SET NULL,
+    issued_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX strikes_user_idx ON strikes (user_id, expires_at);
+
+CREATE TABLE moderation_actions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    moderator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    case_id UUID REFERENCES moderation_cases(id) ON DELETE SET NULL,
+    target_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT NOT NULL
+);
+
+CREATE INDEX moderation_actions_created_idx ON moderation_actions (created_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE moderation_actions;
+DROP TABLE strikes;
+
+ALTER TABLE chirp_flags
+DROP COLUMN case_id;
+
+DROP TABLE reports;
+DROP TABLE moderation_cases;
+
+ALTER TABLE users
+DROP COLUMN suspended_until;
+
+ALTER TABLE chirps
+DROP COLUMN hidden_at;
@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE chirp_events (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    type TEXT NOT NULL,
+    chirp_id UUID NOT NULL,
+    author_id UUID NOT NULL,
+    related_author_id UUID,
+    tags TEXT[] NOT NULL
+);
+
+CREATE INDEX chirp_events_created_idx ON chirp_events (created_at);
+
+-- Every server instance LISTENs on chirp_events. The notification is sent
+-- when the inserting transaction commits, so listeners never see an event
+-- that was rolled back.
+-- +goose StatementBegin
+CREATE FUNCTION notify_chirp_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('chirp_events', NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirp_events_notify
+AFTER INSERT ON chirp_events
+FOR EACH ROW EXECUTE FUNCTION notify_chirp_event();
+
+-- +goose Down
+DROP TRIGGER chirp_events_notify ON chirp_events;
+DROP FUNCTION notify_chirp_event();
+DROP TABLE chirp_events;
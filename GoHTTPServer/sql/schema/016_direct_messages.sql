@@ -0,0 +1,39 @@
+-- +goose Up
+CREATE TABLE conversations (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    is_group BOOLEAN NOT NULL,
+    title TEXT NOT NULL,
+    -- direct_key identifies a one-to-one conversation by its two members,
+    -- so there's only ever one per pair. It's NULL for groups.
+    direct_key TEXT UNIQUE
+);
+
+CREATE TABLE conversation_members (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joined_at TIMESTAMP NOT NULL,
+    last_read_message_id UUID,
+    last_read_at TIMESTAMP,
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE INDEX conversation_members_user_idx ON conversation_members (user_id);
+
+-- Messages live apart from chirps; they're never public.
+CREATE TABLE direct_messages (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    sender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL
+);
+
+CREATE INDEX direct_messages_conversation_idx ON direct_messages (conversation_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE direct_messages;
+DROP TABLE conversation_members;
+DROP TABLE conversations;
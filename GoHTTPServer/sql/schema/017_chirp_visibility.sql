@@ -0,0 +1,48 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+CHECK (visibility IN ('public', 'followers', 'mentioned', 'private'));
+
+-- chirp_visible_to is the one definition of who can read a chirp, used by
+-- every query that lists chirps. The author can always see their own;
+-- followers-only chirps are also shown to the users they mention. A nil
+-- viewer, for anonymous requests, only sees public chirps.
+-- +goose StatementBegin
+CREATE FUNCTION chirp_visible_to(chirp_id UUID, author_id UUID, visibility TEXT, viewer_id UUID)
+RETURNS BOOLEAN AS $$
+    SELECT visibility = 'public'
+    OR author_id = viewer_id
+    OR (
+        visibility IN ('followers', 'mentioned')
+        AND EXISTS (
+            SELECT 1 FROM chirp_mentions
+            WHERE chirp_mentions.chirp_id = chirp_visible_to.chirp_id
+            AND chirp_mentions.user_id = viewer_id
+        )
+    )
+    OR (
+        visibility = 'followers'
+        AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = viewer_id
+            AND follows.followee_id = author_id
+        )
+    );
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+-- Events keep the chirp's audience so streams can filter without loading
+-- the chirp, including after it's deleted.
+ALTER TABLE chirp_events
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public',
+ADD COLUMN mentioned_ids UUID[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE chirp_events
+DROP COLUMN mentioned_ids,
+DROP COLUMN visibility;
+
+DROP FUNCTION chirp_visible_to(UUID, UUID, TEXT, UUID);
+
+ALTER TABLE chirps
+DROP COLUMN visibility;
@@ -0,0 +1,34 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN handle TEXT,
+ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+ADD COLUMN avatar_media_id UUID REFERENCES media_files(id) ON DELETE SET NULL,
+ADD COLUMN pinned_chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL;
+
+-- Handles are unique regardless of case but stored as their owner typed them.
+-- Existing users have none until they pick one.
+CREATE UNIQUE INDEX users_handle_idx ON users (lower(handle));
+
+-- Handles a user has changed away from. They redirect to the user's current
+-- handle, and stay reserved for them, for a while after being released.
+CREATE TABLE handle_history (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    handle TEXT NOT NULL,
+    released_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX handle_history_handle_idx ON handle_history (lower(handle), released_at DESC);
+CREATE INDEX handle_history_user_idx ON handle_history (user_id, released_at DESC);
+
+-- +goose Down
+DROP TABLE handle_history;
+DROP INDEX users_handle_idx;
+
+ALTER TABLE users
+DROP COLUMN pinned_chirp_id,
+DROP COLUMN avatar_media_id,
+DROP COLUMN bio,
+DROP COLUMN display_name,
+DROP COLUMN handle;
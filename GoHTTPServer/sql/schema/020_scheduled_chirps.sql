@@ -0,0 +1,24 @@
+-- +goose Up
+-- Chirps written now to be published later, and drafts with no publish
+-- time yet. Publishing one deletes its row here in the same transaction
+-- that creates the chirp, so it happens exactly once.
+CREATE TABLE scheduled_chirps (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    quote_of UUID REFERENCES chirps(id) ON DELETE SET NULL,
+    visibility TEXT NOT NULL,
+    media_ids UUID[] NOT NULL,
+    publish_at TIMESTAMP,
+    -- failure says why publishing didn't work. The chirp goes back to
+    -- being a draft so its author can fix it.
+    failure TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX scheduled_chirps_due_idx ON scheduled_chirps (publish_at) WHERE publish_at IS NOT NULL;
+CREATE INDEX scheduled_chirps_user_idx ON scheduled_chirps (user_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE scheduled_chirps;
@@ -0,0 +1,85 @@
+-- +goose Up
+-- Each local user is an ActivityPub actor with its own signing key,
+-- created the first time it's needed.
+CREATE TABLE actor_keys (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    public_key_pem TEXT NOT NULL,
+    private_key_pem TEXT NOT NULL
+);
+
+-- Accounts on other servers, cached from their actor documents.
+CREATE TABLE remote_actors (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    uri TEXT NOT NULL UNIQUE,
+    username TEXT NOT NULL,
+    domain TEXT NOT NULL,
+    display_name TEXT NOT NULL DEFAULT '',
+    inbox_url TEXT NOT NULL,
+    shared_inbox_url TEXT NOT NULL DEFAULT '',
+    key_id TEXT NOT NULL,
+    public_key_pem TEXT NOT NULL
+);
+
+CREATE INDEX remote_actors_key_idx ON remote_actors (key_id);
+
+-- Remote accounts following local users.
+CREATE TABLE remote_followers (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_id UUID NOT NULL REFERENCES remote_actors(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    follow_uri TEXT NOT NULL,
+    PRIMARY KEY (user_id, actor_id)
+);
+
+-- Local users following remote accounts. accepted_at stays NULL until the
+-- remote server accepts the follow.
+CREATE TABLE remote_follows (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_id UUID NOT NULL REFERENCES remote_actors(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    follow_uri TEXT NOT NULL,
+    accepted_at TIMESTAMP,
+    PRIMARY KEY (user_id, actor_id)
+);
+
+CREATE INDEX remote_follows_actor_idx ON remote_follows (actor_id);
+
+-- Posts delivered by remote accounts that local users follow, stored as
+-- plain text.
+CREATE TABLE remote_notes (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    uri TEXT NOT NULL UNIQUE,
+    actor_id UUID NOT NULL REFERENCES remote_actors(id) ON DELETE CASCADE,
+    published_at TIMESTAMP NOT NULL,
+    url TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL
+);
+
+CREATE INDEX remote_notes_actor_idx ON remote_notes (actor_id, created_at DESC, id DESC);
+
+-- Activities waiting to be posted to remote inboxes, signed with user_id's
+-- key. Rows are retried with backoff until delivered or given up on.
+CREATE TABLE federation_deliveries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    inbox_url TEXT NOT NULL,
+    activity TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX federation_deliveries_due_idx ON federation_deliveries (next_attempt_at);
+
+-- +goose Down
+DROP TABLE federation_deliveries;
+DROP TABLE remote_notes;
+DROP TABLE remote_follows;
+DROP TABLE remote_followers;
+DROP TABLE remote_actors;
+DROP TABLE actor_keys;
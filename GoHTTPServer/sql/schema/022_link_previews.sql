@@ -0,0 +1,34 @@
+-- +goose Up
+-- Previews are cached per URL and shared by every chirp that links it.
+-- A pending preview is picked up by the background fetcher.
+CREATE TABLE link_previews (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'ready', 'failed', 'blocked')),
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    site_name TEXT NOT NULL DEFAULT '',
+    fetched_at TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX link_previews_pending_idx ON link_previews (next_attempt_at)
+    WHERE status = 'pending';
+
+-- The links in a chirp's body, in order.
+CREATE TABLE chirp_links (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    link_preview_id UUID NOT NULL REFERENCES link_previews(id) ON DELETE CASCADE,
+    PRIMARY KEY (chirp_id, position)
+);
+
+-- +goose Down
+DROP TABLE chirp_links;
+DROP TABLE link_previews;
@@ -0,0 +1,37 @@
+-- +goose Up
+-- Every URL in a chirp's body is replaced by a short link when the chirp
+-- is published. Links belong to the chirp so its author can see how each
+-- one is doing.
+CREATE TABLE short_links (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    code TEXT NOT NULL UNIQUE,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    last_clicked_at TIMESTAMP,
+    UNIQUE (chirp_id, position)
+);
+
+-- Clicks counted by referring site. An empty referrer is a direct visit.
+CREATE TABLE short_link_referrers (
+    short_link_id UUID NOT NULL REFERENCES short_links(id) ON DELETE CASCADE,
+    referrer TEXT NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (short_link_id, referrer)
+);
+
+-- Destinations admins have blocked. Short links to a blocked domain, or
+-- any of its subdomains, stop redirecting.
+CREATE TABLE blocked_link_domains (
+    domain TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    blocked_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL DEFAULT ''
+);
+
+-- +goose Down
+DROP TABLE blocked_link_domains;
+DROP TABLE short_link_referrers;
+DROP TABLE short_links;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- A chirp is sensitive when it has a content warning or its media is
+-- marked sensitive. Authors set these when posting; moderators can set
+-- them afterwards.
+ALTER TABLE chirps
+ADD COLUMN content_warning TEXT NOT NULL DEFAULT '',
+ADD COLUMN sensitive_media BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE scheduled_chirps
+ADD COLUMN content_warning TEXT NOT NULL DEFAULT '',
+ADD COLUMN sensitive_media BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Whether sensitive chirps start out collapsed for this user.
+ALTER TABLE users
+ADD COLUMN sensitive_content TEXT NOT NULL DEFAULT 'hide'
+    CHECK (sensitive_content IN ('hide', 'expand'));
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN sensitive_content;
+
+ALTER TABLE scheduled_chirps
+DROP COLUMN sensitive_media,
+DROP COLUMN content_warning;
+
+ALTER TABLE chirps
+DROP COLUMN sensitive_media,
+DROP COLUMN content_warning;
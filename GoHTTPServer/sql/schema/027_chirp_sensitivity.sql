@@ -0,0 +1,30 @@
+-- +goose Up
+-- A rechirp shows its original, so it is sensitive when the original is
+-- and carries the original's content warning. These let chirp listings
+-- filter on that at query time.
+-- +goose StatementBegin
+CREATE FUNCTION chirp_shown_warning(content_warning TEXT, rechirp_of UUID)
+RETURNS TEXT AS $$
+    SELECT COALESCE(
+        (SELECT originals.content_warning FROM chirps AS originals WHERE originals.id = chirp_shown_warning.rechirp_of),
+        chirp_shown_warning.content_warning
+    );
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION chirp_is_sensitive(content_warning TEXT, sensitive_media BOOLEAN, rechirp_of UUID)
+RETURNS BOOLEAN AS $$
+    SELECT chirp_is_sensitive.content_warning <> ''
+    OR chirp_is_sensitive.sensitive_media
+    OR EXISTS (
+        SELECT 1 FROM chirps AS originals
+        WHERE originals.id = chirp_is_sensitive.rechirp_of
+        AND (originals.content_warning <> '' OR originals.sensitive_media)
+    );
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION chirp_is_sensitive(TEXT, BOOLEAN, UUID);
+DROP FUNCTION chirp_shown_warning(TEXT, UUID);
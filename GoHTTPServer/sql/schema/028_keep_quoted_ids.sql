@@ -0,0 +1,12 @@
+-- +goose Up
+-- A quote keeps the id of the chirp it quotes after that chirp is deleted,
+-- so it can still be shown as quoting something rather than passing for a
+-- plain chirp.
+ALTER TABLE chirps DROP CONSTRAINT chirps_quote_of_fkey;
+
+-- +goose Down
+UPDATE chirps SET quote_of = NULL
+WHERE quote_of IS NOT NULL
+AND NOT EXISTS (SELECT 1 FROM chirps AS originals WHERE originals.id = chirps.quote_of);
+ALTER TABLE chirps ADD CONSTRAINT chirps_quote_of_fkey
+    FOREIGN KEY (quote_of) REFERENCES chirps(id) ON DELETE SET NULL;
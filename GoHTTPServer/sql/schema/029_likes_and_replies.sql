@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE chirp_likes (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, user_id)
+);
+
+-- Like quote_of, reply_to keeps the id of a deleted chirp so the reply
+-- still reads as one.
+ALTER TABLE chirps
+ADD COLUMN reply_to UUID;
+
+CREATE INDEX chirps_reply_to_idx ON chirps (reply_to);
+
+-- +goose Down
+DROP INDEX chirps_reply_to_idx;
+
+ALTER TABLE chirps
+DROP COLUMN reply_to;
+
+DROP TABLE chirp_likes;
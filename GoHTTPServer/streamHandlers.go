@@ -0,0 +1,576 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/entities"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pubsub"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/visibility"
+	"github.com/lib/pq"
+)
+
+const (
+	// chirpEventsChannel is the Postgres channel the chirp_events trigger
+	// notifies on.
+	chirpEventsChannel = "chirp_events"
+	// privateEventsChannel is the Postgres channel private events are
+	// sent on.
+	privateEventsChannel = "user_events"
+	// chirpEventRetention is how long events are kept for clients to
+	// resume from.
+	chirpEventRetention = 24 * time.Hour
+	// streamResumeWindow is how far before a resume point events are
+	// replayed again. An event's ID is taken inside its transaction, so one
+	// with a lower ID can commit after a higher one has been seen; this
+	// must be longer than any transaction that writes events.
+	streamResumeWindow = 30 * time.Second
+
+	streamBuffer       = 64
+	streamHeartbeat    = 15 * time.Second
+	streamWriteTimeout = 10 * time.Second
+	streamReplayPage   = 200
+)
+
+// runEventListener listens for new rows in chirp_events and publishes them
+// to cfg.Events until ctx is cancelled. Every instance runs its own
+// listener, so a chirp posted through one instance reaches streams on all
+// of them.
+func (cfg *apiConfig) runEventListener(ctx context.Context, dbURL string) {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(_ pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Chirp event listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	for _, channel := range []string{chirpEventsChannel, privateEventsChannel} {
+		if err := listener.Listen(channel); err != nil {
+			log.Printf("Couldn't listen on %s: %v", channel, err)
+			return
+		}
+	}
+
+	bounds, err := cfg.DB.GetChirpEventBounds(ctx)
+	if err != nil {
+		log.Printf("Couldn't load chirp events: %v", err)
+	}
+	lastID := bounds.LatestID
+	// seen holds the IDs published since the last catch-up point, so
+	// catching up again doesn't publish them twice.
+	seen := map[int64]bool{}
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case n := <-listener.Notify:
+			if n == nil {
+				// The connection was re-established, and anything sent
+				// while it was down is lost; fetch it from the table.
+				lastID = cfg.publishEventsAfter(ctx, lastID, seen)
+				continue
+			}
+			if n.Channel == privateEventsChannel {
+				cfg.deliverPrivateEvent(ctx, n.Extra)
+				continue
+			}
+			id, err := strconv.ParseInt(n.Extra, 10, 64)
+			if err != nil {
+				log.Printf("Bad chirp event notification %q: %v", n.Extra, err)
+				continue
+			}
+			if seen[id] {
+				continue
+			}
+			row, err := cfg.DB.GetChirpEvent(ctx, id)
+			if err != nil {
+				log.Printf("Couldn't load chirp event %d: %v", id, err)
+				continue
+			}
+			seen[id] = true
+			cfg.publishEvent(ctx, row)
+			// Transactions can commit out of order, so IDs are only used
+			// to know where to catch up from after a reconnect.
+			lastID = max(lastID, id)
+
+		case <-ping.C:
+			if err := listener.Ping(); err != nil {
+				log.Printf("Chirp event listener ping failed: %v", err)
+			}
+			// IDs below where a catch-up would start can be forgotten.
+			if from, err := cfg.resumeFrom(ctx, lastID); err == nil {
+				for id := range seen {
+					if id <= from {
+						delete(seen, id)
+					}
+				}
+			}
+
+		case <-prune.C:
+			err := cfg.DB.DeleteChirpEventsBefore(ctx, time.Now().UTC().Add(-chirpEventRetention))
+			if err != nil {
+				log.Printf("Couldn't prune chirp events: %v", err)
+			}
+		}
+	}
+}
+
+// resumeFrom returns the ID to replay events after when catching up from
+// lastID. It's up to streamResumeWindow earlier than lastID, to include
+// events that committed after lastID was seen.
+func (cfg *apiConfig) resumeFrom(ctx context.Context, lastID int64) (int64, error) {
+	return cfg.DB.GetChirpEventResumeID(ctx, database.GetChirpEventResumeIDParams{
+		AfterID:       lastID,
+		WindowSeconds: streamResumeWindow.Seconds(),
+	})
+}
+
+// publishEventsAfter publishes the stored events it hasn't yet published,
+// from resumeFrom(lastID) on, and returns the highest ID it went through.
+func (cfg *apiConfig) publishEventsAfter(ctx context.Context, lastID int64, seen map[int64]bool) int64 {
+	from, err := cfg.resumeFrom(ctx, lastID)
+	if err != nil {
+		log.Printf("Couldn't load chirp events: %v", err)
+		return lastID
+	}
+	for {
+		rows, err := cfg.DB.GetChirpEventsAfter(ctx, database.GetChirpEventsAfterParams{
+			AfterID:  from,
+			PageSize: streamReplayPage,
+		})
+		if err != nil {
+			log.Printf("Couldn't load chirp events: %v", err)
+			return lastID
+		}
+		for _, row := range rows {
+			from = row.ID
+			lastID = max(lastID, row.ID)
+			if seen[row.ID] {
+				continue
+			}
+			seen[row.ID] = true
+			cfg.publishEvent(ctx, row)
+		}
+		if len(rows) < streamReplayPage {
+			return lastID
+		}
+	}
+}
+
+func (cfg *apiConfig) publishEvent(ctx context.Context, row database.ChirpEvent) {
+	event, ok, err := cfg.loadStreamEvent(ctx, row)
+	if err != nil {
+		log.Printf("Couldn't render chirp event %d: %v", row.ID, err)
+		return
+	}
+	if ok {
+		cfg.Events.Publish(event)
+	}
+}
+
+// privateEvent is an event for particular users, passed between server
+// instances on privateEventsChannel. Unlike chirp events they aren't
+// stored, so clients reload over the REST API after reconnecting.
+type privateEvent struct {
+	Type       string      `json:"type"`
+	Recipients []uuid.UUID `json:"recipients"`
+	// MessageID names a direct message for each instance to load, rather
+	// than sending the body, since Postgres limits notifications to 8000
+	// bytes.
+	MessageID uuid.UUID `json:"message_id"`
+	Data      any       `json:"data,omitempty"`
+}
+
+// publishPrivateEvent sends an event to every server instance. Called in a
+// transaction, it's only sent if the transaction commits.
+func publishPrivateEvent(ctx context.Context, q *database.Queries, e privateEvent) error {
+	if len(e.Recipients) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return q.PublishUserEvent(ctx, string(payload))
+}
+
+// deliverPrivateEvent decodes a private event notification and publishes
+// it to cfg.Private.
+func (cfg *apiConfig) deliverPrivateEvent(ctx context.Context, payload string) {
+	var received struct {
+		Type       string          `json:"type"`
+		Recipients []uuid.UUID     `json:"recipients"`
+		MessageID  uuid.UUID       `json:"message_id"`
+		Data       json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(payload), &received); err != nil {
+		log.Printf("Bad private event notification: %v", err)
+		return
+	}
+
+	event := pubsub.Event{
+		Type:       received.Type,
+		CreatedAt:  time.Now().UTC(),
+		Recipients: received.Recipients,
+		Data:       received.Data,
+	}
+	if received.MessageID != uuid.Nil {
+		message, err := cfg.DB.GetDirectMessage(ctx, received.MessageID)
+		if err != nil {
+			log.Printf("Couldn't load message %s: %v", received.MessageID, err)
+			return
+		}
+		event.Data, err = json.Marshal(newMessageResponse(message))
+		if err != nil {
+			log.Printf("Couldn't render message %s: %v", received.MessageID, err)
+			return
+		}
+	}
+
+	cfg.Private.Publish(event)
+}
+
+// loadStreamEvent renders a stored event for streaming. It reports false
+// for events that shouldn't be sent, such as a created event for a chirp
+// that has since been deleted or hidden.
+func (cfg *apiConfig) loadStreamEvent(ctx context.Context, row database.ChirpEvent) (pubsub.Event, bool, error) {
+	event := pubsub.Event{
+		ID:              row.ID,
+		Type:            row.Type,
+		CreatedAt:       row.CreatedAt,
+		ChirpID:         row.ChirpID,
+		AuthorID:        row.AuthorID,
+		RelatedAuthorID: row.RelatedAuthorID.UUID,
+		Tags:            row.Tags,
+		Visibility:      row.Visibility,
+		MentionedIDs:    row.MentionedIds,
+	}
+
+	var data any
+	switch row.Type {
+	case pubsub.ChirpDeleted:
+		data = struct {
+			ID uuid.UUID `json:"id"`
+		}{row.ChirpID}
+	default:
+		chirp, err := cfg.DB.GetChirp(ctx, row.ChirpID)
+		if err != nil || chirp.HiddenAt.Valid {
+			return pubsub.Event{}, false, nil
+		}
+		response, err := cfg.buildChirpResponse(ctx, uuid.Nil, chirp)
+		if err != nil {
+			return pubsub.Event{}, false, err
+		}
+		data = response
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return pubsub.Event{}, false, err
+	}
+	event.Data = raw
+	return event, true, nil
+}
+
+// Control events sent alongside chirp events.
+const (
+	// streamReset means the client asked to resume from an event that has
+	// already been pruned, so it should reload over the REST API.
+	streamReset = "reset"
+	// streamOverflow means the client fell too far behind and was dropped.
+	streamOverflow = "overflow"
+)
+
+// streamOptions narrows a stream to the chirps a client asked for.
+type streamOptions struct {
+	AuthorID uuid.UUID
+	Tag      string
+	// Timeline limits the stream to the viewer and accounts they follow.
+	Timeline bool
+}
+
+// streamFilter builds the filter for a viewer's stream. Chirps the viewer
+// isn't allowed to see, and chirps by or rechirping users the viewer has
+// blocked, been blocked by, or muted, are always left out. Follows, blocks
+// and mutes are read once, when the stream opens.
+func (cfg *apiConfig) streamFilter(ctx context.Context, viewerID uuid.UUID, opts streamOptions) (pubsub.Filter, error) {
+	following := make(map[uuid.UUID]bool)
+	if viewerID != uuid.Nil {
+		ids, err := cfg.DB.GetFollowingIDs(ctx, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			following[id] = true
+		}
+	}
+
+	filters := []pubsub.Filter{func(e pubsub.Event) bool {
+		return visibility.Level(e.Visibility).CanView(visibility.Viewer{
+			IsAuthor:  e.AuthorID == viewerID,
+			Follows:   following[e.AuthorID],
+			Mentioned: viewerID != uuid.Nil && slices.Contains(e.MentionedIDs, viewerID),
+		})
+	}}
+
+	if opts.AuthorID != uuid.Nil {
+		filters = append(filters, func(e pubsub.Event) bool { return e.AuthorID == opts.AuthorID })
+	}
+
+	if opts.Tag != "" {
+		filters = append(filters, func(e pubsub.Event) bool { return slices.Contains(e.Tags, opts.Tag) })
+	}
+
+	if opts.Timeline {
+		filters = append(filters, func(e pubsub.Event) bool {
+			return e.AuthorID == viewerID || following[e.AuthorID]
+		})
+	}
+
+	if viewerID != uuid.Nil {
+		excluded, err := cfg.DB.GetStreamExclusions(ctx, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		if len(excluded) > 0 {
+			skip := make(map[uuid.UUID]bool, len(excluded))
+			for _, id := range excluded {
+				skip[id] = true
+			}
+			filters = append(filters, func(e pubsub.Event) bool {
+				return !skip[e.AuthorID] && !skip[e.RelatedAuthorID]
+			})
+		}
+	}
+
+	return func(e pubsub.Event) bool {
+		for _, f := range filters {
+			if !f(e) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// replayStream sends the stored events after resumeFrom(lastID) that match
+// filter. That includes events from up to streamResumeWindow before lastID
+// which the client may already have, so it isn't left with a gap when a
+// lower ID committed after it saw lastID; clients ignore IDs they've seen.
+// If lastID is older than anything still stored, it sends a reset event
+// first and nothing else. It returns the IDs it went through so the caller
+// can skip them when they also arrive live.
+func (cfg *apiConfig) replayStream(ctx context.Context, lastID int64, filter pubsub.Filter, send func(pubsub.Event) error) (map[int64]bool, error) {
+	replayed := make(map[int64]bool)
+
+	bounds, err := cfg.DB.GetChirpEventBounds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if lastID+1 < bounds.OldestID || lastID > bounds.LatestID {
+		return replayed, send(pubsub.Event{Type: streamReset, Data: json.RawMessage("{}")})
+	}
+
+	from, err := cfg.resumeFrom(ctx, lastID)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		rows, err := cfg.DB.GetChirpEventsAfter(ctx, database.GetChirpEventsAfterParams{
+			AfterID:  from,
+			PageSize: streamReplayPage,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			from = row.ID
+			replayed[row.ID] = true
+			event, ok, err := cfg.loadStreamEvent(ctx, row)
+			if err != nil {
+				log.Printf("Couldn't render chirp event %d: %v", row.ID, err)
+				continue
+			}
+			if !ok || !filter(event) {
+				continue
+			}
+			if err := send(event); err != nil {
+				return nil, err
+			}
+		}
+		if len(rows) < streamReplayPage {
+			return replayed, nil
+		}
+	}
+}
+
+// streamHandler streams chirp events as server-sent events. Clients can
+// narrow the stream with ?author=, ?tag= or ?timeline=true (chirps from
+// accounts the caller follows, which needs a token), and resume after a
+// dropped connection by sending the last event ID they saw in the
+// Last-Event-ID header. Resuming can repeat events from shortly before that
+// ID, which clients skip by ID. If that ID has already been pruned, a
+// "reset" event tells the client to reload over the REST API instead.
+//
+// A client that can't keep up is sent an "overflow" event and
+// disconnected; it should reconnect with Last-Event-ID to catch up.
+func (cfg *apiConfig) streamHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	viewerID := cfg.viewerID(r)
+
+	var opts streamOptions
+	if authorStr := query.Get("author"); authorStr != "" {
+		authorID, err := uuid.Parse(authorStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid author ID", err)
+			return
+		}
+		opts.AuthorID = authorID
+	}
+	if tagStr := query.Get("tag"); tagStr != "" {
+		opts.Tag = entities.NormalizeTag(tagStr)
+		if opts.Tag == "" {
+			respondWithError(w, http.StatusBadRequest, "Invalid tag", nil)
+			return
+		}
+	}
+	if query.Get("timeline") == "true" {
+		if viewerID == uuid.Nil {
+			respondWithError(w, http.StatusUnauthorized, "The timeline stream needs a valid token", nil)
+			return
+		}
+		opts.Timeline = true
+	}
+
+	lastIDStr := r.Header.Get("Last-Event-ID")
+	if lastIDStr == "" {
+		lastIDStr = query.Get("last_event_id")
+	}
+	var lastID int64
+	resume := lastIDStr != ""
+	if resume {
+		id, err := strconv.ParseInt(lastIDStr, 10, 64)
+		if err != nil || id < 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid Last-Event-ID", err)
+			return
+		}
+		lastID = id
+	}
+
+	filter, err := cfg.streamFilter(r.Context(), viewerID, opts)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't open stream", err)
+		return
+	}
+
+	if !cfg.Live.add() {
+		respondWithError(w, http.StatusServiceUnavailable, "Server is shutting down", nil)
+		return
+	}
+	defer cfg.Live.done()
+
+	// Subscribe before replaying so nothing published in between is
+	// missed; live events the replay already sent are skipped below.
+	sub := cfg.Events.Subscribe(streamBuffer, filter)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream := sseWriter{w: w, rc: http.NewResponseController(w)}
+	if err := stream.send("retry: 3000\n\n"); err != nil {
+		return
+	}
+
+	replayed := map[int64]bool{}
+	if resume {
+		replayed, err = cfg.replayStream(r.Context(), lastID, filter, stream.event)
+		if err != nil {
+			log.Printf("Couldn't replay chirp events: %v", err)
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-cfg.Live.draining():
+			// The client reconnects, with Last-Event-ID, to another
+			// instance.
+			return
+
+		case <-heartbeat.C:
+			if err := stream.send(": ping\n\n"); err != nil {
+				return
+			}
+
+		case event, ok := <-sub.Events():
+			if !ok {
+				if sub.Overflowed() {
+					stream.event(pubsub.Event{Type: streamOverflow, Data: json.RawMessage("{}")})
+				}
+				return
+			}
+			if replayed[event.ID] {
+				delete(replayed, event.ID)
+				continue
+			}
+			if err := stream.event(event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sseWriter writes server-sent events, flushing each one and giving up on
+// clients that stop reading.
+type sseWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (s sseWriter) send(frame string) error {
+	err := s.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if _, err := fmt.Fprint(s.w, frame); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// event sends an event. Control events have no ID, which leaves the
+// client's last event ID unchanged.
+func (s sseWriter) event(e pubsub.Event) error {
+	frame := ""
+	if e.ID != 0 {
+		frame += fmt.Sprintf("id: %d\n", e.ID)
+	}
+	frame += fmt.Sprintf("event: %s\ndata: %s\n\n", e.Type, e.Data)
+	return s.send(frame)
+}
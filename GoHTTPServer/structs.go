@@ -1,32 +1,161 @@
 package main
 
 import (
+	"database/sql"
+	"net/http"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/linkpreview"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/mail"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pipeline"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/profanity"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pubsub"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/ratelimit"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/storage"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	DB             *database.Queries
-	Platform       string
-	JWTSecret      string
-	PolkaKey       string
+	fileserverHits  atomic.Int32
+	DB              *database.Queries
+	DBConn          *sql.DB
+	Platform        string
+	JWTSecret       string
+	PolkaKey        string
+	PublicURL       string
+	Mail            mail.Sender
+	Media           storage.BlobStore
+	Profanity       atomic.Pointer[profanity.Filter]
+	Pipeline        *pipeline.Pipeline
+	MessagePipeline *pipeline.Pipeline
+	Events          *pubsub.Hub
+	Private         *pubsub.Hub
+	Live            *liveClients
+	HTTPClient      *http.Client
+	LinkPreviews    *linkpreview.Fetcher
+	// KeyFetchesPerHost and KeyFetches limit fetching unknown signing
+	// keys for the inbox.
+	KeyFetchesPerHost *ratelimit.Limiter
+	KeyFetches        *ratelimit.Limiter
 }
 
 type parameters struct {
-	Body   string `json:"body"`
-	UserID string `json:"user_id"`
+	Body      string   `json:"body"`
+	UserID    string   `json:"user_id"`
+	RechirpOf string   `json:"rechirp_of"`
+	QuoteOf   string   `json:"quote_of"`
+	ReplyTo   string   `json:"reply_to"`
+	MediaIDs  []string `json:"media_ids"`
+	// Visibility is one of the visibility levels; empty means public.
+	Visibility string `json:"visibility"`
+	// PublishAt schedules the chirp instead of publishing it now. Draft
+	// saves it without a publish time.
+	PublishAt *time.Time `json:"publish_at"`
+	Draft     bool       `json:"draft"`
+	// Poll attaches a poll to the chirp.
+	Poll *pollRequest `json:"poll"`
+	// ContentWarning is shown in place of the chirp until the viewer
+	// expands it. SensitiveMedia hides the attachments the same way.
+	ContentWarning string `json:"content_warning"`
+	SensitiveMedia bool   `json:"sensitive_media"`
+}
+
+type pollRequest struct {
+	Options        []string  `json:"options"`
+	ClosesAt       time.Time `json:"closes_at"`
+	MultipleChoice bool      `json:"multiple_choice"`
+	// HideResults keeps the tallies from people who haven't voted until
+	// the poll closes.
+	HideResults bool `json:"hide_results"`
 }
 
 type chirpResponse struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Body           string    `json:"body"`
+	UserID         uuid.UUID `json:"user_id"`
+	Visibility     string    `json:"visibility"`
+	ContentWarning string    `json:"content_warning"`
+	SensitiveMedia bool      `json:"sensitive_media"`
+	// Collapsed is whether the chirp should start out hidden behind its
+	// warning, following the viewer's sensitive content preference.
+	Collapsed      bool           `json:"collapsed"`
+	RechirpOf      *uuid.UUID     `json:"rechirp_of,omitempty"`
+	QuoteOf        *uuid.UUID     `json:"quote_of,omitempty"`
+	ReplyTo        *uuid.UUID     `json:"reply_to,omitempty"`
+	RechirpedChirp *chirpResponse `json:"rechirped_chirp,omitempty"`
+	QuotedChirp    *chirpResponse `json:"quoted_chirp,omitempty"`
+	// QuoteDeleted marks a quote whose original has been deleted, so it
+	// can be shown as "original deleted".
+	QuoteDeleted bool  `json:"quote_deleted,omitempty"`
+	RechirpCount int64 `json:"rechirp_count"`
+	LikeCount    int64 `json:"like_count"`
+	// Liked is whether the viewer has liked the chirp.
+	Liked    bool            `json:"liked"`
+	Entities chirpEntities   `json:"entities"`
+	Media    []mediaResponse `json:"media"`
+	// Previews are cards for the links in the body, once they've been
+	// fetched.
+	Previews []linkPreviewResponse `json:"previews"`
+	Poll     *pollResponse         `json:"poll,omitempty"`
+}
+
+type pollResponse struct {
+	ClosesAt       time.Time            `json:"closes_at"`
+	Closed         bool                 `json:"closed"`
+	MultipleChoice bool                 `json:"multiple_choice"`
+	HideResults    bool                 `json:"hide_results"`
+	Options        []pollOptionResponse `json:"options"`
+	// Voters and each option's Votes are null while the results are hidden
+	// from the viewer.
+	Voters *int64 `json:"voters"`
+	// OwnVotes are the indexes of the options the viewer picked, empty if
+	// they haven't voted.
+	OwnVotes []int32 `json:"own_votes"`
+}
+
+type pollOptionResponse struct {
+	Text  string `json:"text"`
+	Votes *int64 `json:"votes"`
+}
+
+type linkPreviewResponse struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	SiteName    string `json:"site_name"`
+}
+
+type mediaResponse struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	SizeBytes    int64     `json:"size_bytes"`
+}
+
+type chirpEntities struct {
+	Hashtags []hashtagEntity `json:"hashtags"`
+	Mentions []mentionEntity `json:"mentions"`
+}
+
+type hashtagEntity struct {
+	Tag   string `json:"tag"`
+	Start int32  `json:"start"`
+	End   int32  `json:"end"`
+}
+
+type mentionEntity struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+	Start    int32     `json:"start"`
+	End      int32     `json:"end"`
 }
 
 type returnVals struct {
@@ -36,6 +165,9 @@ type returnVals struct {
 type requestBody struct {
 	Password string `json:"password"`
 	Email    string `json:"email"`
+	// Handle is optional when signing up; users without one can pick it
+	// later.
+	Handle string `json:"handle"`
 }
 
 type loginRequestBody struct {
@@ -49,6 +181,7 @@ type loginResponseBody struct {
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	Email        string    `json:"email"`
+	Handle       string    `json:"handle,omitempty"`
 	IsChirpyRed  bool      `json:"is_chirpy_red"`
 	Token        string    `json:"token"`
 	RefreshToken string    `json:"refresh_token"`
@@ -59,6 +192,24 @@ type User struct {
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 	Email          string    `json:"email"`
+	Handle         string    `json:"handle,omitempty"`
 	IsChirpyRed    bool      `json:"is_chirpy_red"`
 	HashedPassword string    `json:"-"`
 }
+
+type followedUser struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type followListResponse struct {
+	Users          []followedUser `json:"users"`
+	FollowerCount  int64          `json:"follower_count"`
+	FollowingCount int64          `json:"following_count"`
+	NextCursor     string         `json:"next_cursor,omitempty"`
+}
+
+type chirpPageResponse struct {
+	Chirps     []chirpResponse `json:"chirps"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/entities"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pagination"
+)
+
+func (cfg *apiConfig) getTagChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	tag := entities.NormalizeTag(r.PathValue("tag"))
+	if tag == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid tag", nil)
+		return
+	}
+
+	cursor, err := pagination.Parse(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+		return
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	viewerID := cfg.viewerID(r)
+	chirpDB, err := cfg.DB.GetChirpsByTag(r.Context(), database.GetChirpsByTagParams{
+		Tag:             tag,
+		ViewerID:        viewerID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
+	}
+
+	cfg.setFeedLinks(w, tagFeedPath(tag, "atom"), tagFeedPath(tag, "rss"), "#"+tag+" on Chirpy")
+	cfg.respondWithChirpPage(w, r, viewerID, chirpDB, limit)
+}
+
+func (cfg *apiConfig) getMentionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	cursor, err := pagination.Parse(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+		return
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	viewerID := cfg.viewerID(r)
+	chirpDB, err := cfg.DB.GetChirpsMentioningUser(r.Context(), database.GetChirpsMentioningUserParams{
+		UserID:          userID,
+		ViewerID:        viewerID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
+	}
+
+	cfg.respondWithChirpPage(w, r, viewerID, chirpDB, limit)
+}
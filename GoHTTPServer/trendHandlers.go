@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/trends"
+)
+
+const (
+	trendsInterval  = 5 * time.Minute
+	trendsLimit     = 10
+	trendsRetention = 7 * 24 * time.Hour
+
+	// trendsLockID names the Postgres advisory lock that stops several
+	// server instances from computing the same snapshot at once.
+	trendsLockID int64 = 0x63687270
+)
+
+type trendResponse struct {
+	Rank        int32   `json:"rank"`
+	Tag         string  `json:"tag"`
+	Score       float64 `json:"score"`
+	RecentCount int64   `json:"recent_count"`
+}
+
+type trendWindowResponse struct {
+	Window     string          `json:"window"`
+	ComputedAt *time.Time      `json:"computed_at"`
+	Trends     []trendResponse `json:"trends"`
+}
+
+// runTrendAggregator recomputes trending tags every trendsInterval until ctx
+// is cancelled.
+func (cfg *apiConfig) runTrendAggregator(ctx context.Context) {
+	ticker := time.NewTicker(trendsInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := cfg.computeTrends(ctx); err != nil {
+			log.Printf("Couldn't compute trends: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// computeTrends stores a new snapshot for every window. It runs inside a
+// transaction holding an advisory lock, and skips the work if another
+// instance already took a snapshot during this interval.
+func (cfg *apiConfig) computeTrends(ctx context.Context) error {
+	tx, err := cfg.DBConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	locked, err := qtx.TryTrendsLock(ctx, trendsLockID)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+
+	now := time.Now().UTC()
+
+	last, err := qtx.GetLastTrendComputedAt(ctx)
+	if err != nil {
+		return err
+	}
+	if now.Sub(last) < trendsInterval/2 {
+		return nil
+	}
+
+	for _, window := range trends.DefaultWindows {
+		rows, err := qtx.GetHashtagActivity(ctx, database.GetHashtagActivityParams{
+			WindowStart:     now.Add(-window.Length),
+			ComputedAt:      now,
+			HalfLifeSeconds: window.HalfLife().Seconds(),
+			BaselineStart:   now.Add(-trends.BaselinePeriod),
+		})
+		if err != nil {
+			return err
+		}
+
+		activity := make([]trends.TagActivity, 0, len(rows))
+		for _, row := range rows {
+			activity = append(activity, trends.TagActivity{
+				Tag:           row.Tag,
+				RecentCount:   row.RecentCount,
+				RecentWeight:  row.RecentWeight,
+				BaselineCount: row.BaselineCount,
+			})
+		}
+
+		// The run is recorded even when nothing ranks, so readers stop
+		// seeing the previous run's trends.
+		err = qtx.CreateTrendRun(ctx, database.CreateTrendRunParams{
+			WindowName: window.Name,
+			ComputedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+
+		for i, trend := range trends.Rank(activity, window, trendsLimit) {
+			err := qtx.CreateTrendSnapshot(ctx, database.CreateTrendSnapshotParams{
+				WindowName:  window.Name,
+				ComputedAt:  now,
+				Rank:        int32(i + 1),
+				Tag:         trend.Tag,
+				Score:       trend.Score,
+				RecentCount: trend.RecentCount,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := qtx.DeleteTrendSnapshotsBefore(ctx, now.Add(-trendsRetention)); err != nil {
+		return err
+	}
+	if err := qtx.DeleteTrendRunsBefore(ctx, now.Add(-trendsRetention)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (cfg *apiConfig) getTrendsHandler(w http.ResponseWriter, r *http.Request) {
+	windows := trends.DefaultWindows
+	if name := r.URL.Query().Get("window"); name != "" {
+		windows = nil
+		for _, window := range trends.DefaultWindows {
+			if window.Name == name {
+				windows = append(windows, window)
+			}
+		}
+		if len(windows) == 0 {
+			respondWithError(w, http.StatusBadRequest, "Unknown trends window", nil)
+			return
+		}
+	}
+
+	response := make([]trendWindowResponse, 0, len(windows))
+	for _, window := range windows {
+		windowResponse := trendWindowResponse{
+			Window: window.Name,
+			Trends: []trendResponse{},
+		}
+
+		computedAt, err := cfg.DB.GetLatestTrendRun(r.Context(), window.Name)
+		if errors.Is(err, sql.ErrNoRows) {
+			response = append(response, windowResponse)
+			continue
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve trends", err)
+			return
+		}
+		windowResponse.ComputedAt = &computedAt
+
+		snapshots, err := cfg.DB.GetTrendSnapshots(r.Context(), database.GetTrendSnapshotsParams{
+			WindowName: window.Name,
+			ComputedAt: computedAt,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve trends", err)
+			return
+		}
+		for _, s := range snapshots {
+			windowResponse.Trends = append(windowResponse.Trends, trendResponse{
+				Rank:        s.Rank,
+				Tag:         s.Tag,
+				Score:       s.Score,
+				RecentCount: s.RecentCount,
+			})
+		}
+		response = append(response, windowResponse)
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
 	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/database"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/handles"
 )
 
 func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -19,6 +22,28 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	var handle sql.NullString
+	if req.Handle != "" {
+		if err := handles.Validate(req.Handle); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		reserved, err := cfg.DB.IsHandleReserved(r.Context(), database.IsHandleReservedParams{
+			Handle: req.Handle,
+			UserID: uuid.Nil,
+			Since:  time.Now().UTC().Add(-handles.RedirectPeriod),
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "could not create user", err)
+			return
+		}
+		if reserved {
+			respondWithError(w, http.StatusConflict, "That handle is taken", nil)
+			return
+		}
+		handle = sql.NullString{String: req.Handle, Valid: true}
+	}
+
 	hashedPassword, err := auth.HashPassword(req.Password)
 
 	if err != nil {
@@ -29,8 +54,13 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 	user, err := cfg.DB.CreateUser(r.Context(), database.CreateUserParams{
 		Email:          req.Email,
 		HashedPassword: hashedPassword,
+		Handle:         handle,
 	})
 
+	if isUniqueViolationOf(err, "users_handle_idx") {
+		respondWithError(w, http.StatusConflict, "That handle is taken", err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "could not create user", err)
 		return
@@ -41,6 +71,7 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 		CreatedAt:   user.CreatedAt,
 		UpdatedAt:   user.UpdatedAt,
 		Email:       user.Email,
+		Handle:      user.Handle.String,
 		IsChirpyRed: user.IsChirpyRed,
 	}
 
@@ -68,32 +99,13 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	expiresIn := time.Hour
-
-	token, err := auth.MakeJWT(user.ID, cfg.JWTSecret, expiresIn)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "could not generate token", err)
-		return
-	}
-
-	refreshToken, err := auth.MakeRefreshToken()
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "could not generate refresh token", err)
+	if respondIfSuspended(w, user) {
 		return
 	}
 
-	refreshTokenParams := database.CreateRefreshTokenParams{
-		Token:     refreshToken,
-		UserID:    user.ID,
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-		ExpiresAt: time.Now().UTC().Add(60 * 24 * time.Hour),
-		RevokedAt: sql.NullTime{Valid: false},
-	}
-
-	err = cfg.DB.CreateRefreshToken(r.Context(), refreshTokenParams)
+	token, refreshToken, err := cfg.startSession(r.Context(), user.ID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "could not save refresh token", err)
+		respondWithError(w, http.StatusInternalServerError, "could not start session", err)
 		return
 	}
 
@@ -102,6 +114,7 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		CreatedAt:    user.CreatedAt,
 		UpdatedAt:    user.UpdatedAt,
 		Email:        user.Email,
+		Handle:       user.Handle.String,
 		IsChirpyRed:  user.IsChirpyRed,
 		Token:        token,
 		RefreshToken: refreshToken,
@@ -110,51 +123,28 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, tokenUser)
 }
 
-func (cfg *apiConfig) updateHandler(w http.ResponseWriter, r *http.Request) {
-
-	req := requestBody{}
-	tokenStr, err := auth.GetBearerToken(r.Header)
+// startSession issues an access token and a refresh token for userID.
+func (cfg *apiConfig) startSession(ctx context.Context, userID uuid.UUID) (token, refreshToken string, err error) {
+	token, err = auth.MakeJWT(userID, cfg.JWTSecret, time.Hour)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
-		return
-	}
-	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
-		return
+		return "", "", err
 	}
 
-	err = json.NewDecoder(r.Body).Decode(&req)
-
+	refreshToken, err = auth.MakeRefreshToken()
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Email and password are required", nil)
-		return
+		return "", "", err
 	}
 
-	hashedPassword, err := auth.HashPassword(req.Password)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't hash password", err)
-		return
-	}
-
-	user, err := cfg.DB.UpdateUser(r.Context(), database.UpdateUserParams{
-		ID:             userID,
-		Email:          req.Email,
-		HashedPassword: hashedPassword,
+	err = cfg.DB.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:     refreshToken,
+		UserID:    userID,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		ExpiresAt: time.Now().UTC().Add(60 * 24 * time.Hour),
+		RevokedAt: sql.NullTime{Valid: false},
 	})
-
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Could not update user", err)
-		return
+		return "", "", err
 	}
-
-	updatedUser := User{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       user.Email,
-		IsChirpyRed: user.IsChirpyRed,
-	}
-
-	respondWithJSON(w, http.StatusOK, updatedUser)
+	return token, refreshToken, nil
 }
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/auth"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/entities"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/pubsub"
+	"github.com/ha36ad/BootsDevProjects/GoHTTPServer/internal/wsproto"
+)
+
+const (
+	wsPingInterval     = 30 * time.Second
+	wsPongTimeout      = 10 * time.Second
+	wsWriteTimeout     = 10 * time.Second
+	wsMaxMessageBytes  = 4096
+	wsMaxSubscriptions = 10
+	wsMaxNameLength    = 64
+)
+
+// liveClients tracks long-lived connections, SSE streams and WebSockets,
+// so they can be closed cleanly on shutdown. http.Server.Shutdown doesn't
+// know about hijacked connections, and would sit out its whole timeout
+// waiting for open streams.
+type liveClients struct {
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+	closed bool
+	drain  chan struct{}
+}
+
+func newLiveClients() *liveClients {
+	return &liveClients{drain: make(chan struct{})}
+}
+
+// add registers a connection. It reports false once the server has begun
+// shutting down.
+func (l *liveClients) add() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return false
+	}
+	l.wg.Add(1)
+	return true
+}
+
+func (l *liveClients) done() {
+	l.wg.Done()
+}
+
+// draining is closed when connections should wrap up and disconnect.
+func (l *liveClients) draining() <-chan struct{} {
+	return l.drain
+}
+
+// shutdown asks every connection to close and waits until they have, or
+// ctx is done.
+func (l *liveClients) shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.drain)
+	}
+	l.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// wsHandler upgrades an authenticated request to a WebSocket speaking the
+// wsproto protocol. Browsers can't set headers on the handshake, so the
+// token may also be passed as ?access_token=.
+func (cfg *apiConfig) wsHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		tokenStr = r.URL.Query().Get("access_token")
+	}
+	if tokenStr == "" {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return
+	}
+
+	if !cfg.Live.add() {
+		respondWithError(w, http.StatusServiceUnavailable, "Server is shutting down", nil)
+		return
+	}
+	defer cfg.Live.done()
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		Subprotocols: []string{wsproto.Subprotocol},
+	})
+	if err != nil {
+		// Accept has already written the error response.
+		log.Printf("WebSocket handshake failed: %v", err)
+		return
+	}
+	conn.SetReadLimit(wsMaxMessageBytes)
+
+	client := &wsClient{
+		cfg:    cfg,
+		conn:   conn,
+		userID: userID,
+		subs:   make(map[string]*pubsub.Subscription),
+	}
+	client.run(r.Context())
+}
+
+// wsClient is one WebSocket connection. Messages from the client are
+// handled in order on the read loop; each subscription forwards its events
+// from its own goroutine.
+type wsClient struct {
+	cfg    *apiConfig
+	conn   *websocket.Conn
+	userID uuid.UUID
+
+	mu   sync.Mutex
+	subs map[string]*pubsub.Subscription
+	// forwarders tracks the subscription goroutines.
+	forwarders sync.WaitGroup
+}
+
+func (c *wsClient) run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go c.keepAlive(ctx)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.cfg.Live.draining():
+			c.send(ctx, wsproto.TypeGoodbye, "", wsproto.GoodbyeData{Reason: "shutdown"})
+			c.conn.Close(websocket.StatusGoingAway, "server shutting down")
+		}
+	}()
+
+	err := c.send(ctx, wsproto.TypeWelcome, "", wsproto.WelcomeData{
+		Version:      wsproto.Version,
+		UserID:       c.userID,
+		PingInterval: int(wsPingInterval / time.Second),
+	})
+
+	for err == nil {
+		var msgType websocket.MessageType
+		var raw []byte
+		msgType, raw, err = c.conn.Read(ctx)
+		if err != nil {
+			break
+		}
+		if msgType != websocket.MessageText {
+			c.sendError(ctx, "", wsproto.Errorf(wsproto.CodeBadMessage, "messages must be text"))
+			continue
+		}
+		c.handle(ctx, raw)
+	}
+
+	cancel()
+	c.mu.Lock()
+	for _, sub := range c.subs {
+		sub.Close()
+	}
+	c.mu.Unlock()
+	c.forwarders.Wait()
+	c.conn.CloseNow()
+}
+
+func (c *wsClient) handle(ctx context.Context, raw []byte) {
+	msg, protoErr := wsproto.Decode(raw)
+	if protoErr != nil {
+		c.sendError(ctx, msg.ID, protoErr)
+		return
+	}
+
+	switch msg.Type {
+	case wsproto.TypePing:
+		c.send(ctx, wsproto.TypePong, msg.ID, nil)
+
+	case wsproto.TypeSubscribe:
+		start, protoErr := c.subscribe(ctx, msg)
+		if protoErr != nil {
+			c.sendError(ctx, msg.ID, protoErr)
+			return
+		}
+		// Acknowledge before any events for the subscription go out.
+		c.send(ctx, wsproto.TypeAck, msg.ID, nil)
+		start()
+
+	case wsproto.TypeUnsubscribe:
+		if protoErr := c.unsubscribe(msg); protoErr != nil {
+			c.sendError(ctx, msg.ID, protoErr)
+			return
+		}
+		c.send(ctx, wsproto.TypeAck, msg.ID, nil)
+
+	case wsproto.TypeTyping:
+		var data wsproto.TypingData
+		if protoErr := msg.Bind(&data); protoErr != nil {
+			c.sendError(ctx, msg.ID, protoErr)
+			return
+		}
+		err := c.cfg.sendTyping(ctx, c.userID, data.ConversationID)
+		c.replyToAction(ctx, msg.ID, err, "conversation not found")
+
+	case wsproto.TypeRead:
+		var data wsproto.ReadData
+		if protoErr := msg.Bind(&data); protoErr != nil {
+			c.sendError(ctx, msg.ID, protoErr)
+			return
+		}
+		err := c.cfg.markConversationRead(ctx, c.userID, data.ConversationID, data.MessageID)
+		c.replyToAction(ctx, msg.ID, err, "message not found")
+
+	default:
+		c.sendError(ctx, msg.ID, wsproto.Errorf(wsproto.CodeUnknownType, "unknown message type %q", msg.Type))
+	}
+}
+
+// replyToAction acknowledges an action, or reports why it failed.
+// sql.ErrNoRows from the action is reported as notFound.
+func (c *wsClient) replyToAction(ctx context.Context, id string, err error, notFound string) {
+	switch {
+	case err == nil:
+		c.send(ctx, wsproto.TypeAck, id, nil)
+	case errors.Is(err, sql.ErrNoRows):
+		c.sendError(ctx, id, wsproto.Errorf(wsproto.CodeNotFound, "%s", notFound))
+	default:
+		log.Printf("WebSocket action failed: %v", err)
+		c.sendError(ctx, id, wsproto.Errorf(wsproto.CodeInternal, "something went wrong"))
+	}
+}
+
+// subscribe validates a subscribe message and registers the subscription.
+// It returns a function that starts delivering events, so the caller can
+// acknowledge the subscription first.
+func (c *wsClient) subscribe(ctx context.Context, msg wsproto.Message) (func(), *wsproto.Error) {
+	var data wsproto.SubscribeData
+	if protoErr := msg.Bind(&data); protoErr != nil {
+		return nil, protoErr
+	}
+	if data.Subscription == "" || len(data.Subscription) > wsMaxNameLength {
+		return nil, wsproto.Errorf(wsproto.CodeInvalid, "subscription name must be 1 to %d characters", wsMaxNameLength)
+	}
+
+	var hub *pubsub.Hub
+	var filter pubsub.Filter
+	switch data.Channel {
+	case wsproto.ChannelChirps:
+		if data.LastEventID < 0 {
+			return nil, wsproto.Errorf(wsproto.CodeInvalid, "last_event_id can't be negative")
+		}
+		opts := streamOptions{AuthorID: data.Author, Timeline: data.Timeline}
+		if data.Tag != "" {
+			opts.Tag = entities.NormalizeTag(data.Tag)
+			if opts.Tag == "" {
+				return nil, wsproto.Errorf(wsproto.CodeInvalid, "invalid tag")
+			}
+		}
+		var err error
+		filter, err = c.cfg.streamFilter(ctx, c.userID, opts)
+		if err != nil {
+			log.Printf("Couldn't build stream filter: %v", err)
+			return nil, wsproto.Errorf(wsproto.CodeInternal, "couldn't subscribe")
+		}
+		hub = c.cfg.Events
+
+	case wsproto.ChannelMessages, wsproto.ChannelNotifications:
+		if data.Author != uuid.Nil || data.Tag != "" || data.Timeline || data.LastEventID != 0 {
+			return nil, wsproto.Errorf(wsproto.CodeInvalid, "the %s channel takes no filters", data.Channel)
+		}
+		// Both channels share the private hub; notifications go to one and
+		// everything else to the other.
+		notifications := data.Channel == wsproto.ChannelNotifications
+		filter = func(e pubsub.Event) bool {
+			return slices.Contains(e.Recipients, c.userID) && (e.Type == pubsub.NotificationCreated) == notifications
+		}
+		hub = c.cfg.Private
+
+	default:
+		return nil, wsproto.Errorf(wsproto.CodeInvalid, "unknown channel %q", data.Channel)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.subs[data.Subscription]; ok {
+		return nil, wsproto.Errorf(wsproto.CodeInvalid, "subscription %q already exists", data.Subscription)
+	}
+	if len(c.subs) >= wsMaxSubscriptions {
+		return nil, wsproto.Errorf(wsproto.CodeLimitExceeded, "a connection can have at most %d subscriptions", wsMaxSubscriptions)
+	}
+
+	// Subscribe before replaying, as the SSE stream does, so nothing is
+	// missed in between.
+	sub := hub.Subscribe(streamBuffer, filter)
+	c.subs[data.Subscription] = sub
+	c.forwarders.Add(1)
+
+	return func() {
+		go c.forward(ctx, data.Subscription, sub, filter, data.LastEventID)
+	}, nil
+}
+
+func (c *wsClient) unsubscribe(msg wsproto.Message) *wsproto.Error {
+	var data wsproto.UnsubscribeData
+	if protoErr := msg.Bind(&data); protoErr != nil {
+		return protoErr
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sub, ok := c.subs[data.Subscription]
+	if !ok {
+		return wsproto.Errorf(wsproto.CodeNotFound, "no subscription %q", data.Subscription)
+	}
+	delete(c.subs, data.Subscription)
+	sub.Close()
+	return nil
+}
+
+// forward sends a subscription's events to the client until the
+// subscription ends. If it ends because the client fell behind, the client
+// is told so it can subscribe again from where it got to.
+func (c *wsClient) forward(ctx context.Context, name string, sub *pubsub.Subscription, filter pubsub.Filter, lastID int64) {
+	defer c.forwarders.Done()
+
+	send := func(e pubsub.Event) error {
+		return c.send(ctx, wsproto.TypeEvent, "", wsproto.EventData{
+			Subscription: name,
+			EventID:      e.ID,
+			Event:        e.Type,
+			Payload:      e.Data,
+		})
+	}
+
+	replayed := map[int64]bool{}
+	if lastID > 0 {
+		var err error
+		replayed, err = c.cfg.replayStream(ctx, lastID, filter, send)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Couldn't replay chirp events: %v", err)
+			}
+			return
+		}
+	}
+
+	for event := range sub.Events() {
+		if replayed[event.ID] {
+			delete(replayed, event.ID)
+			continue
+		}
+		if err := send(event); err != nil {
+			return
+		}
+	}
+
+	if !sub.Overflowed() {
+		return
+	}
+	c.mu.Lock()
+	if c.subs[name] == sub {
+		delete(c.subs, name)
+	}
+	c.mu.Unlock()
+	c.send(ctx, wsproto.TypeOverflow, "", wsproto.OverflowData{Subscription: name})
+}
+
+// keepAlive pings the client and drops the connection if it stops
+// answering.
+func (c *wsClient) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, wsPongTimeout)
+			err := c.conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				c.conn.CloseNow()
+				return
+			}
+		}
+	}
+}
+
+// send writes a message to the client. A client that can't take a message
+// within wsWriteTimeout is disconnected.
+func (c *wsClient) send(ctx context.Context, msgType, id string, data any) error {
+	raw, err := wsproto.Encode(msgType, id, data)
+	if err != nil {
+		return err
+	}
+
+	writeCtx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+	defer cancel()
+	err = c.conn.Write(writeCtx, websocket.MessageText, raw)
+	if err != nil && errors.Is(writeCtx.Err(), context.DeadlineExceeded) {
+		c.conn.CloseNow()
+	}
+	return err
+}
+
+func (c *wsClient) sendError(ctx context.Context, id string, protoErr *wsproto.Error) error {
+	return c.send(ctx, wsproto.TypeError, id, protoErr)
+}